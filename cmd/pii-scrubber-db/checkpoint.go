@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+)
+
+// checkpoint records the progress of a run and the findings so far, so that
+// an interrupted run resumes after the last batch it committed and still
+// reports the totals. It is saved after every batch when it has a path
+type checkpoint struct {
+	path   string
+	Tables map[string]*tableProgress `json:"tables"`
+}
+
+type tableProgress struct {
+	// LastKey is the key of the last row scrubbed
+	LastKey interface{}              `json:"last_key,omitempty"`
+	Done    bool                     `json:"done"`
+	Rows    int                      `json:"rows"`
+	Columns map[string]*columnReport `json:"columns"`
+}
+
+// columnReport counts what was found in the values of a column, values that
+// are NULL or not strings are not counted
+type columnReport struct {
+	Values   int                        `json:"values"`
+	Changed  int                        `json:"changed"`
+	Findings map[piiscrubber.Entity]int `json:"findings,omitempty"`
+}
+
+func (r *columnReport) add(other *columnReport) {
+	r.Values += other.Values
+	r.Changed += other.Changed
+	for entity, count := range other.Findings {
+		if r.Findings == nil {
+			r.Findings = make(map[piiscrubber.Entity]int)
+		}
+		r.Findings[entity] += count
+	}
+}
+
+func loadCheckpoint(path string) (*checkpoint, error) {
+	cp := &checkpoint{path: path, Tables: make(map[string]*tableProgress)}
+	if path == "" {
+		return cp, nil
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	// keys are compared with the column they were read from, so integers
+	// must stay integers
+	decoder.UseNumber()
+	if err := decoder.Decode(cp); err != nil {
+		return nil, err
+	}
+	for _, progress := range cp.Tables {
+		if number, ok := progress.LastKey.(json.Number); ok {
+			if key, err := number.Int64(); err == nil {
+				progress.LastKey = key
+			} else {
+				progress.LastKey = number.String()
+			}
+		}
+	}
+	return cp, nil
+}
+
+// started reports whether a previous run committed anything
+func (cp *checkpoint) started() bool {
+	return len(cp.Tables) > 0
+}
+
+func (cp *checkpoint) table(name string) *tableProgress {
+	progress, ok := cp.Tables[name]
+	if !ok {
+		progress = &tableProgress{Columns: make(map[string]*columnReport)}
+		cp.Tables[name] = progress
+	}
+	return progress
+}
+
+// save replaces the checkpoint file, a run interrupted while saving resumes
+// from the previous one
+func (cp *checkpoint) save() error {
+	if cp.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(cp, "", "\t")
+	if err != nil {
+		return err
+	}
+	tmp := cp.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, cp.path)
+}
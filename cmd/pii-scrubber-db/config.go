@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+)
+
+const _defaultBatchSize = 1000
+
+// Config is read from the JSON file passed with -config
+//
+//	{
+//		"entities": ["EMAIL", "PHONE"],
+//		"columns": {
+//			"users.email": {},
+//			"users.notes": {"entities": ["PHONE"]},
+//			"users.password": {"action": "redact"}
+//		},
+//		"keys": {"orders": "id"}
+//	}
+type Config struct {
+	// Entities are scrubbed out of the columns that don't list their own,
+	// defaults to the entities of piiscrubber.NewDefaultScrubber
+	Entities []piiscrubber.Entity `json:"entities"`
+	// Columns map table.column to the policy of the column. Tables may be
+	// qualified with their schema, e.g. public.users.email
+	Columns map[string]ColumnPolicy `json:"columns"`
+	// Keys name the unique column the rows of a table are read in order of
+	// and updated by. SQLite tables default to their rowid, other databases
+	// need a key for every table
+	Keys map[string]string `json:"keys"`
+	// BatchSize is the number of rows scrubbed per transaction
+	BatchSize int `json:"batch_size"`
+}
+
+// ColumnPolicy ...
+type ColumnPolicy struct {
+	// Action is piiscrubber.Scan, the default, or piiscrubber.Redact
+	Action piiscrubber.Action `json:"action"`
+	// Entities overrides the entities scrubbed out of the column
+	Entities []piiscrubber.Entity `json:"entities"`
+}
+
+// tableConfig is the configuration of the columns of one table
+type tableConfig struct {
+	name    string
+	key     string
+	columns []columnConfig
+}
+
+type columnConfig struct {
+	name     string
+	action   piiscrubber.Action
+	scrubber piiscrubber.Scrubber
+}
+
+func loadConfig(path string) (*Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var config Config
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("in config %v: %v", path, err)
+	}
+	return &config, nil
+}
+
+// tables returns the configured tables sorted by name, with a scrubber per
+// distinct list of entities
+func (c *Config) tables() ([]*tableConfig, error) {
+	if len(c.Columns) == 0 {
+		return nil, fmt.Errorf("no columns configured")
+	}
+
+	scrubbers := make(map[string]piiscrubber.Scrubber)
+	scrubberFor := func(entities []piiscrubber.Entity) (piiscrubber.Scrubber, error) {
+		if entities == nil {
+			entities = c.Entities
+		}
+		key := fmt.Sprint(entities)
+		if scrubber, ok := scrubbers[key]; ok {
+			return scrubber, nil
+		}
+
+		var scrubber piiscrubber.Scrubber
+		var err error
+		if entities == nil {
+			scrubber, err = piiscrubber.NewDefaultScrubber()
+		} else {
+			scrubber, err = piiscrubber.New(piiscrubber.Params{BlacklistedEntities: entities})
+		}
+		if err != nil {
+			return nil, err
+		}
+		scrubbers[key] = scrubber
+		return scrubber, nil
+	}
+
+	byName := make(map[string]*tableConfig)
+	for key, policy := range c.Columns {
+		dot := strings.LastIndexByte(key, '.')
+		if dot <= 0 || dot == len(key)-1 {
+			return nil, fmt.Errorf("in column policy for: %v, error: expected table.column", key)
+		}
+
+		action := policy.Action
+		if action == "" {
+			action = piiscrubber.Scan
+		}
+		if action != piiscrubber.Scan && action != piiscrubber.Redact {
+			return nil, fmt.Errorf("in column policy for: %v, error: unknown action: %q", key, action)
+		}
+		scrubber, err := scrubberFor(policy.Entities)
+		if err != nil {
+			return nil, fmt.Errorf("in column policy for: %v, error: %v", key, err.Error())
+		}
+
+		name := key[:dot]
+		table, ok := byName[name]
+		if !ok {
+			table = &tableConfig{name: name, key: c.Keys[name]}
+			byName[name] = table
+		}
+		table.columns = append(table.columns, columnConfig{
+			name:     key[dot+1:],
+			action:   action,
+			scrubber: scrubber,
+		})
+	}
+
+	tables := make([]*tableConfig, 0, len(byName))
+	for _, table := range byName {
+		sort.Slice(table.columns, func(i, j int) bool {
+			return table.columns[i].name < table.columns[j].name
+		})
+		tables = append(tables, table)
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].name < tables[j].name
+	})
+	return tables, nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// dialect holds what differs between the supported databases
+type dialect struct {
+	driver string
+	// quote is the character identifiers are quoted with
+	quote byte
+	// numbered placeholders are $1, $2, ... instead of ?
+	numbered bool
+	// defaultKey is the key of tables without one in the config
+	defaultKey string
+}
+
+var _dialects = map[string]*dialect{
+	"sqlite":   {driver: "sqlite", quote: '"', defaultKey: "rowid"},
+	"postgres": {driver: "pgx", quote: '"', numbered: true},
+	"mysql":    {driver: "mysql", quote: '`'},
+}
+
+// dialectFor returns the dialect named with -driver, or the one the DSN is
+// for: postgres:// and postgresql:// URLs are PostgreSQL, mysql:// URLs are
+// MySQL and anything else is a SQLite file. The DSN is returned in the form
+// the driver expects
+func dialectFor(name, dsn string) (*dialect, string, error) {
+	if name == "" {
+		switch {
+		case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"):
+			name = "postgres"
+		case strings.HasPrefix(dsn, "mysql://"):
+			name = "mysql"
+		default:
+			name = "sqlite"
+		}
+	}
+
+	d, ok := _dialects[name]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown driver: %q, expected sqlite, postgres or mysql", name)
+	}
+	if d.driver == "mysql" {
+		// the MySQL driver takes user:password@tcp(host)/database DSNs
+		dsn = strings.TrimPrefix(dsn, "mysql://")
+	}
+	return d, dsn, nil
+}
+
+// quoteName quotes every part of a possibly qualified name
+func (d *dialect) quoteName(name string) string {
+	quote := string(d.quote)
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = quote + strings.ReplaceAll(part, quote, quote+quote) + quote
+	}
+	return strings.Join(parts, ".")
+}
+
+// placeholder returns the placeholder of the nth parameter, from 1
+func (d *dialect) placeholder(n int) string {
+	if d.numbered {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// selectBatch returns the query reading the next rows of a table after the
+// last key, or the first rows when after is false
+func (d *dialect) selectBatch(table *tableConfig, after bool, batchSize int) string {
+	var query strings.Builder
+	query.WriteString("SELECT ")
+	query.WriteString(d.quoteName(table.key))
+	for _, column := range table.columns {
+		query.WriteString(", ")
+		query.WriteString(d.quoteName(column.name))
+	}
+	query.WriteString(" FROM ")
+	query.WriteString(d.quoteName(table.name))
+	if after {
+		query.WriteString(" WHERE ")
+		query.WriteString(d.quoteName(table.key))
+		query.WriteString(" > ")
+		query.WriteString(d.placeholder(1))
+	}
+	fmt.Fprintf(&query, " ORDER BY %v LIMIT %d", d.quoteName(table.key), batchSize)
+	return query.String()
+}
+
+// updateRow returns the statement writing the columns of a row back, the
+// key being the last parameter
+func (d *dialect) updateRow(table *tableConfig) string {
+	var query strings.Builder
+	query.WriteString("UPDATE ")
+	query.WriteString(d.quoteName(table.name))
+	query.WriteString(" SET ")
+	for i, column := range table.columns {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString(d.quoteName(column.name))
+		query.WriteString(" = ")
+		query.WriteString(d.placeholder(i + 1))
+	}
+	query.WriteString(" WHERE ")
+	query.WriteString(d.quoteName(table.key))
+	query.WriteString(" = ")
+	query.WriteString(d.placeholder(len(table.columns) + 1))
+	return query.String()
+}
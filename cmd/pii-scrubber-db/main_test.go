@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/stretchr/testify/assert"
+)
+
+const _schema = `
+CREATE TABLE users (
+	id INTEGER PRIMARY KEY,
+	name TEXT,
+	email TEXT,
+	notes BLOB,
+	password TEXT
+);
+CREATE TABLE orders (code TEXT PRIMARY KEY, note TEXT);
+INSERT INTO users (name, email, notes, password) VALUES
+	('Jane', 'jane@example.com', CAST('call +919140520809' AS BLOB), 'hunter2'),
+	('John', 'john@example.com', NULL, 'secret'),
+	('Ann', 'ann@example.com', 'no pii here', NULL),
+	('Bob', 'bob@example.com', 'mail bob@example.com', 'x'),
+	('Eve', 'eve@example.com', '+919140520809', 'y');
+INSERT INTO orders VALUES ('b-2', 'ship to jane@example.com'), ('a-1', 'leave at door');
+`
+
+var _testConfig = &Config{
+	Entities: []piiscrubber.Entity{piiscrubber.Email, piiscrubber.Phone},
+	Columns: map[string]ColumnPolicy{
+		"users.email":    {},
+		"users.notes":    {Entities: []piiscrubber.Entity{piiscrubber.Phone}},
+		"users.password": {Action: piiscrubber.Redact},
+		"orders.note":    {},
+	},
+	Keys: map[string]string{"orders": "code"},
+}
+
+func newTestDB(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "prod.db")
+	db, err := sql.Open("sqlite", path)
+	assert.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec(_schema)
+	assert.NoError(t, err)
+	return path
+}
+
+func queryStrings(t *testing.T, path, query string) []string {
+	db, err := sql.Open("sqlite", path)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	rows, err := db.Query(query)
+	assert.NoError(t, err)
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value sql.NullString
+		assert.NoError(t, rows.Scan(&value))
+		values = append(values, value.String)
+	}
+	return values
+}
+
+func TestRun_CopyTo(t *testing.T) {
+	source := newTestDB(t)
+	staging := filepath.Join(t.TempDir(), "staging.db")
+
+	cp, err := run(context.Background(), &options{
+		config:    _testConfig,
+		dsn:       source,
+		copyTo:    staging,
+		batchSize: 2,
+	})
+	assert.NoError(t, err)
+
+	// the source is left as it is
+	assert.Equal(t, "jane@example.com", queryStrings(t, source, "SELECT email FROM users")[0])
+
+	assert.Equal(t, []string{
+		"<EMAIL_ADDRESS>", "<EMAIL_ADDRESS>", "<EMAIL_ADDRESS>", "<EMAIL_ADDRESS>", "<EMAIL_ADDRESS>",
+	}, queryStrings(t, staging, "SELECT email FROM users ORDER BY id"))
+	// only phone numbers are scrubbed out of notes
+	assert.Equal(t, []string{
+		"call <PHONE_NUMBER>", "", "no pii here", "mail bob@example.com", "<PHONE_NUMBER>",
+	}, queryStrings(t, staging, "SELECT notes FROM users ORDER BY id"))
+	assert.Equal(t, []string{
+		"<REDACTED>", "<REDACTED>", "", "<REDACTED>", "<REDACTED>",
+	}, queryStrings(t, staging, "SELECT password FROM users ORDER BY id"))
+	assert.Equal(t, []string{
+		"leave at door", "ship to <EMAIL_ADDRESS>",
+	}, queryStrings(t, staging, "SELECT note FROM orders ORDER BY code"))
+	// blobs stay blobs
+	assert.Equal(t, []string{"blob"}, queryStrings(t, staging, "SELECT typeof(notes) FROM users WHERE id = 1"))
+
+	assert.Equal(t, 5, cp.Tables["users"].Rows)
+	assert.Equal(t, &columnReport{Values: 5, Changed: 5, Findings: map[piiscrubber.Entity]int{piiscrubber.Email: 5}}, cp.Tables["users"].Columns["email"])
+	assert.Equal(t, &columnReport{Values: 4, Changed: 2, Findings: map[piiscrubber.Entity]int{piiscrubber.Phone: 2}}, cp.Tables["users"].Columns["notes"])
+	assert.Equal(t, &columnReport{Values: 4, Changed: 4}, cp.Tables["users"].Columns["password"])
+
+	var report bytes.Buffer
+	writeReport(&report, cp)
+	assert.Equal(t, `COLUMN          VALUES  CHANGED  FINDINGS
+orders.note     2       1        EMAIL=1
+users.email     5       5        EMAIL=5
+users.notes     4       2        PHONE=2
+users.password  4       4        -
+`, report.String())
+
+	// a second copy is not made over the first one
+	_, err = run(context.Background(), &options{config: _testConfig, dsn: source, copyTo: staging})
+	assert.Error(t, err)
+}
+
+func TestRun_ResumesFromCheckpoint(t *testing.T) {
+	path := newTestDB(t)
+	checkpointPath := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	db, err := sql.Open("sqlite", path)
+	assert.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec(`CREATE TRIGGER interrupt BEFORE UPDATE ON users WHEN NEW.id = 3
+		BEGIN SELECT RAISE(ABORT, 'interrupted'); END`)
+	assert.NoError(t, err)
+
+	opts := &options{
+		config:         _testConfig,
+		dsn:            path,
+		inPlace:        true,
+		checkpointPath: checkpointPath,
+		batchSize:      2,
+	}
+	_, err = run(context.Background(), opts)
+	assert.ErrorContains(t, err, "interrupted")
+
+	// the first batch was committed, the second one was rolled back
+	assert.Equal(t, []string{
+		"<EMAIL_ADDRESS>", "<EMAIL_ADDRESS>", "ann@example.com", "bob@example.com", "eve@example.com",
+	}, queryStrings(t, path, "SELECT email FROM users ORDER BY id"))
+
+	_, err = db.Exec("DROP TRIGGER interrupt")
+	assert.NoError(t, err)
+	// rows of the first batch that are scrubbed again would count twice
+	_, err = db.Exec("UPDATE users SET email = 'jane@example.com' WHERE id = 1")
+	assert.NoError(t, err)
+
+	cp, err := run(context.Background(), opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"jane@example.com", "<EMAIL_ADDRESS>", "<EMAIL_ADDRESS>", "<EMAIL_ADDRESS>", "<EMAIL_ADDRESS>",
+	}, queryStrings(t, path, "SELECT email FROM users ORDER BY id"))
+	assert.Equal(t, 5, cp.Tables["users"].Rows)
+	assert.Equal(t, 5, cp.Tables["users"].Columns["email"].Changed)
+
+	// a finished run is not repeated
+	cp, err = run(context.Background(), opts)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, cp.Tables["users"].Rows)
+	assert.Equal(t, "jane@example.com", queryStrings(t, path, "SELECT email FROM users ORDER BY id")[0])
+}
+
+func TestRun_InvalidOptions(t *testing.T) {
+	for name, opts := range map[string]*options{
+		"no mode":    {config: _testConfig, dsn: "prod.db"},
+		"both modes": {config: _testConfig, dsn: "prod.db", inPlace: true, copyTo: "staging.db"},
+		"copy of postgres": {
+			config: _testConfig, dsn: "postgres://localhost/prod", copyTo: "staging.db",
+		},
+		"no postgres key": {
+			config: &Config{Columns: map[string]ColumnPolicy{"users.email": {}}}, dsn: "postgres://localhost/prod", inPlace: true,
+		},
+		"unknown action": {
+			config: &Config{Columns: map[string]ColumnPolicy{"users.email": {Action: "hash"}}}, dsn: "prod.db", inPlace: true,
+		},
+		"no table": {
+			config: &Config{Columns: map[string]ColumnPolicy{"email": {}}}, dsn: "prod.db", inPlace: true,
+		},
+		"unknown driver": {config: _testConfig, dsn: "prod.db", driver: "oracle", inPlace: true},
+	} {
+		_, err := run(context.Background(), opts)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestDialect_Statements(t *testing.T) {
+	table := &tableConfig{
+		name:    "public.users",
+		key:     "id",
+		columns: []columnConfig{{name: "email"}, {name: `odd"name`}},
+	}
+
+	d, dsn, err := dialectFor("", "postgres://localhost/prod")
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres://localhost/prod", dsn)
+	assert.Equal(t, `SELECT "id", "email", "odd""name" FROM "public"."users" WHERE "id" > $1 ORDER BY "id" LIMIT 10`, d.selectBatch(table, true, 10))
+	assert.Equal(t, `UPDATE "public"."users" SET "email" = $1, "odd""name" = $2 WHERE "id" = $3`, d.updateRow(table))
+
+	d, dsn, err = dialectFor("", "mysql://root@tcp(localhost)/prod")
+	assert.NoError(t, err)
+	assert.Equal(t, "root@tcp(localhost)/prod", dsn)
+	assert.Equal(t, "SELECT `id`, `email`, `odd\"name` FROM `public`.`users` ORDER BY `id` LIMIT 10", d.selectBatch(table, false, 10))
+	assert.Equal(t, "UPDATE `public`.`users` SET `email` = ?, `odd\"name` = ? WHERE `id` = ?", d.updateRow(table))
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+)
+
+const _redactedValue = "<REDACTED>"
+
+// options are the flags of a run
+type options struct {
+	config         *Config
+	driver         string
+	dsn            string
+	copyTo         string
+	inPlace        bool
+	checkpointPath string
+	batchSize      int
+}
+
+// run scrubs the configured columns and returns the progress, which holds
+// the findings per column
+func run(ctx context.Context, opts *options) (*checkpoint, error) {
+	tables, err := opts.config.tables()
+	if err != nil {
+		return nil, err
+	}
+	d, dsn, err := dialectFor(opts.driver, opts.dsn)
+	if err != nil {
+		return nil, err
+	}
+	if opts.inPlace == (opts.copyTo != "") {
+		return nil, fmt.Errorf("either -in-place or -copy-to is required")
+	}
+	if opts.copyTo != "" && d.driver != "sqlite" {
+		return nil, fmt.Errorf("-copy-to is only supported for SQLite, scrub a restored copy of the database in place instead")
+	}
+
+	batchSize := opts.batchSize
+	if batchSize <= 0 {
+		batchSize = opts.config.BatchSize
+	}
+	if batchSize <= 0 {
+		batchSize = _defaultBatchSize
+	}
+	for _, table := range tables {
+		if table.key == "" {
+			table.key = d.defaultKey
+		}
+		if table.key == "" {
+			return nil, fmt.Errorf("no key configured for table: %v", table.name)
+		}
+	}
+
+	cp, err := loadCheckpoint(opts.checkpointPath)
+	if err != nil {
+		return nil, fmt.Errorf("in checkpoint %v: %v", opts.checkpointPath, err)
+	}
+
+	db, err := sql.Open(d.driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if opts.copyTo != "" {
+		if db, err = copySQLite(ctx, db, opts.copyTo, cp); err != nil {
+			return nil, err
+		}
+		defer db.Close()
+	}
+
+	for _, table := range tables {
+		progress := cp.table(table.name)
+		for !progress.Done {
+			if err := scrubBatch(ctx, db, d, table, progress, batchSize); err != nil {
+				return cp, fmt.Errorf("in table %v: %v", table.name, err)
+			}
+			if err := cp.save(); err != nil {
+				return cp, err
+			}
+		}
+	}
+	return cp, nil
+}
+
+// copySQLite copies the database to path and returns the copy, unless a
+// previous run already did
+func copySQLite(ctx context.Context, db *sql.DB, path string, cp *checkpoint) (*sql.DB, error) {
+	_, err := os.Stat(path)
+	exists := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	switch {
+	case exists && !cp.started():
+		return nil, fmt.Errorf("copy %v already exists", path)
+	case !exists && cp.started():
+		// a new copy would keep the rows the checkpoint says are scrubbed
+		return nil, fmt.Errorf("copy %v of the checkpoint does not exist", path)
+	case !exists:
+		if _, err := db.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+			return nil, err
+		}
+	}
+	return sql.Open("sqlite", path)
+}
+
+// scrubBatch scrubs the rows after the last key of progress in a single
+// transaction and updates progress once it is committed. The values of a
+// column are scrubbed with a single ScrubTextsWithFindings call
+func scrubBatch(ctx context.Context, db *sql.DB, d *dialect, table *tableConfig, progress *tableProgress, batchSize int) error {
+	var args []interface{}
+	if progress.LastKey != nil {
+		args = append(args, progress.LastKey)
+	}
+	rows, err := db.QueryContext(ctx, d.selectBatch(table, progress.LastKey != nil, batchSize), args...)
+	if err != nil {
+		return err
+	}
+
+	// each row holds its key followed by the configured columns
+	var values [][]interface{}
+	for rows.Next() {
+		row := make([]interface{}, len(table.columns)+1)
+		dest := make([]interface{}, len(row))
+		for i := range row {
+			dest[i] = &row[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return err
+		}
+		values = append(values, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(values) == 0 {
+		progress.Done = true
+		return nil
+	}
+
+	changed := make([]bool, len(values))
+	reports := make([]*columnReport, len(table.columns))
+	for i, column := range table.columns {
+		report, err := scrubColumn(column, values, i+1, changed)
+		if err != nil {
+			return fmt.Errorf("in column %v: %v", column.name, err)
+		}
+		reports[i] = report
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, d.updateRow(table))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i, row := range values {
+		if !changed[i] {
+			continue
+		}
+		// the key goes last
+		args := append(row[1:len(row):len(row)], row[0])
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for i, column := range table.columns {
+		report, ok := progress.Columns[column.name]
+		if !ok {
+			report = &columnReport{}
+			progress.Columns[column.name] = report
+		}
+		report.add(reports[i])
+	}
+	progress.Rows += len(values)
+	progress.Done = len(values) < batchSize
+	progress.LastKey = values[len(values)-1][0]
+	if key, ok := progress.LastKey.([]byte); ok {
+		progress.LastKey = string(key)
+	}
+	return nil
+}
+
+// scrubColumn scrubs the string values at index in the rows in place and
+// marks the rows it changed
+func scrubColumn(column columnConfig, values [][]interface{}, index int, changed []bool) (*columnReport, error) {
+	report := &columnReport{}
+
+	var rows []int
+	var texts []string
+	for i, row := range values {
+		switch value := row[index].(type) {
+		case string:
+			texts = append(texts, value)
+		case []byte:
+			texts = append(texts, string(value))
+		default:
+			continue
+		}
+		rows = append(rows, i)
+	}
+	report.Values = len(texts)
+	if len(texts) == 0 {
+		return report, nil
+	}
+
+	scrubbed := make([]string, len(texts))
+	if column.action == piiscrubber.Redact {
+		for i := range scrubbed {
+			scrubbed[i] = _redactedValue
+		}
+	} else {
+		var findings [][]piiscrubber.Finding
+		var err error
+		scrubbed, findings, err = piiscrubber.ScrubTextsWithFindings(column.scrubber, texts)
+		if err != nil {
+			return nil, err
+		}
+		for _, textFindings := range findings {
+			for _, finding := range textFindings {
+				if report.Findings == nil {
+					report.Findings = make(map[piiscrubber.Entity]int)
+				}
+				report.Findings[finding.Entity]++
+			}
+		}
+	}
+
+	for i, row := range rows {
+		if scrubbed[i] == texts[i] {
+			continue
+		}
+		report.Changed++
+		changed[row] = true
+		if _, ok := values[row][index].([]byte); ok {
+			values[row][index] = []byte(scrubbed[i])
+		} else {
+			values[row][index] = scrubbed[i]
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/aavaz-ai/pii-scrubber/internal/policyconfig"
+)
+
+const _defaultPolicy = "default"
+
+// Config is read from the JSON file passed with -config
+//
+//	{
+//		"default_policy": "support",
+//		"policies": {
+//			"support": {"entities": ["EMAIL", "PHONE"]},
+//			"analytics": {
+//				"masking": {"EMAIL": {"replace_with": "<EMAIL>"}},
+//				"json_rules": [{"path": "$..password", "action": "drop"}]
+//			}
+//		}
+//	}
+//
+// Every policy is read by policyconfig.Read, so it takes the fields of
+// piiscrubber.LoadPolicy and json_rules for /v1/scrub/json. Without a config
+// there is a single policy, default, scrubbing the entities of
+// piiscrubber.NewDefaultScrubber
+type Config struct {
+	// DefaultPolicy is the policy of requests that don't name one, defaults
+	// to default
+	DefaultPolicy string
+	Policies      map[string]*policyconfig.Config
+}
+
+// configFile is the layout of the file passed with -config, its policies are
+// read once the file is decoded. The lines of their errors count from the
+// start of the policy
+type configFile struct {
+	DefaultPolicy string                     `json:"default_policy"`
+	Policies      map[string]json.RawMessage `json:"policies"`
+}
+
+func loadConfig(path string) (*Config, error) {
+	file := &configFile{}
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		decoder := json.NewDecoder(f)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(file); err != nil {
+			return nil, fmt.Errorf("in config %v: %v", path, err)
+		}
+	}
+
+	config := &Config{
+		DefaultPolicy: file.DefaultPolicy,
+		Policies:      make(map[string]*policyconfig.Config, len(file.Policies)),
+	}
+	if config.DefaultPolicy == "" {
+		config.DefaultPolicy = _defaultPolicy
+	}
+	if len(file.Policies) == 0 {
+		file.Policies = map[string]json.RawMessage{_defaultPolicy: nil}
+	}
+	for name, raw := range file.Policies {
+		// null is the default policy, like an empty one
+		if bytes.Equal(raw, []byte("null")) {
+			raw = nil
+		}
+		policy, err := policyconfig.Read(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("in config %v, policy %v: %v", path, name, err)
+		}
+		config.Policies[name] = policy
+	}
+	if _, ok := config.Policies[config.DefaultPolicy]; !ok {
+		return nil, fmt.Errorf("default policy %q is not configured", config.DefaultPolicy)
+	}
+	return config, nil
+}
@@ -0,0 +1,92 @@
+// Command pii-scrubber-server serves the scrubbers of its config over HTTP,
+// so that services not written in Go scrub the same way Go services do
+//
+// Usage:
+//
+//	pii-scrubber-server -addr :8080 -config policies.json
+//
+// Endpoints, described in openapi.yaml and served at /v1/openapi.yaml:
+//
+//	POST /v1/scrub        scrubs a list of texts
+//	POST /v1/analyze      reports the entities found in a list of texts
+//	POST /v1/scrub/json   scrubs a JSON document with the JSON rules of the policy
+//	GET  /healthz         reports that the server is up
+//	GET  /readyz          reports whether the server takes requests
+//
+// The policy query parameter selects a policy of the config by name. On
+// SIGINT or SIGTERM /readyz starts failing and the server stops once the
+// requests in flight complete
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	configPath := flag.String("config", "", "JSON file with the policies to serve, each read by piiscrubber.LoadPolicy")
+	maxBodyBytes := flag.Int64("max-body-bytes", 1<<20, "largest request body accepted")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 10*time.Second, "how long requests in flight are waited for on shutdown")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: pii-scrubber-server [flags]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("pii-scrubber-server: %v", err)
+	}
+	s, err := newServer(config, *maxBodyBytes)
+	if err != nil {
+		log.Fatalf("pii-scrubber-server: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := s.serve(ctx, *addr, *shutdownTimeout); err != nil {
+		log.Fatalf("pii-scrubber-server: %v", err)
+	}
+}
+
+// serve serves requests on addr until ctx is done, then shuts down
+// gracefully
+func (s *server) serve(ctx context.Context, addr string, shutdownTimeout time.Duration) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		log.Printf("listening on %v", addr)
+		errs <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.draining.Store(true)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"unicode/utf8"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+)
+
+//go:embed openapi.yaml
+var _openAPI []byte
+
+// policy is a configured scrubber and the rules of its JSON documents
+type policy struct {
+	scrubber  piiscrubber.Scrubber
+	jsonRules []piiscrubber.JSONRule
+}
+
+type server struct {
+	policies      map[string]*policy
+	defaultPolicy string
+	maxBodyBytes  int64
+	// draining is set once the server shuts down, so that load balancers
+	// stop sending requests while the ones in flight complete
+	draining atomic.Bool
+}
+
+// apiError is an error reported to the client with its status
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &apiError{status: http.StatusBadRequest, message: fmt.Sprintf(format, args...)}
+}
+
+type scrubRequest struct {
+	Texts []string `json:"texts"`
+}
+
+type scrubResponse struct {
+	Texts []string `json:"texts"`
+}
+
+type analyzeResponse struct {
+	Results []analyzeResult `json:"results"`
+}
+
+type analyzeResult struct {
+	Findings []finding `json:"findings"`
+}
+
+// finding is a piiscrubber.Finding. Start and End are offsets in code
+// points, which is how most languages index strings, ByteStart and ByteEnd
+// offsets into the UTF-8 encoded text
+type finding struct {
+	Entity    piiscrubber.Entity `json:"entity"`
+	Start     int                `json:"start"`
+	End       int                `json:"end"`
+	ByteStart int                `json:"byte_start"`
+	ByteEnd   int                `json:"byte_end"`
+	Text      string             `json:"text"`
+}
+
+func newFinding(text string, f piiscrubber.Finding) finding {
+	start := utf8.RuneCountInString(text[:f.Start])
+	return finding{
+		Entity:    f.Entity,
+		Start:     start,
+		End:       start + utf8.RuneCountInString(text[f.Start:f.End]),
+		ByteStart: f.Start,
+		ByteEnd:   f.End,
+		Text:      text[f.Start:f.End],
+	}
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type statusResponse struct {
+	Status string `json:"status"`
+}
+
+func newServer(config *Config, maxBodyBytes int64) (*server, error) {
+	s := &server{
+		policies:      make(map[string]*policy, len(config.Policies)),
+		defaultPolicy: config.DefaultPolicy,
+		maxBodyBytes:  maxBodyBytes,
+	}
+	for name, policyConfig := range config.Policies {
+		scrubber, err := piiscrubber.New(policyConfig.Params)
+		if err != nil {
+			return nil, fmt.Errorf("in policy %v: %v", name, err)
+		}
+		s.policies[name] = &policy{scrubber: scrubber, jsonRules: policyConfig.JSONRules}
+	}
+	return s, nil
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/scrub", s.post(s.scrub))
+	mux.HandleFunc("/v1/analyze", s.post(s.analyze))
+	mux.HandleFunc("/v1/scrub/json", s.post(s.scrubJSON))
+	mux.HandleFunc("/v1/openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(_openAPI)
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, statusResponse{Status: "ok"})
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if s.draining.Load() {
+			writeJSON(w, http.StatusServiceUnavailable, statusResponse{Status: "draining"})
+			return
+		}
+		writeJSON(w, http.StatusOK, statusResponse{Status: "ready"})
+	})
+	return mux
+}
+
+// post wraps the handler of a POST endpoint, it limits the size of the body
+// and resolves the policy named by the policy query parameter
+func (s *server) post(handle func(http.ResponseWriter, *http.Request, *policy) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		name := r.URL.Query().Get("policy")
+		if name == "" {
+			name = s.defaultPolicy
+		}
+		p, ok := s.policies[name]
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("unknown policy: %q", name))
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxBodyBytes)
+		err := handle(w, r, p)
+		var apiErr *apiError
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case err == nil:
+		case errors.As(err, &apiErr):
+			writeError(w, apiErr.status, apiErr.message)
+		case errors.As(err, &maxBytesErr):
+			writeError(w, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("request body is larger than %d bytes", maxBytesErr.Limit))
+		default:
+			// scrubbing errors are not reported back, they might quote the
+			// texts
+			log.Printf("%v %v: %v", r.Method, r.URL.Path, err)
+			writeError(w, http.StatusInternalServerError, "scrubbing failed")
+		}
+	}
+}
+
+func (s *server) scrub(w http.ResponseWriter, r *http.Request, p *policy) error {
+	var req scrubRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		return err
+	}
+	texts, err := p.scrubber.ScrubTexts(req.Texts)
+	if err != nil {
+		return err
+	}
+	if texts == nil {
+		texts = []string{}
+	}
+	writeJSON(w, http.StatusOK, scrubResponse{Texts: texts})
+	return nil
+}
+
+func (s *server) analyze(w http.ResponseWriter, r *http.Request, p *policy) error {
+	var req scrubRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		return err
+	}
+	_, findings, err := piiscrubber.ScrubTextsWithFindings(p.scrubber, req.Texts)
+	if err != nil {
+		return err
+	}
+
+	resp := analyzeResponse{Results: make([]analyzeResult, len(findings))}
+	for i, found := range findings {
+		resp.Results[i].Findings = make([]finding, len(found))
+		for j, f := range found {
+			resp.Results[i].Findings[j] = newFinding(req.Texts[i], f)
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+	return nil
+}
+
+func (s *server) scrubJSON(w http.ResponseWriter, r *http.Request, p *policy) error {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	scrubbed, err := piiscrubber.ScrubJSON(data, piiscrubber.JSONPolicy{Scrubber: p.scrubber, Rules: p.jsonRules})
+	if errors.Is(err, piiscrubber.ErrInvalidJSON) {
+		return badRequest("request body is not a JSON document")
+	}
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(scrubbed)
+	return nil
+}
+
+// decodeJSON decodes the request body into v, rejecting unknown fields and
+// trailing data
+func decodeJSON(body io.Reader, v interface{}) error {
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return err
+		}
+		return badRequest("invalid request body: %v", err)
+	}
+	if decoder.More() {
+		return badRequest("invalid request body: trailing data after the JSON object")
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	// placeholders such as <EMAIL_ADDRESS> are kept readable
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T, config string) *httptest.Server {
+	path := ""
+	if config != "" {
+		path = filepath.Join(t.TempDir(), "config.json")
+		assert.NoError(t, os.WriteFile(path, []byte(config), 0644))
+	}
+	cfg, err := loadConfig(path)
+	assert.NoError(t, err)
+	s, err := newServer(cfg, 1024)
+	assert.NoError(t, err)
+
+	ts := httptest.NewServer(s.handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func request(t *testing.T, method, url, body string) (int, string) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	assert.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return resp.StatusCode, strings.TrimSpace(string(data))
+}
+
+const _testConfig = `{
+	"default_policy": "support",
+	"policies": {
+		"support": {"entities": ["EMAIL", "PHONE"]},
+		"strict": {
+			"entities": ["EMAIL"],
+			"allow_list": ["support@example.com"],
+			"masking": {"EMAIL": {"replace_with": "<EMAIL>"}},
+			"json_rules": [{"path": "$..password", "action": "drop"}]
+		},
+		"null": null
+	}
+}`
+
+func TestServer_Scrub(t *testing.T) {
+	ts := newTestServer(t, _testConfig)
+
+	status, body := request(t, http.MethodPost, ts.URL+"/v1/scrub",
+		`{"texts": ["mail jane@example.com", "call +919140520809", ""]}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"texts":["mail <EMAIL_ADDRESS>","call <PHONE_NUMBER>",""]}`, body)
+
+	status, body = request(t, http.MethodPost, ts.URL+"/v1/scrub?policy=strict",
+		`{"texts": ["mail jane@example.com", "call +919140520809", "mail support@example.com"]}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"texts":["mail <EMAIL>","call +919140520809","mail support@example.com"]}`, body)
+
+	// a null policy is the default one
+	status, body = request(t, http.MethodPost, ts.URL+"/v1/scrub?policy=null", `{"texts": ["mail jane@example.com"]}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"texts":["mail <EMAIL_ADDRESS>"]}`, body)
+
+	status, body = request(t, http.MethodPost, ts.URL+"/v1/scrub", `{}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"texts":[]}`, body)
+}
+
+func TestServer_Analyze(t *testing.T) {
+	ts := newTestServer(t, "")
+
+	status, body := request(t, http.MethodPost, ts.URL+"/v1/analyze",
+		`{"texts": ["mail jane@example.com", "nothing"]}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"results":[{"findings":[{"entity":"EMAIL","start":5,"end":21,"byte_start":5,"byte_end":21,"text":"jane@example.com"}]},{"findings":[]}]}`, body)
+
+	// offsets count code points, the byte offsets are past the multi-byte
+	// characters
+	status, body = request(t, http.MethodPost, ts.URL+"/v1/analyze",
+		`{"texts": ["grüße an jane@example.com"]}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"results":[{"findings":[{"entity":"EMAIL","start":9,"end":25,"byte_start":11,"byte_end":27,"text":"jane@example.com"}]}]}`, body)
+}
+
+func TestServer_ScrubJSON(t *testing.T) {
+	ts := newTestServer(t, _testConfig)
+
+	status, body := request(t, http.MethodPost, ts.URL+"/v1/scrub/json?policy=strict",
+		`{"user": {"email": "jane@example.com", "password": "hunter2"}, "n": 1.50}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"user":{"email":"<EMAIL>"},"n":1.50}`, body)
+
+	status, _ = request(t, http.MethodPost, ts.URL+"/v1/scrub/json", `{"user": `)
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestServer_Errors(t *testing.T) {
+	ts := newTestServer(t, _testConfig)
+
+	for _, tc := range []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{http.MethodGet, "/v1/scrub", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/v1/scrub?policy=missing", `{"texts": []}`, http.StatusNotFound},
+		{http.MethodPost, "/v1/scrub", `{"texts": "a"}`, http.StatusBadRequest},
+		{http.MethodPost, "/v1/scrub", `{"text": ["a"]}`, http.StatusBadRequest},
+		{http.MethodPost, "/v1/scrub", `{"texts": []} {}`, http.StatusBadRequest},
+		{http.MethodPost, "/v1/analyze", `{"texts": ["` + strings.Repeat("a", 2048) + `"]}`, http.StatusRequestEntityTooLarge},
+		{http.MethodPost, "/v1/scrub/json", `["` + strings.Repeat("a", 2048) + `"]`, http.StatusRequestEntityTooLarge},
+	} {
+		status, body := request(t, tc.method, ts.URL+tc.path, tc.body)
+		assert.Equal(t, tc.status, status, tc.path)
+		assert.Contains(t, body, `"error"`, tc.path)
+	}
+}
+
+func TestServer_HealthAndShutdown(t *testing.T) {
+	cfg, err := loadConfig("")
+	assert.NoError(t, err)
+	s, err := newServer(cfg, 1024)
+	assert.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- s.serve(ctx, addr, time.Second)
+	}()
+
+	assert.Eventually(t, func() bool {
+		resp, err := http.Get("http://" + addr + "/readyz")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+
+	status, body := request(t, http.MethodGet, "http://"+addr+"/healthz", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"status":"ok"}`, body)
+
+	status, body = request(t, http.MethodGet, "http://"+addr+"/v1/openapi.yaml", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "/v1/scrub/json:")
+
+	cancel()
+	assert.NoError(t, <-done)
+	assert.True(t, s.draining.Load())
+}
+
+func TestLoadConfig_Errors(t *testing.T) {
+	dir := t.TempDir()
+	for _, config := range []string{
+		`{"default_policy": "missing", "policies": {"default": {}}}`,
+		`{"policies": {"default": {"entities": ["EMAIL"], "unknown": true}}}`,
+		`{"policies": {"default": {"json_rules": [{"path": "$.a", "acton": "drop"}]}}}`,
+		`{"policies": `,
+	} {
+		path := filepath.Join(dir, "config.json")
+		assert.NoError(t, os.WriteFile(path, []byte(config), 0644))
+		_, err := loadConfig(path)
+		assert.Error(t, err, config)
+	}
+}
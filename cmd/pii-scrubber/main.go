@@ -0,0 +1,185 @@
+// Command pii-scrubber scrubs PII out of stdin, files or whole directory
+// trees
+//
+// Usage:
+//
+//	pii-scrubber < app.log > app.scrubbed.log
+//	pii-scrubber -entities EMAIL,PHONE -mask redact users.csv > users.scrubbed.csv
+//	pii-scrubber -config policy.json -out-dir exports/scrubbed exports/raw
+//	pii-scrubber -in-place -l uploads
+//
+// The format of a file is told by its extension, .json, .ndjson or .jsonl,
+// .csv and .tsv, other files are scrubbed as text, line by line. Binary files
+// are skipped unless -format is set. stdin is text unless -format is set.
+// Files are scrubbed in parallel, each one into a temporary file renamed over
+// the output once it is complete
+//
+// The exit code is 0 when no PII was found, 1 when PII was found and
+// scrubbed, and 2 on errors
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/aavaz-ai/pii-scrubber/internal/policyconfig"
+)
+
+// _hashKeyEnv names the environment variable holding the key of the hashes
+// of CSV columns hashed by csv_rules, so that it is not kept in the config
+const _hashKeyEnv = "PII_SCRUBBER_HASH_KEY"
+
+// Exit codes ...
+const (
+	_exitClean    = 0
+	_exitScrubbed = 1
+	_exitError    = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("pii-scrubber", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configPath := flags.String("config", "", "YAML or JSON policy file, as read by piiscrubber.LoadPolicy, with json_rules and csv_rules for JSON and CSV inputs")
+	entities := flags.String("entities", "", "comma separated entities to scrub, overrides the config")
+	ignored := flags.String("ignore", "", "comma separated entities to leave alone, overrides the config")
+	mask := flags.String("mask", "placeholder", "how entities are masked: placeholder, redact or char")
+	maskChar := flags.String("mask-char", "*", "character entities are masked with when -mask is char")
+	opts := &options{}
+	flags.StringVar(&opts.format, "format", "", "format of the inputs: text, json, ndjson, csv or tsv, defaults to the one of the file extension")
+	flags.BoolVar(&opts.inPlace, "in-place", false, "replace the files with their scrubbed version")
+	flags.StringVar(&opts.outDir, "out-dir", "", "directory the scrubbed files are written to, mirroring the input directories")
+	flags.BoolVar(&opts.list, "l", false, "list the files PII was found in, without writing them unless -in-place or -out-dir is set")
+	flags.IntVar(&opts.jobs, "jobs", runtime.NumCPU(), "number of files scrubbed in parallel")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: pii-scrubber [flags] [file or directory ...]\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return _exitError
+	}
+
+	fail := func(err error) int {
+		fmt.Fprintf(stderr, "pii-scrubber: %v\n", err)
+		return _exitError
+	}
+
+	config, err := policyconfig.Load(*configPath)
+	if err != nil {
+		return fail(err)
+	}
+	if list := parseEntities(*entities); list != nil {
+		config.Params.BlacklistedEntities = list
+	}
+	if list := parseEntities(*ignored); list != nil {
+		config.Params.IgnoredEntities = list
+	}
+	char, size := utf8.DecodeRuneInString(*maskChar)
+	if size == 0 || size != len(*maskChar) {
+		return fail(fmt.Errorf("-mask-char must be a single character"))
+	}
+	params, err := config.MaskedParams(*mask, char)
+	if err != nil {
+		return fail(err)
+	}
+	if opts.scrubber, err = piiscrubber.New(params); err != nil {
+		return fail(err)
+	}
+	opts.jsonRules, opts.csvRules = config.JSONRules, config.CSVRules
+	opts.hashKey = []byte(os.Getenv(_hashKeyEnv))
+
+	if opts.format != "" && !validFormat(opts.format) {
+		return fail(fmt.Errorf("unknown format: %q, expected text, json, ndjson, csv or tsv", opts.format))
+	}
+	if opts.inPlace && opts.outDir != "" {
+		return fail(fmt.Errorf("-in-place and -out-dir are mutually exclusive"))
+	}
+	if opts.jobs < 1 {
+		opts.jobs = 1
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 || (len(paths) == 1 && paths[0] == "-") {
+		if opts.inPlace || opts.outDir != "" {
+			return fail(fmt.Errorf("stdin can only be scrubbed to stdout"))
+		}
+		return runStdin(opts, stdin, stdout, stderr)
+	}
+
+	jobs, err := opts.jobsFor(paths)
+	if err != nil {
+		return fail(err)
+	}
+	if !opts.inPlace && opts.outDir == "" && !opts.list && len(jobs) > 1 {
+		return fail(fmt.Errorf("several files can only be scrubbed with -in-place or -out-dir"))
+	}
+
+	code := _exitClean
+	results := opts.runJobs(jobs, stdout)
+	for _, res := range results {
+		if res.err != nil || res.skipped {
+			fmt.Fprintf(stderr, "pii-scrubber: %v\n", describe(res))
+		}
+		if res.err != nil {
+			code = _exitError
+		}
+	}
+	found := pathsWithFindings(results)
+	if opts.list {
+		for _, path := range found {
+			fmt.Fprintln(stdout, path)
+		}
+	}
+	if code == _exitClean && len(found) > 0 {
+		code = _exitScrubbed
+	}
+	return code
+}
+
+func runStdin(opts *options, stdin io.Reader, stdout, stderr io.Writer) int {
+	format := opts.format
+	if format == "" {
+		format = _formatText
+	}
+	out := stdout
+	if opts.list {
+		out = io.Discard
+	}
+
+	findings, err := opts.scrub(stdin, out, format)
+	if err != nil {
+		fmt.Fprintf(stderr, "pii-scrubber: %v\n", err)
+		return _exitError
+	}
+	if findings == 0 {
+		return _exitClean
+	}
+	if opts.list {
+		fmt.Fprintln(stdout, "-")
+	}
+	return _exitScrubbed
+}
+
+// parseEntities parses a comma separated list of entities, nil when list is
+// empty
+func parseEntities(list string) []piiscrubber.Entity {
+	if list == "" {
+		return nil
+	}
+	entities := []piiscrubber.Entity{}
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			entities = append(entities, piiscrubber.Entity(strings.ToUpper(name)))
+		}
+	}
+	return entities
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func runForTest(t *testing.T, stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func writeFile(t *testing.T, path, data string) {
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, os.WriteFile(path, []byte(data), 0640))
+}
+
+func readFile(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	return string(data)
+}
+
+func TestRun_Stdin(t *testing.T) {
+	code, stdout, stderr := runForTest(t, "mail jane@example.com\nnothing here", "-entities", "email")
+	assert.Equal(t, _exitScrubbed, code, stderr)
+	assert.Equal(t, "mail <EMAIL_ADDRESS>\nnothing here", stdout)
+
+	code, stdout, _ = runForTest(t, "call +919140520809\n", "-mask", "redact")
+	assert.Equal(t, _exitScrubbed, code)
+	assert.Equal(t, "call <REDACTED>\n", stdout)
+
+	code, stdout, _ = runForTest(t, "nothing here\n")
+	assert.Equal(t, _exitClean, code)
+	assert.Equal(t, "nothing here\n", stdout)
+
+	code, stdout, _ = runForTest(t, `{"email":"jane@example.com","password":"x"}`+"\n", "-format", "ndjson", "-mask", "char", "-mask-char", "#")
+	assert.Equal(t, _exitScrubbed, code)
+	assert.Equal(t, `{"email":"################","password":"x"}`+"\n", stdout)
+}
+
+func TestRun_Directory(t *testing.T) {
+	dir := t.TempDir()
+	in, out := filepath.Join(dir, "in"), filepath.Join(dir, "out")
+	config := filepath.Join(dir, "policy.json")
+	writeFile(t, config, `{
+		"entities": ["EMAIL", "PHONE"],
+		"masking": {"PHONE": {"mask_with_char": "*", "unmasked_suffix": 4}},
+		"json_rules": [{"path": "$..password", "action": "drop"}],
+		"csv_rules": [{"name": "name", "action": "redact"}]
+	}`)
+	writeFile(t, filepath.Join(in, "notes.txt"), "call +919140520809\n")
+	writeFile(t, filepath.Join(in, "a", "user.json"), `{"email": "jane@example.com", "password": "x", "n": 1.0}`)
+	writeFile(t, filepath.Join(in, "a", "users.csv"), "name,note\nJane,hi\n")
+	writeFile(t, filepath.Join(in, "a", "b", "clean.log"), "nothing here\n")
+	writeFile(t, filepath.Join(in, "image.png"), "\x89PNG\x00jane@example.com")
+
+	code, stdout, stderr := runForTest(t, "", "-config", config, "-out-dir", out, "-l", in)
+	assert.Equal(t, _exitScrubbed, code, stderr)
+	assert.Equal(t, filepath.Join(in, "a", "user.json")+"\n"+filepath.Join(in, "notes.txt")+"\n", stdout)
+	assert.Contains(t, stderr, "image.png: skipped binary file")
+
+	assert.Equal(t, "call *********0809\n", readFile(t, filepath.Join(out, "notes.txt")))
+	assert.Equal(t, `{"email":"<EMAIL_ADDRESS>","n":1.0}`+"\n", readFile(t, filepath.Join(out, "a", "user.json")))
+	assert.Equal(t, "name,note\n<REDACTED>,hi\n", readFile(t, filepath.Join(out, "a", "users.csv")))
+	assert.Equal(t, "nothing here\n", readFile(t, filepath.Join(out, "a", "b", "clean.log")))
+	assert.NoFileExists(t, filepath.Join(out, "image.png"))
+
+	info, err := os.Stat(filepath.Join(out, "notes.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+	// the inputs are left alone
+	assert.Equal(t, "call +919140520809\n", readFile(t, filepath.Join(in, "notes.txt")))
+}
+
+func TestRun_YAMLPolicy(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "policy.yaml")
+	writeFile(t, config, `
+entities: [EMAIL, EMPLOYEE_ID]
+allow_list: [support@example.com]
+custom_entities:
+  EMPLOYEE_ID: {patterns: ['EMP-\d{6}'], word_boundaries: True}
+csv_rules:
+  - {index: 0, action: keep}
+`)
+
+	code, stdout, stderr := runForTest(t, "EMP-123456 mailed support@example.com and jane@example.com\n", "-config", config)
+	assert.Equal(t, _exitScrubbed, code, stderr)
+	assert.Equal(t, "<EMPLOYEE_ID> mailed support@example.com and <EMAIL_ADDRESS>\n", stdout)
+
+	code, stdout, stderr = runForTest(t, "jane@example.com,jane@example.com\n", "-config", config, "-format", "csv")
+	assert.Equal(t, _exitScrubbed, code, stderr)
+	assert.Equal(t, "jane@example.com,<EMAIL_ADDRESS>\n", stdout)
+
+	// errors point at the entry of the policy
+	writeFile(t, config, "csv_rules:\n  - {nmae: email, action: redact}\n")
+	code, _, stderr = runForTest(t, "", "-config", config)
+	assert.Equal(t, _exitError, code)
+	assert.Contains(t, stderr, "line 2, column 3")
+}
+
+func TestRun_InPlace(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	writeFile(t, path, "mail jane@example.com\n")
+	broken := filepath.Join(dir, "broken.json")
+	writeFile(t, broken, `{"email": "jane@example.com"`)
+
+	code, _, stderr := runForTest(t, "", "-in-place", "-jobs", "2", dir)
+	assert.Equal(t, _exitError, code)
+	assert.Contains(t, stderr, "broken.json")
+	assert.Equal(t, "mail <EMAIL_ADDRESS>\n", readFile(t, path))
+	// a file that fails is not replaced
+	assert.Equal(t, `{"email": "jane@example.com"`, readFile(t, broken))
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestRun_InvalidOptions(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.txt"), "a")
+	writeFile(t, filepath.Join(dir, "b.txt"), "b")
+
+	for _, args := range [][]string{
+		{"-unknown"},
+		{"-format", "xml"},
+		{"-mask", "blur"},
+		{"-mask", "char", "-mask-char", "**"},
+		{"-in-place", "-out-dir", dir, dir},
+		{"-in-place"},
+		{"-config", filepath.Join(dir, "missing.json")},
+		{filepath.Join(dir, "missing.txt")},
+		{dir},
+	} {
+		code, _, _ := runForTest(t, "", args...)
+		assert.Equal(t, _exitError, code, args)
+	}
+}
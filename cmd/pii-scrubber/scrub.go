@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+)
+
+// _sniffSize is the number of bytes looked at to tell binary files apart, as
+// git does
+const _sniffSize = 8000
+
+// Input formats ...
+const (
+	_formatText   = "text"
+	_formatJSON   = "json"
+	_formatNDJSON = "ndjson"
+	_formatCSV    = "csv"
+	_formatTSV    = "tsv"
+)
+
+// options are the flags of a run
+type options struct {
+	scrubber  piiscrubber.Scrubber
+	jsonRules []piiscrubber.JSONRule
+	csvRules  []piiscrubber.CSVColumnRule
+	hashKey   []byte
+	// format is the format of every input, the extension of a file tells its
+	// format when it is empty
+	format  string
+	inPlace bool
+	outDir  string
+	list    bool
+	jobs    int
+}
+
+// job is a file to scrub, dst is empty when the output is discarded and "-"
+// for stdout
+type job struct {
+	src string
+	dst string
+}
+
+type result struct {
+	path     string
+	findings int
+	skipped  bool
+	err      error
+}
+
+// countingScrubber counts the entities scrubbed through it
+type countingScrubber struct {
+	piiscrubber.Scrubber
+	findings int
+}
+
+func (c *countingScrubber) ScrubTexts(texts []string) ([]string, error) {
+	scrubbedTexts, _, err := c.ScrubTextsWithFindings(texts)
+	return scrubbedTexts, err
+}
+
+func (c *countingScrubber) ScrubTextsWithFindings(texts []string) ([]string, [][]piiscrubber.Finding, error) {
+	scrubbedTexts, findings, err := piiscrubber.ScrubTextsWithFindings(c.Scrubber, texts)
+	for _, found := range findings {
+		c.findings += len(found)
+	}
+	return scrubbedTexts, findings, err
+}
+
+func (c *countingScrubber) ScrubStructWithReport(obj interface{}) (interface{}, []piiscrubber.FieldReport, error) {
+	scrubbed, report, err := piiscrubber.ScrubStructWithReport(c.Scrubber, obj)
+	for _, field := range report {
+		c.findings += len(field.Findings)
+	}
+	return scrubbed, report, err
+}
+
+func validFormat(format string) bool {
+	switch format {
+	case _formatText, _formatJSON, _formatNDJSON, _formatCSV, _formatTSV:
+		return true
+	}
+	return false
+}
+
+// formatOf returns the format of the file at path
+func (o *options) formatOf(path string) string {
+	if o.format != "" {
+		return o.format
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return _formatJSON
+	case ".ndjson", ".jsonl":
+		return _formatNDJSON
+	case ".csv":
+		return _formatCSV
+	case ".tsv":
+		return _formatTSV
+	}
+	return _formatText
+}
+
+// scrub scrubs r into w and returns the number of entities found
+func (o *options) scrub(r io.Reader, w io.Writer, format string) (int, error) {
+	s := &countingScrubber{Scrubber: o.scrubber}
+	var err error
+	switch format {
+	case _formatJSON:
+		if err = piiscrubber.ScrubJSONStream(r, w, piiscrubber.JSONPolicy{Scrubber: s, Rules: o.jsonRules}); err == nil {
+			_, err = w.Write([]byte{'\n'})
+		}
+
+	case _formatNDJSON:
+		err = piiscrubber.ScrubNDJSON(r, w, piiscrubber.JSONPolicy{Scrubber: s, Rules: o.jsonRules})
+
+	case _formatCSV, _formatTSV:
+		policy := piiscrubber.CSVPolicy{Scrubber: s, Rules: o.csvRules, HashKey: o.hashKey}
+		if format == _formatTSV {
+			policy.Comma = '\t'
+		}
+		_, err = piiscrubber.ScrubCSV(r, w, policy)
+
+	default:
+		writer := piiscrubber.NewWriter(w, s, nil)
+		if _, err = io.Copy(writer, r); err == nil {
+			err = writer.Close()
+		}
+	}
+	return s.findings, err
+}
+
+// jobsFor lists the files to scrub, walking directories
+func (o *options) jobsFor(paths []string) ([]job, error) {
+	var jobs []job
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			jobs = append(jobs, job{src: path, dst: o.dstFor(path, filepath.Base(path))})
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(src string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(path, src)
+			if err != nil {
+				return err
+			}
+			jobs = append(jobs, job{src: src, dst: o.dstFor(src, rel)})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return jobs, nil
+}
+
+// dstFor returns where the scrubbed src is written, rel being its path in the
+// output directory
+func (o *options) dstFor(src, rel string) string {
+	switch {
+	case o.inPlace:
+		return src
+	case o.outDir != "":
+		return filepath.Join(o.outDir, rel)
+	case o.list:
+		return ""
+	}
+	return "-"
+}
+
+// runJobs scrubs the files with o.jobs workers and returns the results in
+// the order of jobs
+func (o *options) runJobs(jobs []job, stdout io.Writer) []result {
+	results := make([]result, len(jobs))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < o.jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				results[index] = o.scrubFile(jobs[index], stdout)
+			}
+		}()
+	}
+	for index := range jobs {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+func (o *options) scrubFile(j job, stdout io.Writer) result {
+	res := result{path: j.src}
+	in, err := os.Open(j.src)
+	if err != nil {
+		res.err = err
+		return res
+	}
+	defer in.Close()
+
+	format := o.formatOf(j.src)
+	reader := bufio.NewReader(in)
+	if format == _formatText && o.format == "" {
+		// files that only look like text by their name are left alone
+		head, _ := reader.Peek(_sniffSize)
+		if bytes.IndexByte(head, 0) >= 0 {
+			res.skipped = true
+			return res
+		}
+	}
+
+	switch j.dst {
+	case "":
+		res.findings, res.err = o.scrub(reader, io.Discard, format)
+	case "-":
+		res.findings, res.err = o.scrub(reader, stdout, format)
+	default:
+		info, err := in.Stat()
+		if err != nil {
+			res.err = err
+			return res
+		}
+		res.findings, res.err = o.scrubTo(reader, j.dst, format, info.Mode().Perm())
+	}
+	return res
+}
+
+// scrubTo writes the scrubbed input to a temporary file renamed to dst once
+// complete, so that dst is never left half written even when it is the input.
+// The file gets the mode of the input
+func (o *options) scrubTo(r io.Reader, dst, format string, mode fs.FileMode) (int, error) {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return 0, err
+	}
+	out, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(out.Name())
+
+	writer := bufio.NewWriter(out)
+	findings, err := o.scrub(r, writer, format)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return findings, err
+	}
+
+	if err := os.Chmod(out.Name(), mode); err != nil {
+		return findings, err
+	}
+	return findings, os.Rename(out.Name(), dst)
+}
+
+// pathsWithFindings returns the paths of the results entities were found in,
+// sorted
+func pathsWithFindings(results []result) []string {
+	var paths []string
+	for _, res := range results {
+		if res.err == nil && res.findings > 0 {
+			paths = append(paths, res.path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func describe(res result) string {
+	if res.skipped {
+		return fmt.Sprintf("%v: skipped binary file", res.path)
+	}
+	return fmt.Sprintf("%v: %v", res.path, res.err)
+}
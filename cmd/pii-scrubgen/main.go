@@ -0,0 +1,54 @@
+// Command pii-scrubgen generates reflection-free ScrubPII methods for structs
+// tagged with pii:"true"
+//
+// Usage, from a file of the package holding the tagged structs:
+//
+//	//go:generate go run github.com/aavaz-ai/pii-scrubber/cmd/pii-scrubgen
+//
+// The generated methods scrub the same fields as ScrubStruct and produce the
+// same result, but walk the fields directly and scrub all the strings of a
+// value with a single ScrubTexts call. Maps and interfaces are still handed to
+// ScrubStruct, and so is the whole value when the Scrubber applies field
+// rules, which are only known at run time
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aavaz-ai/pii-scrubber/internal/scrubgen"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "comma separated list of types to generate ScrubPII for, defaults to every struct with a pii tag")
+	output := flag.String("output", scrubgen.DefaultOutput, "name of the generated file, written to the package directory")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: pii-scrubgen [flags] [package directory]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	var types []string
+	if *typeNames != "" {
+		types = strings.Split(*typeNames, ",")
+	}
+
+	source, err := scrubgen.Generate(dir, types, *output)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pii-scrubgen: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, *output), source, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "pii-scrubgen: %v\n", err)
+		os.Exit(1)
+	}
+}
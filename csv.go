@@ -0,0 +1,571 @@
+package piiscrubber
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Hash replaces values with a keyed hash of them, as HashRedactor does, so
+// that equal values can still be joined on. It is only valid in CSV column
+// rules
+const Hash Action = "hash"
+
+const (
+	// a batch of rows is scrubbed once it holds this many rows or this many
+	// bytes of values to scan
+	_csvBatchRows  = 256
+	_csvBatchBytes = 32 * 1024
+)
+
+var (
+	// ErrInvalidCSV ...
+	ErrInvalidCSV = fmt.Errorf("invalid CSV input")
+)
+
+// CSVHeader tells whether the first row of a CSV input names its columns
+type CSVHeader int
+
+// Possible CSVHeaders ...
+const (
+	// DetectCSVHeader treats the first row as a header when its fields are
+	// distinct, not empty, not numbers and free of detected entities. As a
+	// data row may look like that, the Redact and Hash rules selecting
+	// columns by Index still apply to it
+	DetectCSVHeader CSVHeader = iota
+	// HasCSVHeader treats the first row as a header
+	HasCSVHeader
+	// NoCSVHeader treats the first row as data
+	NoCSVHeader
+)
+
+// CSVColumnRule applies Action to a column, selected by its Name in the
+// header, compared case-insensitively, or by its 0-based Index when Name is
+// empty. Action is Scan, Redact, Hash, Keep or Drop
+type CSVColumnRule struct {
+	Name   string
+	Index  int
+	Action Action
+}
+
+// CSVPolicy tells ScrubCSV how to scrub the rows of a CSV input. Columns no
+// rule selects are scanned, when several rules select a column the last one
+// wins. Empty values are never replaced
+type CSVPolicy struct {
+	// Scrubber scans the values. The reports only count entities when it is a
+	// FindingsScrubber, e.g. one returned by New, other Scrubbers are taken
+	// to have found PII in the values they change
+	Scrubber Scrubber
+	Rules    []CSVColumnRule
+	// Comma is the field delimiter, ',' when zero. Use '\t' for TSV
+	Comma  rune
+	Header CSVHeader
+	// HashKey is the key of the hashes of Hash columns, it is required when
+	// a rule hashes
+	HashKey []byte
+	// DetectRows makes ScrubCSV sample this many rows first and redact the
+	// columns no rule selects that entities were detected in
+	DetectRows int
+}
+
+// CSVColumnReport tells what was done to a column and what was found in it
+type CSVColumnReport struct {
+	Index int
+	// Name is the header of the column, empty without header
+	Name   string
+	Action Action
+	// Values is the number of values that are not empty, Changed the number
+	// of them that were replaced
+	Values  int
+	Changed int
+	// Findings counts the entities detected in the values scanned, by
+	// entity. Columns redacted because of DetectRows count the entities
+	// found in the sample
+	Findings map[Entity]int
+}
+
+// Entities returns the entities found in the column, sorted by name
+func (r CSVColumnReport) Entities() []Entity {
+	entities := make([]Entity, 0, len(r.Findings))
+	for entity := range r.Findings {
+		entities = append(entities, entity)
+	}
+	sort.Slice(entities, func(i, j int) bool {
+		return entities[i] < entities[j]
+	})
+	return entities
+}
+
+func (p CSVPolicy) isValid() error {
+	if p.Scrubber == nil {
+		return fmt.Errorf("CSV policy has no scrubber")
+	}
+	if p.Comma != 0 && (p.Comma == '"' || p.Comma == '\r' || p.Comma == '\n' ||
+		!utf8.ValidRune(p.Comma) || p.Comma == utf8.RuneError) {
+		return fmt.Errorf("invalid CSV delimiter %q", p.Comma)
+	}
+	for _, rule := range p.Rules {
+		column := rule.Name
+		if column == "" {
+			column = strconv.Itoa(rule.Index)
+		}
+		switch {
+		case rule.Action == Drop:
+		case rule.Action == Hash:
+			if len(p.HashKey) == 0 {
+				return fmt.Errorf("in CSV rule for column: %v, error: hashing requires a HashKey", column)
+			}
+		default:
+			if err := rule.Action.isValid(); err != nil {
+				return fmt.Errorf("in CSV rule for column: %v, error: %v", column, err.Error())
+			}
+		}
+		if rule.Name == "" && rule.Index < 0 {
+			return fmt.Errorf("in CSV rule for column: %v, error: index must not be negative", column)
+		}
+	}
+	return nil
+}
+
+// ScrubCSV scrubs the rows of the CSV input read from r and writes them to w,
+// streaming them through in batches. Quoted fields, embedded newlines and
+// rows of different lengths are handled. The header, if any, is written
+// without the dropped columns, and as it is unless it was detected. Returns a
+// report for every column
+//
+// Rows are written as they are scrubbed, so when the input turns out to be
+// invalid part of it may have been written already. The error returned then
+// wraps ErrInvalidCSV
+func ScrubCSV(r io.Reader, w io.Writer, policy CSVPolicy) ([]CSVColumnReport, error) {
+	c, err := newCSVScrubber(policy, r)
+	if err != nil {
+		return nil, err
+	}
+	c.writer = csv.NewWriter(w)
+	c.writer.Comma = c.reader.Comma
+	return c.run()
+}
+
+// DetectCSVColumns samples the first policy.DetectRows rows of the CSV input
+// read from r, all of them when it is zero, and reports the entities found
+// in every column and the action ScrubCSV would apply to it
+func DetectCSVColumns(r io.Reader, policy CSVPolicy) ([]CSVColumnReport, error) {
+	c, err := newCSVScrubber(policy, r)
+	if err != nil {
+		return nil, err
+	}
+	c.detectOnly = true
+	return c.run()
+}
+
+// csvScrubber streams the rows of a CSV input through a scrubber
+type csvScrubber struct {
+	policy CSVPolicy
+	reader *csv.Reader
+	// writer is nil when only detecting
+	writer     *csv.Writer
+	detectOnly bool
+	hasher     Redactor
+
+	header []string
+	// actions are the actions of the rules by column, detected the ones of
+	// the columns DetectRows found entities in
+	actions  map[int]Action
+	detected map[int]Action
+	// byIndex are the actions of the columns whose last rule selects them by
+	// index, they apply to a detected header as well
+	byIndex map[int]Action
+	reports []*CSVColumnReport
+
+	// pending are the rows read but not written yet
+	pending [][]string
+}
+
+func newCSVScrubber(policy CSVPolicy, r io.Reader) (*csvScrubber, error) {
+	if err := policy.isValid(); err != nil {
+		return nil, err
+	}
+	c := &csvScrubber{
+		policy:   policy,
+		reader:   csv.NewReader(r),
+		actions:  make(map[int]Action),
+		detected: make(map[int]Action),
+		byIndex:  make(map[int]Action),
+	}
+	if policy.Comma != 0 {
+		c.reader.Comma = policy.Comma
+	}
+	// spreadsheets often have rows of different lengths
+	c.reader.FieldsPerRecord = -1
+	if len(policy.HashKey) > 0 {
+		c.hasher = HashRedactor(policy.HashKey)
+	}
+	return c, nil
+}
+
+func (c *csvScrubber) run() ([]CSVColumnReport, error) {
+	first, err := c.read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	isHeader := c.policy.Header == HasCSVHeader
+	if c.policy.Header == DetectCSVHeader {
+		if isHeader, err = c.looksLikeHeader(first); err != nil {
+			return nil, err
+		}
+	}
+	if isHeader {
+		c.header = first
+	} else {
+		c.pending = append(c.pending, first)
+	}
+	if err := c.resolveRules(); err != nil {
+		return nil, err
+	}
+
+	if c.detectOnly || c.policy.DetectRows > 0 {
+		if err := c.detect(); err != nil {
+			return nil, err
+		}
+		if c.detectOnly {
+			return c.columnReports(), nil
+		}
+	}
+
+	if c.header != nil {
+		if err := c.writer.Write(c.headerFields()); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.scrubRows(); err != nil {
+		return nil, err
+	}
+	c.writer.Flush()
+	if err := c.writer.Error(); err != nil {
+		return nil, err
+	}
+	return c.columnReports(), nil
+}
+
+func (c *csvScrubber) read() ([]string, error) {
+	row, err := c.reader.Read()
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+	}
+	return row, err
+}
+
+// looksLikeHeader tells whether the first row is a header
+func (c *csvScrubber) looksLikeHeader(row []string) (bool, error) {
+	seen := make(map[string]bool, len(row))
+	for _, field := range row {
+		name := strings.ToLower(strings.TrimSpace(field))
+		if name == "" || seen[name] {
+			return false, nil
+		}
+		if _, err := strconv.ParseFloat(name, 64); err == nil {
+			return false, nil
+		}
+		seen[name] = true
+	}
+
+	_, found, _, err := c.scrubTexts(row)
+	if err != nil {
+		return false, err
+	}
+	for _, ok := range found {
+		if ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// scrubTexts scrubs texts and tells which of them PII was found in. findings
+// is nil when the scrubber is not a FindingsScrubber, the texts it changed
+// are the ones PII was found in then
+func (c *csvScrubber) scrubTexts(texts []string) (scrubbedTexts []string, found []bool, findings [][]Finding, err error) {
+	if _, ok := c.policy.Scrubber.(FindingsScrubber); ok {
+		scrubbedTexts, findings, err = ScrubTextsWithFindings(c.policy.Scrubber, texts)
+	} else {
+		scrubbedTexts, err = c.policy.Scrubber.ScrubTexts(texts)
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	found = make([]bool, len(texts))
+	for i := range texts {
+		if findings != nil {
+			found[i] = len(findings[i]) > 0
+		} else {
+			found[i] = scrubbedTexts[i] != texts[i]
+		}
+	}
+	return scrubbedTexts, found, findings, nil
+}
+
+// resolveRules maps the rules to columns, a rule naming a column that is not
+// in the header is an error rather than a column left unscrubbed
+func (c *csvScrubber) resolveRules() error {
+	for _, rule := range c.policy.Rules {
+		if rule.Name == "" {
+			c.actions[rule.Index] = rule.Action
+			c.byIndex[rule.Index] = rule.Action
+			continue
+		}
+		if c.header == nil {
+			return fmt.Errorf("in CSV rule for column: %v, error: the input has no header", rule.Name)
+		}
+		index := -1
+		for i, name := range c.header {
+			if strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(rule.Name)) {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return fmt.Errorf("in CSV rule for column: %v, error: no such column in the header", rule.Name)
+		}
+		c.actions[index] = rule.Action
+		delete(c.byIndex, index)
+	}
+	return nil
+}
+
+func (c *csvScrubber) action(column int) Action {
+	if action, ok := c.actions[column]; ok {
+		return action
+	}
+	if action, ok := c.detected[column]; ok {
+		return action
+	}
+	return Scan
+}
+
+func (c *csvScrubber) report(column int) *CSVColumnReport {
+	for len(c.reports) <= column {
+		index := len(c.reports)
+		report := &CSVColumnReport{Index: index}
+		if index < len(c.header) {
+			report.Name = c.header[index]
+		}
+		c.reports = append(c.reports, report)
+	}
+	return c.reports[column]
+}
+
+func (c *csvScrubber) columnReports() []CSVColumnReport {
+	for i := range c.header {
+		c.report(i)
+	}
+	reports := make([]CSVColumnReport, len(c.reports))
+	for i, report := range c.reports {
+		reports[i] = *report
+		reports[i].Action = c.action(i)
+	}
+	return reports
+}
+
+// detect reads the sample rows and scans all their values, the columns no
+// rule selects that entities are found in are redacted
+func (c *csvScrubber) detect() error {
+	for c.policy.DetectRows == 0 || len(c.pending) < c.policy.DetectRows {
+		row, err := c.read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		c.pending = append(c.pending, row)
+	}
+
+	var texts []string
+	var columns []int
+	for _, row := range c.pending {
+		for column, value := range row {
+			if value != "" {
+				texts = append(texts, value)
+				columns = append(columns, column)
+			}
+		}
+	}
+	if len(texts) == 0 {
+		return nil
+	}
+	_, found, findings, err := c.scrubTexts(texts)
+	if err != nil {
+		return err
+	}
+
+	// counts are nil without findings
+	sampled := make(map[int]map[Entity]int)
+	for i, ok := range found {
+		if !ok {
+			continue
+		}
+		counts, seen := sampled[columns[i]]
+		if !seen && findings != nil {
+			counts = make(map[Entity]int)
+		}
+		sampled[columns[i]] = counts
+		if findings != nil {
+			for _, finding := range findings[i] {
+				counts[finding.Entity]++
+			}
+		}
+	}
+	for column, counts := range sampled {
+		if _, ok := c.actions[column]; !ok {
+			c.detected[column] = Redact
+		}
+		// scanned columns count their findings when they are scrubbed
+		if _, ok := c.detected[column]; ok || c.detectOnly {
+			c.report(column).Findings = counts
+		}
+	}
+	if c.detectOnly {
+		for _, row := range c.pending {
+			for column, value := range row {
+				if value != "" {
+					c.report(column).Values++
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// scrubRows scrubs the pending rows and the rest of the input in batches
+func (c *csvScrubber) scrubRows() error {
+	var texts []string
+	size := 0
+	flush := func() error {
+		if err := c.writeBatch(texts); err != nil {
+			return err
+		}
+		texts, size, c.pending = texts[:0], 0, c.pending[:0]
+		return nil
+	}
+
+	// rows read while sampling are batched like the others
+	sampled := c.pending
+	c.pending = nil
+	for i := 0; ; i++ {
+		var row []string
+		if i < len(sampled) {
+			row = sampled[i]
+		} else {
+			var err error
+			row, err = c.read()
+			if err == io.EOF {
+				return flush()
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		c.pending = append(c.pending, row)
+		for column, value := range row {
+			if value != "" && c.action(column) == Scan {
+				texts = append(texts, value)
+				size += len(value)
+			}
+		}
+		if len(c.pending) >= _csvBatchRows || size >= _csvBatchBytes {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// writeBatch writes the pending rows, texts being the values of their
+// scanned columns in order
+func (c *csvScrubber) writeBatch(texts []string) error {
+	var scrubbedTexts []string
+	var findings [][]Finding
+	if len(texts) > 0 {
+		var err error
+		scrubbedTexts, _, findings, err = c.scrubTexts(texts)
+		if err != nil {
+			return err
+		}
+	}
+
+	next := 0
+	for _, row := range c.pending {
+		out := make([]string, 0, len(row))
+		for column, value := range row {
+			action := c.action(column)
+			if action == Drop {
+				continue
+			}
+			if value == "" {
+				out = append(out, value)
+				continue
+			}
+
+			report := c.report(column)
+			report.Values++
+			scrubbed := value
+			switch action {
+			case Scan:
+				scrubbed = scrubbedTexts[next]
+				if findings != nil {
+					for _, finding := range findings[next] {
+						if report.Findings == nil {
+							report.Findings = make(map[Entity]int)
+						}
+						report.Findings[finding.Entity]++
+					}
+				}
+				next++
+			case Redact:
+				scrubbed = _redactedValue
+			case Hash:
+				scrubbed = c.hasher.Redact(value)
+			}
+			if scrubbed != value {
+				report.Changed++
+			}
+			out = append(out, scrubbed)
+		}
+		if err := c.writer.Write(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// headerFields returns the header without the dropped columns. A detected
+// header may be a data row that looks like one, so the columns redacted or
+// hashed by index are redacted or hashed in it too
+func (c *csvScrubber) headerFields() []string {
+	out := make([]string, 0, len(c.header))
+	for column, value := range c.header {
+		if c.action(column) == Drop {
+			continue
+		}
+		if c.policy.Header == DetectCSVHeader && value != "" {
+			switch c.byIndex[column] {
+			case Redact:
+				value = _redactedValue
+			case Hash:
+				value = c.hasher.Redact(value)
+			}
+		}
+		out = append(out, value)
+	}
+	return out
+}
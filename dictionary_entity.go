@@ -0,0 +1,286 @@
+package piiscrubber
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DictionaryEntityOptions ...
+type DictionaryEntityOptions struct {
+	// CaseSensitive only matches terms with the case they are given in
+	CaseSensitive bool
+	// MatchInsideWords also matches terms that are part of a longer word,
+	// e.g. "Acme" in "Acmeplex"
+	MatchInsideWords bool
+	// Fuzzy ignores differences in punctuation and whitespace, e.g. "Acme,
+	// Inc." matches "acme inc" and "ACME-Inc". Punctuation at the start and
+	// end of terms is ignored
+	Fuzzy bool
+	// Placeholder replaces the matches of entities without a config,
+	// <REDACTED> by default
+	Placeholder string
+}
+
+// DictionaryEntityScrubber is an EntityScrubber detecting a list of literal
+// terms, e.g. the names of customers or employees. All the terms are matched
+// in a single pass over the text with an Aho-Corasick automaton, so the cost
+// of matching hardly depends on the number of terms. Overlapping matches
+// are resolved to the leftmost, longest one
+type DictionaryEntityScrubber struct {
+	opts        DictionaryEntityOptions
+	placeholder string
+	automaton   atomic.Pointer[dictionaryAutomaton]
+}
+
+// NewDictionaryEntityScrubber returns a DictionaryEntityScrubber matching
+// the terms, opts may be nil
+func NewDictionaryEntityScrubber(terms []string, opts *DictionaryEntityOptions) *DictionaryEntityScrubber {
+	s := &DictionaryEntityScrubber{placeholder: _redactedValue}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if s.opts.Placeholder != "" {
+		s.placeholder = s.opts.Placeholder
+	}
+	s.Update(terms)
+	return s
+}
+
+// Update replaces the terms of the scrubber. It is safe to call while texts
+// are being scrubbed, which see either the old or the new terms
+func (s *DictionaryEntityScrubber) Update(terms []string) {
+	automaton := newDictionaryAutomaton()
+	for _, term := range terms {
+		normalized := s.normalize(term, nil)
+		if s.opts.Fuzzy {
+			normalized.text = []byte(strings.Trim(string(normalized.text), " "))
+		}
+		automaton.add(normalized.text)
+	}
+	automaton.build()
+	s.automaton.Store(automaton)
+}
+
+// LoadDictionary reads the terms of a dictionary file, see ReadDictionary
+func LoadDictionary(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadDictionary(file)
+}
+
+// ReadDictionary reads one term per line, surrounding whitespace, empty
+// lines and lines starting with # are skipped
+func ReadDictionary(r io.Reader) ([]string, error) {
+	var terms []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		term := strings.TrimSpace(scanner.Text())
+		if term == "" || strings.HasPrefix(term, "#") {
+			continue
+		}
+		terms = append(terms, term)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return terms, nil
+}
+
+// Match ...
+func (s *DictionaryEntityScrubber) Match(text string) [][]int {
+	automaton := s.automaton.Load()
+	normalized := s.normalize(text, make([]int, 0, len(text)+1))
+
+	var candidates [][]int
+	automaton.match(normalized.text, func(start, end int) {
+		// offsets of the original text, end is just past the last rune
+		match := []int{normalized.offsets[start], normalized.offsets[end]}
+		if s.opts.MatchInsideWords || isWordBoundary(text, match[0], match[1]) {
+			candidates = append(candidates, match)
+		}
+	})
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i][0] != candidates[j][0] {
+			return candidates[i][0] < candidates[j][0]
+		}
+		return candidates[i][1] > candidates[j][1]
+	})
+	matches := candidates[:0]
+	for _, candidate := range candidates {
+		if len(matches) > 0 && candidate[0] < matches[len(matches)-1][1] {
+			continue
+		}
+		matches = append(matches, candidate)
+	}
+	return matches
+}
+
+// Mask ...
+func (s *DictionaryEntityScrubber) Mask(detectedEntity []byte, config *EntityConfig) []byte {
+	if config == nil {
+		return []byte(s.placeholder)
+	}
+	return NativeMasking(detectedEntity, config)
+}
+
+// normalizedText is a text folded for matching, offsets holds the offset in
+// the original text of every byte of text, and of its end
+type normalizedText struct {
+	text    []byte
+	offsets []int
+}
+
+// normalize folds the case of text unless the scrubber is case sensitive,
+// and replaces runs of punctuation and whitespace with a single space when
+// it is fuzzy. offsets are only recorded when it is not nil
+func (s *DictionaryEntityScrubber) normalize(text string, offsets []int) normalizedText {
+	normalized := normalizedText{text: make([]byte, 0, len(text)), offsets: offsets}
+	separated := false
+	for i, r := range text {
+		if s.opts.Fuzzy && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if separated {
+				continue
+			}
+			separated = true
+			r = ' '
+		} else {
+			separated = false
+			if !s.opts.CaseSensitive {
+				r = unicode.ToLower(r)
+			}
+		}
+		n := len(normalized.text)
+		normalized.text = utf8.AppendRune(normalized.text, r)
+		if offsets != nil {
+			for j := n; j < len(normalized.text); j++ {
+				normalized.offsets = append(normalized.offsets, i)
+			}
+		}
+	}
+	if offsets != nil {
+		normalized.offsets = append(normalized.offsets, len(text))
+	}
+	return normalized
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// isWordBoundary reports whether text[start:end] is neither preceded nor
+// followed by a letter, digit or underscore
+func isWordBoundary(text string, start, end int) bool {
+	if r, _ := utf8.DecodeLastRuneInString(text[:start]); start > 0 && isWordRune(r) {
+		return false
+	}
+	if r, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && isWordRune(r) {
+		return false
+	}
+	return true
+}
+
+// dictionaryAutomaton is an Aho-Corasick automaton over the bytes of the
+// normalized terms
+type dictionaryAutomaton struct {
+	nodes []dictionaryNode
+}
+
+type dictionaryNode struct {
+	next map[byte]int32
+	fail int32
+	// output is the nearest node on the fail chain that ends a term, or -1
+	output int32
+	// length is the length of the term the node ends, 0 if it ends none
+	length int32
+}
+
+func newDictionaryAutomaton() *dictionaryAutomaton {
+	return &dictionaryAutomaton{nodes: []dictionaryNode{{output: -1}}}
+}
+
+func (a *dictionaryAutomaton) add(term []byte) {
+	if len(term) == 0 {
+		return
+	}
+	node := int32(0)
+	for _, c := range term {
+		next, ok := a.nodes[node].next[c]
+		if !ok {
+			next = int32(len(a.nodes))
+			a.nodes = append(a.nodes, dictionaryNode{output: -1})
+			if a.nodes[node].next == nil {
+				a.nodes[node].next = make(map[byte]int32)
+			}
+			a.nodes[node].next[c] = next
+		}
+		node = next
+	}
+	a.nodes[node].length = int32(len(term))
+}
+
+// build sets the fail and output links, breadth first so that the links of
+// shorter prefixes are set before they are followed
+func (a *dictionaryAutomaton) build() {
+	queue := make([]int32, 0, len(a.nodes))
+	for _, child := range a.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for c, child := range a.nodes[node].next {
+			fail := a.nodes[node].fail
+			for {
+				if next, ok := a.nodes[fail].next[c]; ok {
+					a.nodes[child].fail = next
+					break
+				}
+				if fail == 0 {
+					break
+				}
+				fail = a.nodes[fail].fail
+			}
+			if failNode := a.nodes[a.nodes[child].fail]; failNode.length > 0 {
+				a.nodes[child].output = a.nodes[child].fail
+			} else {
+				a.nodes[child].output = failNode.output
+			}
+			queue = append(queue, child)
+		}
+	}
+}
+
+// match calls found with the start and end of every term in text,
+// including overlapping ones
+func (a *dictionaryAutomaton) match(text []byte, found func(start, end int)) {
+	node := int32(0)
+	for i, c := range text {
+		for {
+			if next, ok := a.nodes[node].next[c]; ok {
+				node = next
+				break
+			}
+			if node == 0 {
+				break
+			}
+			node = a.nodes[node].fail
+		}
+		output := node
+		if a.nodes[output].length == 0 {
+			output = a.nodes[output].output
+		}
+		for ; output > 0; output = a.nodes[output].output {
+			found(i+1-int(a.nodes[output].length), i+1)
+		}
+	}
+}
@@ -2,23 +2,10 @@ package main
 
 import (
 	"fmt"
-	"regexp"
 
 	piiscrubber "github.com/aavaz-ai/pii-scrubber"
 )
 
-type orgNameEntityScrubber struct {
-}
-
-func (s *orgNameEntityScrubber) Match(text string) [][]int {
-	regex := regexp.MustCompile("Enterpret")
-	return regex.FindAllStringIndex(text, -1)
-}
-
-func (s *orgNameEntityScrubber) Mask(detectedEntity []byte, config *piiscrubber.EntityConfig) []byte {
-	return []byte("<ORG_PLACEHOLDER>")
-}
-
 func main() {
 
 	texts := []string{
@@ -27,6 +14,16 @@ func main() {
 
 	orgNameEntity := piiscrubber.Entity("ORG_NAME")
 
+	// the pattern is compiled once, here, instead of on every Match call
+	orgNameEntityScrubber, err := piiscrubber.NewRegexEntityScrubberWithOptions(&piiscrubber.RegexEntityOptions{
+		WordBoundaries: true,
+		IgnoreCase:     true,
+		Placeholder:    "<ORG_PLACEHOLDER>",
+	}, "Enterpret")
+	if err != nil {
+		panic(err)
+	}
+
 	scrubber, err := piiscrubber.NewWithCustomEntityScrubbers(piiscrubber.NewWithCustomEntityScrubbersParams{
 		BlacklistedEntities: []piiscrubber.Entity{
 			piiscrubber.CreditCard,
@@ -36,7 +33,7 @@ func main() {
 			orgNameEntity,
 		},
 		CustomEntityScrubbers: map[piiscrubber.Entity]piiscrubber.EntityScrubber{
-			orgNameEntity: &orgNameEntityScrubber{},
+			orgNameEntity: orgNameEntityScrubber,
 		},
 	})
 	if err != nil {
@@ -0,0 +1,84 @@
+package piiscrubber
+
+// TextBatch collects strings so that they can be scrubbed with a single
+// ScrubTexts call and written back in place. It is used by the ScrubPII
+// methods generated with cmd/pii-scrubgen
+type TextBatch struct {
+	targets []*string
+}
+
+// Add queues the string pointed to by text for scrubbing
+func (b *TextBatch) Add(text *string) {
+	b.targets = append(b.targets, text)
+}
+
+// Scrub scrubs all queued strings and writes the results back
+func (b *TextBatch) Scrub(s Scrubber) error {
+	if len(b.targets) == 0 {
+		return nil
+	}
+
+	texts := make([]string, 0, len(b.targets))
+	for _, target := range b.targets {
+		texts = append(texts, *target)
+	}
+
+	scrubbedTexts, err := s.ScrubTexts(texts)
+	if err != nil {
+		return err
+	}
+
+	for i, target := range b.targets {
+		*target = scrubbedTexts[i]
+	}
+	b.targets = b.targets[:0]
+	return nil
+}
+
+type taggedValue struct {
+	V interface{} `pii:"true"`
+}
+
+type untaggedValue struct {
+	V interface{}
+}
+
+// NeedsScrubStruct reports whether the ScrubPII methods generated with
+// cmd/pii-scrubgen must hand their value to s.ScrubStruct to produce its
+// result: when field rules apply, which are only known at run time, or when
+// s is not one of the Scrubbers of this package
+func NeedsScrubStruct(s Scrubber) bool {
+	sc, ok := s.(*scrubber)
+	if !ok {
+		return true
+	}
+	return len(sc.fieldRules) > 0 || len(registeredFieldRules()) > 0
+}
+
+// ScrubValue returns a scrubbed copy of v, treating it like a struct field
+// that is tagged with pii:"true" when tagged is set. It is used by the
+// ScrubPII methods generated with cmd/pii-scrubgen for values they don't walk
+// themselves, such as maps and interfaces
+func ScrubValue(s Scrubber, v interface{}, tagged bool) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	if sc, ok := s.(*scrubber); ok {
+		return sc.parseValue(v, tagged, nil)
+	}
+
+	if tagged {
+		copy, err := s.ScrubStruct(taggedValue{V: v})
+		if err != nil {
+			return nil, err
+		}
+		return copy.(taggedValue).V, nil
+	}
+
+	copy, err := s.ScrubStruct(untaggedValue{V: v})
+	if err != nil {
+		return nil, err
+	}
+	return copy.(untaggedValue).V, nil
+}
@@ -1,8 +1,14 @@
 module github.com/aavaz-ai/pii-scrubber
 
-go 1.16
+go 1.21
 
 require (
 	github.com/anshal21/go-worker v1.1.0
 	github.com/stretchr/testify v1.8.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
 )
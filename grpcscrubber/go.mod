@@ -0,0 +1,23 @@
+module github.com/aavaz-ai/pii-scrubber/grpcscrubber
+
+go 1.22.0
+
+require (
+	github.com/aavaz-ai/pii-scrubber v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.8.1
+	google.golang.org/grpc v1.70.0
+	google.golang.org/protobuf v1.36.5
+)
+
+require (
+	github.com/anshal21/go-worker v1.1.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/net v0.32.0 // indirect
+	golang.org/x/sys v0.28.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20241202173237-19429a94021a // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/aavaz-ai/pii-scrubber => ../
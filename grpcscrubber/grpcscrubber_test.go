@@ -0,0 +1,189 @@
+package grpcscrubber_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/aavaz-ai/pii-scrubber/grpcscrubber"
+	"github.com/aavaz-ai/pii-scrubber/grpcscrubber/internal/testpb"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+func newTestScrubber(t *testing.T) piiscrubber.Scrubber {
+	scrubber, err := piiscrubber.New(piiscrubber.Params{
+		BlacklistedEntities: []piiscrubber.Entity{
+			piiscrubber.Email,
+			piiscrubber.Phone,
+		},
+	})
+	assert.NoError(t, err)
+	return scrubber
+}
+
+func newTestUser() *testpb.User {
+	return &testpb.User{
+		Id:       "jane@example.com",
+		Name:     "Jane <jane@example.com>",
+		Email:    "jane@example.com, +919140520809",
+		Password: "hunter2",
+		Notes:    []string{"call +919140520809"},
+		Address:  &testpb.Address{Street: "mail jane@example.com"},
+		Attributes: map[string]string{
+			"backup": "john@example.com",
+		},
+		Contact: &testpb.User_Phone{Phone: "+919140520809"},
+		Support: "help@example.com",
+	}
+}
+
+var _expectedUser = &testpb.User{
+	Id:   "jane@example.com",
+	Name: "Jane <<EMAIL_ADDRESS>>",
+	// only emails are scrubbed out of this one
+	Email:    "<EMAIL_ADDRESS>, +919140520809",
+	Password: "<REDACTED>",
+	Notes:    []string{"call <PHONE_NUMBER>"},
+	Address:  &testpb.Address{Street: "mail <EMAIL_ADDRESS>"},
+	Attributes: map[string]string{
+		"backup": "<EMAIL_ADDRESS>",
+	},
+	Contact: &testpb.User_Phone{Phone: "<PHONE_NUMBER>"},
+	Support: "help@example.com",
+}
+
+func TestScrubMessage(t *testing.T) {
+	scrubber := newTestScrubber(t)
+
+	user := newTestUser()
+	scrubbed, err := grpcscrubber.ScrubMessage(scrubber, user)
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(_expectedUser, scrubbed), "got %v", scrubbed)
+
+	// the original is left as it is
+	assert.True(t, proto.Equal(newTestUser(), user))
+}
+
+func TestScrubMessage_NestedRules(t *testing.T) {
+	profile := &testpb.Profile{
+		User: &testpb.User{
+			Id:      "jane@example.com",
+			Support: "help@example.com",
+		},
+		Display: "jane@example.com",
+	}
+
+	scrubbed, err := grpcscrubber.ScrubMessage(newTestScrubber(t), profile)
+	assert.NoError(t, err)
+
+	// the rules of the user field apply to all of its strings but the ones
+	// with rules of their own
+	assert.True(t, proto.Equal(&testpb.Profile{
+		User: &testpb.User{
+			Id:      "jane@example.com",
+			Support: "<EMAIL_ADDRESS>",
+		},
+		Display: "jane@example.com",
+	}, scrubbed), "got %v", scrubbed)
+}
+
+type usersServer struct {
+	testpb.UnimplementedUsersServer
+	queries []string
+}
+
+func (s *usersServer) GetUser(ctx context.Context, req *testpb.GetUserRequest) (*testpb.User, error) {
+	s.queries = append(s.queries, req.GetQuery())
+	return newTestUser(), nil
+}
+
+func (s *usersServer) WatchUsers(req *testpb.GetUserRequest, stream testpb.Users_WatchUsersServer) error {
+	s.queries = append(s.queries, req.GetQuery())
+	for i := 0; i < 2; i++ {
+		if err := stream.Send(newTestUser()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newTestClient(t *testing.T, server *usersServer, serverOpts []grpc.ServerOption, dialOpts ...grpc.DialOption) testpb.UsersClient {
+	listener := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer(serverOpts...)
+	testpb.RegisterUsersServer(s, server)
+	go s.Serve(listener)
+	t.Cleanup(s.Stop)
+
+	dialOpts = append(dialOpts,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+	)
+	conn, err := grpc.NewClient("passthrough:///bufconn", dialOpts...)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return testpb.NewUsersClient(conn)
+}
+
+func TestServerInterceptors(t *testing.T) {
+	scrubber := newTestScrubber(t)
+	server := &usersServer{}
+	client := newTestClient(t, server, []grpc.ServerOption{
+		grpc.UnaryInterceptor(grpcscrubber.UnaryServerInterceptor(scrubber, nil)),
+		grpc.StreamInterceptor(grpcscrubber.StreamServerInterceptor(scrubber, nil)),
+	})
+
+	user, err := client.GetUser(context.Background(), &testpb.GetUserRequest{Query: "jane@example.com"})
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(_expectedUser, user), "got %v", user)
+
+	stream, err := client.WatchUsers(context.Background(), &testpb.GetUserRequest{Query: "jane@example.com"})
+	assert.NoError(t, err)
+	received := 0
+	for {
+		user, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		assert.True(t, proto.Equal(_expectedUser, user), "got %v", user)
+		received++
+	}
+	assert.Equal(t, 2, received)
+
+	// requests are only scrubbed with Incoming
+	assert.Equal(t, []string{"jane@example.com", "jane@example.com"}, server.queries)
+}
+
+func TestClientInterceptors(t *testing.T) {
+	scrubber := newTestScrubber(t)
+	server := &usersServer{}
+	opts := &grpcscrubber.Options{Incoming: true, Outgoing: true}
+	client := newTestClient(t, server, nil,
+		grpc.WithUnaryInterceptor(grpcscrubber.UnaryClientInterceptor(scrubber, opts)),
+		grpc.WithStreamInterceptor(grpcscrubber.StreamClientInterceptor(scrubber, opts)),
+	)
+
+	request := &testpb.GetUserRequest{Query: "jane@example.com"}
+	user, err := client.GetUser(context.Background(), request)
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(_expectedUser, user), "got %v", user)
+	// the caller's request is left as it is
+	assert.Equal(t, "jane@example.com", request.GetQuery())
+
+	stream, err := client.WatchUsers(context.Background(), request)
+	assert.NoError(t, err)
+	user, err = stream.Recv()
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(_expectedUser, user), "got %v", user)
+
+	assert.Equal(t, []string{"<EMAIL_ADDRESS>", "<EMAIL_ADDRESS>"}, server.queries)
+}
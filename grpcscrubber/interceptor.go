@@ -0,0 +1,179 @@
+package grpcscrubber
+
+import (
+	"context"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Options ...
+type Options struct {
+	// Incoming scrubs the messages received, requests on servers and
+	// responses on clients, before they are handed over
+	Incoming bool
+	// Outgoing scrubs the messages sent, responses on servers and requests on
+	// clients
+	Outgoing bool
+}
+
+// interceptor scrubs the messages going in one direction, messages that are
+// not protobuf messages are passed on as they are
+type interceptor struct {
+	scrubber piiscrubber.Scrubber
+	opts     Options
+}
+
+func newInterceptor(s piiscrubber.Scrubber, opts *Options) *interceptor {
+	if opts == nil {
+		// never let PII leave the process by default
+		opts = &Options{Outgoing: true}
+	}
+	return &interceptor{scrubber: s, opts: *opts}
+}
+
+// scrubbedCopy returns a scrubbed copy of v
+func (i *interceptor) scrubbedCopy(v interface{}) (interface{}, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return v, nil
+	}
+
+	scrubbed, err := ScrubMessage(i.scrubber, m)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "scrubbing %T: %v", v, err)
+	}
+	return scrubbed, nil
+}
+
+// scrubInPlace scrubs v, which was received into by the caller
+func (i *interceptor) scrubInPlace(v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil
+	}
+
+	if err := scrubMessage(i.scrubber, m); err != nil {
+		return status.Errorf(codes.Internal, "scrubbing %T: %v", v, err)
+	}
+	return nil
+}
+
+// UnaryServerInterceptor returns an interceptor that scrubs responses, and
+// requests when opts.Incoming is set. opts defaults to scrubbing responses
+func UnaryServerInterceptor(s piiscrubber.Scrubber, opts *Options) grpc.UnaryServerInterceptor {
+	i := newInterceptor(s, opts)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if i.opts.Incoming {
+			var err error
+			if req, err = i.scrubbedCopy(req); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := handler(ctx, req)
+		if err != nil || !i.opts.Outgoing {
+			return resp, err
+		}
+		return i.scrubbedCopy(resp)
+	}
+}
+
+// StreamServerInterceptor returns an interceptor that scrubs the messages
+// sent on server streams, and the messages received when opts.Incoming is
+// set. opts defaults to scrubbing the messages sent
+func StreamServerInterceptor(s piiscrubber.Scrubber, opts *Options) grpc.StreamServerInterceptor {
+	i := newInterceptor(s, opts)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &serverStream{ServerStream: ss, interceptor: i})
+	}
+}
+
+// UnaryClientInterceptor returns an interceptor that scrubs requests, and
+// responses when opts.Incoming is set. opts defaults to scrubbing requests
+func UnaryClientInterceptor(s piiscrubber.Scrubber, opts *Options) grpc.UnaryClientInterceptor {
+	i := newInterceptor(s, opts)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		if i.opts.Outgoing {
+			var err error
+			if req, err = i.scrubbedCopy(req); err != nil {
+				return err
+			}
+		}
+
+		if err := invoker(ctx, method, req, reply, cc, callOpts...); err != nil {
+			return err
+		}
+		if i.opts.Incoming {
+			return i.scrubInPlace(reply)
+		}
+		return nil
+	}
+}
+
+// StreamClientInterceptor returns an interceptor that scrubs the messages
+// sent on client streams, and the messages received when opts.Incoming is
+// set. opts defaults to scrubbing the messages sent
+func StreamClientInterceptor(s piiscrubber.Scrubber, opts *Options) grpc.StreamClientInterceptor {
+	i := newInterceptor(s, opts)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		cs, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, interceptor: i}, nil
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	interceptor *interceptor
+}
+
+func (s *serverStream) SendMsg(m interface{}) error {
+	if s.interceptor.opts.Outgoing {
+		var err error
+		if m, err = s.interceptor.scrubbedCopy(m); err != nil {
+			return err
+		}
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if s.interceptor.opts.Incoming {
+		return s.interceptor.scrubInPlace(m)
+	}
+	return nil
+}
+
+type clientStream struct {
+	grpc.ClientStream
+	interceptor *interceptor
+}
+
+func (s *clientStream) SendMsg(m interface{}) error {
+	if s.interceptor.opts.Outgoing {
+		var err error
+		if m, err = s.interceptor.scrubbedCopy(m); err != nil {
+			return err
+		}
+	}
+	return s.ClientStream.SendMsg(m)
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	if err := s.ClientStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if s.interceptor.opts.Incoming {
+		return s.interceptor.scrubInPlace(m)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.5
+// 	protoc        (unknown)
+// source: pii/pii.proto
+
+package piipb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// FieldRules marks a field as holding PII, the same way the pii struct tag
+// does. On message, repeated and map fields they apply to every string held
+// by the field, unless a nested field has rules of its own
+type FieldRules struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// entities limits scrubbing to these entities, e.g. "EMAIL". Every entity
+	// of the scrubber is scrubbed when it is empty
+	Entities []string `protobuf:"bytes,1,rep,name=entities,proto3" json:"entities,omitempty"`
+	// redact replaces the whole value with <REDACTED>
+	Redact bool `protobuf:"varint,2,opt,name=redact,proto3" json:"redact,omitempty"`
+	// keep leaves the value as it is, even inside a field with rules
+	Keep          bool `protobuf:"varint,3,opt,name=keep,proto3" json:"keep,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FieldRules) Reset() {
+	*x = FieldRules{}
+	mi := &file_pii_pii_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FieldRules) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FieldRules) ProtoMessage() {}
+
+func (x *FieldRules) ProtoReflect() protoreflect.Message {
+	mi := &file_pii_pii_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FieldRules.ProtoReflect.Descriptor instead.
+func (*FieldRules) Descriptor() ([]byte, []int) {
+	return file_pii_pii_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *FieldRules) GetEntities() []string {
+	if x != nil {
+		return x.Entities
+	}
+	return nil
+}
+
+func (x *FieldRules) GetRedact() bool {
+	if x != nil {
+		return x.Redact
+	}
+	return false
+}
+
+func (x *FieldRules) GetKeep() bool {
+	if x != nil {
+		return x.Keep
+	}
+	return false
+}
+
+var file_pii_pii_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.FieldOptions)(nil),
+		ExtensionType: (*FieldRules)(nil),
+		Field:         50731,
+		Name:          "pii.field",
+		Tag:           "bytes,50731,opt,name=field",
+		Filename:      "pii/pii.proto",
+	},
+}
+
+// Extension fields to descriptorpb.FieldOptions.
+var (
+	// string email = 1 [(pii.field) = {entities: "EMAIL"}];
+	//
+	// optional pii.FieldRules field = 50731;
+	E_Field = &file_pii_pii_proto_extTypes[0]
+)
+
+var File_pii_pii_proto protoreflect.FileDescriptor
+
+var file_pii_pii_proto_rawDesc = string([]byte{
+	0x0a, 0x0d, 0x70, 0x69, 0x69, 0x2f, 0x70, 0x69, 0x69, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
+	0x03, 0x70, 0x69, 0x69, 0x1a, 0x20, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x6f, 0x72,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x54, 0x0a, 0x0a, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x52,
+	0x75, 0x6c, 0x65, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x65, 0x6e, 0x74, 0x69, 0x74, 0x69, 0x65, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08, 0x65, 0x6e, 0x74, 0x69, 0x74, 0x69, 0x65, 0x73,
+	0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x64, 0x61, 0x63, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x06, 0x72, 0x65, 0x64, 0x61, 0x63, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x6b, 0x65, 0x65, 0x70,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x04, 0x6b, 0x65, 0x65, 0x70, 0x3a, 0x46, 0x0a, 0x05,
+	0x66, 0x69, 0x65, 0x6c, 0x64, 0x12, 0x1d, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x4f, 0x70, 0x74,
+	0x69, 0x6f, 0x6e, 0x73, 0x18, 0xab, 0x8c, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x70,
+	0x69, 0x69, 0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x52, 0x75, 0x6c, 0x65, 0x73, 0x52, 0x05, 0x66,
+	0x69, 0x65, 0x6c, 0x64, 0x42, 0x35, 0x5a, 0x33, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
+	0x6f, 0x6d, 0x2f, 0x61, 0x61, 0x76, 0x61, 0x7a, 0x2d, 0x61, 0x69, 0x2f, 0x70, 0x69, 0x69, 0x2d,
+	0x73, 0x63, 0x72, 0x75, 0x62, 0x62, 0x65, 0x72, 0x2f, 0x67, 0x72, 0x70, 0x63, 0x73, 0x63, 0x72,
+	0x75, 0x62, 0x62, 0x65, 0x72, 0x2f, 0x70, 0x69, 0x69, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x33,
+})
+
+var (
+	file_pii_pii_proto_rawDescOnce sync.Once
+	file_pii_pii_proto_rawDescData []byte
+)
+
+func file_pii_pii_proto_rawDescGZIP() []byte {
+	file_pii_pii_proto_rawDescOnce.Do(func() {
+		file_pii_pii_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_pii_pii_proto_rawDesc), len(file_pii_pii_proto_rawDesc)))
+	})
+	return file_pii_pii_proto_rawDescData
+}
+
+var file_pii_pii_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_pii_pii_proto_goTypes = []any{
+	(*FieldRules)(nil),                // 0: pii.FieldRules
+	(*descriptorpb.FieldOptions)(nil), // 1: google.protobuf.FieldOptions
+}
+var file_pii_pii_proto_depIdxs = []int32{
+	1, // 0: pii.field:extendee -> google.protobuf.FieldOptions
+	0, // 1: pii.field:type_name -> pii.FieldRules
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	1, // [1:2] is the sub-list for extension type_name
+	0, // [0:1] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_pii_pii_proto_init() }
+func file_pii_pii_proto_init() {
+	if File_pii_pii_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pii_pii_proto_rawDesc), len(file_pii_pii_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 1,
+			NumServices:   0,
+		},
+		GoTypes:           file_pii_pii_proto_goTypes,
+		DependencyIndexes: file_pii_pii_proto_depIdxs,
+		MessageInfos:      file_pii_pii_proto_msgTypes,
+		ExtensionInfos:    file_pii_pii_proto_extTypes,
+	}.Build()
+	File_pii_pii_proto = out.File
+	file_pii_pii_proto_goTypes = nil
+	file_pii_pii_proto_depIdxs = nil
+}
@@ -0,0 +1,244 @@
+// Package grpcscrubber scrubs protobuf messages with a piiscrubber.Scrubber,
+// driven by the (pii.field) option defined in proto/pii/pii.proto, and
+// provides gRPC interceptors that scrub the messages passing through them
+//
+//	import "pii/pii.proto";
+//
+//	message User {
+//	  string email = 1 [(pii.field) = {entities: "EMAIL"}];
+//	  string password = 2 [(pii.field) = {redact: true}];
+//	}
+//
+// It lives in its own module so that the library doesn't depend on gRPC
+package grpcscrubber
+
+//go:generate protoc -I proto --go_out=. --go_opt=module=github.com/aavaz-ai/pii-scrubber/grpcscrubber pii/pii.proto
+//go:generate protoc -I proto -I internal/testpb --go_out=. --go_opt=module=github.com/aavaz-ai/pii-scrubber/grpcscrubber --go-grpc_out=. --go-grpc_opt=module=github.com/aavaz-ai/pii-scrubber/grpcscrubber test.proto
+
+import (
+	"sync"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/aavaz-ai/pii-scrubber/grpcscrubber/piipb"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const _redactedValue = "<REDACTED>"
+
+// fieldState tells the walker what to do with the strings of a field
+type fieldState struct {
+	scan   bool
+	redact bool
+	// entities limits scanning to these entities, nil scans for all of them
+	entities map[piiscrubber.Entity]bool
+}
+
+// _fieldStates caches the state set by the (pii.field) option of a field
+// descriptor, nil when the field has no option
+var _fieldStates sync.Map
+
+func fieldStateOf(fd protoreflect.FieldDescriptor) *fieldState {
+	if cached, ok := _fieldStates.Load(fd); ok {
+		return cached.(*fieldState)
+	}
+
+	var state *fieldState
+	if options := fd.Options(); options != nil && proto.HasExtension(options, piipb.E_Field) {
+		rules := proto.GetExtension(options, piipb.E_Field).(*piipb.FieldRules)
+		state = &fieldState{
+			scan:   !rules.GetKeep(),
+			redact: rules.GetRedact() && !rules.GetKeep(),
+		}
+		if len(rules.GetEntities()) > 0 {
+			state.entities = make(map[piiscrubber.Entity]bool, len(rules.GetEntities()))
+			for _, entity := range rules.GetEntities() {
+				state.entities[piiscrubber.Entity(entity)] = true
+			}
+		}
+	}
+
+	_fieldStates.Store(fd, state)
+	return state
+}
+
+// ScrubMessage returns a scrubbed copy of m. Strings in fields with the
+// (pii.field) option, and in the messages, lists and maps held by such
+// fields, are scrubbed with s. Map keys are left as they are
+func ScrubMessage(s piiscrubber.Scrubber, m proto.Message) (proto.Message, error) {
+	if m == nil {
+		return nil, nil
+	}
+
+	copy := proto.Clone(m)
+	if err := scrubMessage(s, copy); err != nil {
+		return nil, err
+	}
+	return copy, nil
+}
+
+// scrubMessage scrubs m in place
+func scrubMessage(s piiscrubber.Scrubber, m proto.Message) error {
+	w := &walker{}
+	w.walk(m.ProtoReflect(), fieldState{})
+	return w.scrub(s)
+}
+
+// target is a string collected by the walker and the function that puts its
+// scrubbed form back
+type target struct {
+	text     string
+	entities map[piiscrubber.Entity]bool
+	set      func(string)
+}
+
+// walker collects the strings of a message so that they can be scrubbed
+// with a single ScrubTexts call
+type walker struct {
+	targets []target
+}
+
+func (w *walker) walk(m protoreflect.Message, state fieldState) {
+	// fields are collected first since setting them while ranging over the
+	// message is not allowed
+	var fields []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		fields = append(fields, fd)
+		return true
+	})
+
+	for _, fd := range fields {
+		fieldState := state
+		if own := fieldStateOf(fd); own != nil {
+			fieldState = *own
+		}
+
+		switch {
+		case fd.IsList():
+			list := m.Mutable(fd).List()
+			for i := 0; i < list.Len(); i++ {
+				i := i
+				w.walkValue(fd, list.Get(i), fieldState, func(v protoreflect.Value) {
+					list.Set(i, v)
+				})
+			}
+
+		case fd.IsMap():
+			mapValue := m.Mutable(fd).Map()
+			var keys []protoreflect.MapKey
+			mapValue.Range(func(key protoreflect.MapKey, _ protoreflect.Value) bool {
+				keys = append(keys, key)
+				return true
+			})
+			for _, key := range keys {
+				key := key
+				w.walkValue(fd.MapValue(), mapValue.Get(key), fieldState, func(v protoreflect.Value) {
+					mapValue.Set(key, v)
+				})
+			}
+
+		case fd.Message() != nil:
+			w.walk(m.Mutable(fd).Message(), fieldState)
+
+		default:
+			fd := fd
+			w.walkValue(fd, m.Get(fd), fieldState, func(v protoreflect.Value) {
+				m.Set(fd, v)
+			})
+		}
+	}
+}
+
+// walkValue visits a single value of kind fd.Kind(), set replaces it
+func (w *walker) walkValue(fd protoreflect.FieldDescriptor, v protoreflect.Value, state fieldState, set func(protoreflect.Value)) {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		// messages held by mutable lists and maps are mutable themselves
+		w.walk(v.Message(), state)
+
+	case protoreflect.StringKind:
+		switch {
+		case state.redact:
+			set(protoreflect.ValueOfString(_redactedValue))
+		case state.scan:
+			w.targets = append(w.targets, target{
+				text:     v.String(),
+				entities: state.entities,
+				set: func(text string) {
+					set(protoreflect.ValueOfString(text))
+				},
+			})
+		}
+	}
+}
+
+// scrub scrubs the collected strings and puts them back. Strings limited to
+// some entities are scrubbed with ScrubTextsWithFindings, and only the
+// findings of those entities are masked
+func (w *walker) scrub(s piiscrubber.Scrubber) error {
+	if len(w.targets) == 0 {
+		return nil
+	}
+
+	texts := make([]string, 0, len(w.targets))
+	for _, t := range w.targets {
+		texts = append(texts, t.text)
+	}
+
+	scrubbedTexts, findings, err := piiscrubber.ScrubTextsWithFindings(s, texts)
+	if err != nil {
+		return err
+	}
+
+	// pieces are the findings kept in texts limited to some entities, masked
+	// on their own
+	var pieces []string
+	for i, t := range w.targets {
+		if t.entities == nil {
+			continue
+		}
+		for _, finding := range findings[i] {
+			if t.entities[finding.Entity] {
+				pieces = append(pieces, t.text[finding.Start:finding.End])
+			}
+		}
+	}
+
+	var maskedPieces []string
+	var pieceFindings [][]piiscrubber.Finding
+	if len(pieces) > 0 {
+		maskedPieces, pieceFindings, err = piiscrubber.ScrubTextsWithFindings(s, pieces)
+		if err != nil {
+			return err
+		}
+	}
+
+	next := 0
+	for i, t := range w.targets {
+		if t.entities == nil {
+			t.set(scrubbedTexts[i])
+			continue
+		}
+
+		var out []byte
+		last := 0
+		for _, finding := range findings[i] {
+			if !t.entities[finding.Entity] {
+				continue
+			}
+			out = append(out, t.text[last:finding.Start]...)
+			if len(pieceFindings[next]) > 0 {
+				out = append(out, maskedPieces[next]...)
+			} else {
+				// the entity is not detected without its surroundings, never
+				// leave it as it is
+				out = append(out, _redactedValue...)
+			}
+			last = finding.End
+			next++
+		}
+		out = append(out, t.text[last:]...)
+		t.set(string(out))
+	}
+	return nil
+}
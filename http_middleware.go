@@ -0,0 +1,420 @@
+package piiscrubber
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const _defaultMaxBodySize = 64 * 1024
+
+// DefaultRedactedHeaders are the headers whose values are replaced with
+// <REDACTED> when no other headers are given
+var DefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// HTTPMessage is the scrubbed copy of a request or a response
+type HTTPMessage struct {
+	Header http.Header
+	// Body is nil when the content type is not JSON, form-encoded or text
+	Body []byte
+	// Truncated is set when only the first MaxBodySize bytes were captured
+	Truncated bool
+}
+
+// HTTPExchange is the scrubbed copy of a request and of the response written
+// for it
+type HTTPExchange struct {
+	Method string
+	// URL has its query keys and values scrubbed
+	URL        string
+	Request    HTTPMessage
+	StatusCode int
+	Response   HTTPMessage
+}
+
+// HTTPMiddlewareOptions ...
+type HTTPMiddlewareOptions struct {
+	// MaxBodySize is the number of bytes of each body that is captured.
+	// Defaults to 64KiB
+	MaxBodySize int64
+	// RedactHeaders are the headers whose values are replaced with
+	// <REDACTED>, the values of other headers are scrubbed. Defaults to
+	// DefaultRedactedHeaders
+	RedactHeaders []string
+}
+
+// HTTPMiddleware returns a middleware that hands a scrubbed copy of every
+// request and response to callback once the response is written, e.g. for
+// audit logs. The handler sees the request and writes the response as they
+// are. JSON bodies are scrubbed field by field, so that the KeyRules of s
+// apply to them, up to where truncated or invalid JSON stops being valid,
+// form bodies and query strings key and value by value, and text bodies
+// with ScrubTexts. The writer passed to the handler still
+// implements http.Flusher and http.Hijacker
+func HTTPMiddleware(s Scrubber, callback func(*HTTPExchange), opts *HTTPMiddlewareOptions) func(http.Handler) http.Handler {
+	b := newHTTPBodyScrubber(s, opts)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			exchange := &HTTPExchange{
+				Method: r.Method,
+				URL:    b.scrubURL(r.URL).String(),
+			}
+
+			requestBody, truncated := b.captureRequestBody(r)
+			exchange.Request = b.scrubMessage(r.Header, requestBody, truncated)
+
+			recorder := &responseRecorder{ResponseWriter: w, maxBodySize: b.maxBodySize}
+			next.ServeHTTP(recorder, r)
+
+			exchange.StatusCode = recorder.statusCode
+			if exchange.StatusCode == 0 {
+				exchange.StatusCode = http.StatusOK
+			}
+			exchange.Response = b.scrubMessage(w.Header(), recorder.body.Bytes(), recorder.truncated)
+
+			callback(exchange)
+		})
+	}
+}
+
+// httpBodyScrubber scrubs headers, URLs and bodies by their content type
+type httpBodyScrubber struct {
+	scrubber      Scrubber
+	maxBodySize   int64
+	redactHeaders map[string]bool
+}
+
+func newHTTPBodyScrubber(s Scrubber, opts *HTTPMiddlewareOptions) *httpBodyScrubber {
+	if opts == nil {
+		opts = &HTTPMiddlewareOptions{}
+	}
+
+	b := &httpBodyScrubber{
+		scrubber:      s,
+		maxBodySize:   opts.MaxBodySize,
+		redactHeaders: make(map[string]bool),
+	}
+	if b.maxBodySize <= 0 {
+		b.maxBodySize = _defaultMaxBodySize
+	}
+
+	redactHeaders := opts.RedactHeaders
+	if redactHeaders == nil {
+		redactHeaders = DefaultRedactedHeaders
+	}
+	for _, header := range redactHeaders {
+		b.redactHeaders[http.CanonicalHeaderKey(header)] = true
+	}
+	return b
+}
+
+// captureRequestBody reads the first maxBodySize bytes of the request body
+// and puts them back in front of the rest of it
+func (b *httpBodyScrubber) captureRequestBody(r *http.Request) ([]byte, bool) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, false
+	}
+
+	captured, err := io.ReadAll(io.LimitReader(r.Body, b.maxBodySize+1))
+	r.Body = &prefixedBody{
+		Reader: io.MultiReader(bytes.NewReader(captured), &errReader{err: err, reader: r.Body}),
+		Closer: r.Body,
+	}
+
+	if int64(len(captured)) > b.maxBodySize {
+		return captured[:b.maxBodySize], true
+	}
+	return captured, false
+}
+
+func (b *httpBodyScrubber) scrubMessage(header http.Header, body []byte, truncated bool) HTTPMessage {
+	message := HTTPMessage{
+		Header:    b.scrubHeader(header),
+		Truncated: truncated,
+	}
+	if len(body) > 0 {
+		message.Body = b.scrubBody(header.Get("Content-Type"), body)
+	}
+	return message
+}
+
+// scrubHeader returns a copy of header with the values of redacted headers
+// replaced and the values of other headers scrubbed
+func (b *httpBodyScrubber) scrubHeader(header http.Header) http.Header {
+	scrubbed := make(http.Header, len(header))
+	var keys []string
+	var texts []string
+	for key, values := range header {
+		if b.redactHeaders[http.CanonicalHeaderKey(key)] {
+			redacted := make([]string, len(values))
+			for i := range values {
+				redacted[i] = _redactedValue
+			}
+			scrubbed[key] = redacted
+			continue
+		}
+		keys = append(keys, key)
+		texts = append(texts, values...)
+	}
+	if len(texts) == 0 {
+		for _, key := range keys {
+			scrubbed[key] = []string{}
+		}
+		return scrubbed
+	}
+
+	scrubbedTexts, err := b.scrubber.ScrubTexts(texts)
+	next := 0
+	for _, key := range keys {
+		values := make([]string, len(header[key]))
+		for i := range values {
+			if err != nil {
+				values[i] = _redactedValue
+				continue
+			}
+			values[i] = scrubbedTexts[next]
+			next++
+		}
+		scrubbed[key] = values
+	}
+	return scrubbed
+}
+
+// scrubURL returns a copy of u with its query scrubbed and its user info
+// left out
+func (b *httpBodyScrubber) scrubURL(u *url.URL) *url.URL {
+	scrubbed := *u
+	scrubbed.User = nil
+	if u.RawQuery != "" {
+		query, err := scrubQuery(b.scrubber, u.RawQuery)
+		if err != nil {
+			query = _redactedValue
+		}
+		scrubbed.RawQuery = query
+	}
+	return &scrubbed
+}
+
+// scrubBody scrubs body by its content type. Bodies of other content types
+// are left out
+func (b *httpBodyScrubber) scrubBody(contentType string, body []byte) []byte {
+	if contentType == "" {
+		// net/http sniffs the content type of responses without writing it
+		// to the header map of the handler
+		contentType = http.DetectContentType(body)
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	var scrubbed []byte
+	var err error
+	switch {
+	case isJSONMediaType(mediaType):
+		scrubbed, err = b.scrubJSONBody(body)
+	case mediaType == "application/x-www-form-urlencoded":
+		scrubbed, err = b.scrubFormBody(body)
+	case strings.HasPrefix(mediaType, "text/"):
+		scrubbed, err = b.scrubTextBody(body)
+	default:
+		return nil
+	}
+
+	if err != nil {
+		// never hand an unscrubbed body over
+		return []byte(_redactedValue)
+	}
+	return scrubbed
+}
+
+// scrubJSONBody scrubs body token by token, so that KeyRules apply. Bodies
+// that were truncated or are not valid JSON are scrubbed up to where they
+// stop being valid and the rest is left out, as text scrubbing would miss
+// the values of keys such as "password"
+func (b *httpBodyScrubber) scrubJSONBody(body []byte) ([]byte, error) {
+	var out bytes.Buffer
+	if err := scrubJSONPrefix(b.scrubber, bytes.NewReader(body), &out); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func (b *httpBodyScrubber) scrubFormBody(body []byte) ([]byte, error) {
+	scrubbed, err := scrubQuery(b.scrubber, string(body))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(scrubbed), nil
+}
+
+func (b *httpBodyScrubber) scrubTextBody(body []byte) ([]byte, error) {
+	scrubbedTexts, err := b.scrubber.ScrubTexts([]string{string(body)})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(scrubbedTexts[0]), nil
+}
+
+// scrubQuery scrubs the keys and values of a query string or form body,
+// keeping the order of the pairs and the encoding of what is left unchanged.
+// The KeyRules of s apply to the values of the keys they match
+func scrubQuery(s Scrubber, query string) (string, error) {
+	var keyRules []compiledKeyRule
+	if internal, ok := s.(*scrubber); ok {
+		keyRules = internal.keyRules
+	}
+
+	pairs := strings.Split(query, "&")
+	keys := make([]string, len(pairs))
+	values := make([]string, len(pairs))
+	// texts[i] is the unescaped form of *targets[i]
+	texts := make([]string, 0, 2*len(pairs))
+	targets := make([]*string, 0, 2*len(pairs))
+
+	for i, pair := range pairs {
+		rawKey, rawValue, hasValue := strings.Cut(pair, "=")
+		keys[i], values[i] = rawKey, rawValue
+
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			key = rawKey
+		}
+		// keys are scrubbed whatever rule applies to their value, e.g. the
+		// e-mail address in ?jane@example.com
+		if key != "" {
+			texts = append(texts, key)
+			targets = append(targets, &keys[i])
+		}
+		if !hasValue {
+			continue
+		}
+
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			value = rawValue
+		}
+
+		state := parseState{hasPIITag: true}
+		if action, ok := matchKeyRules(keyRules, key); ok {
+			state = state.withAction(action)
+		}
+		switch {
+		case state.redact:
+			values[i] = url.QueryEscape(_redactedValue)
+		case state.hasPIITag:
+			texts = append(texts, value)
+			targets = append(targets, &values[i])
+		}
+	}
+
+	if len(texts) > 0 {
+		scrubbedTexts, err := s.ScrubTexts(texts)
+		if err != nil {
+			return "", err
+		}
+		for i, target := range targets {
+			if scrubbedTexts[i] != texts[i] {
+				*target = url.QueryEscape(scrubbedTexts[i])
+			}
+		}
+	}
+
+	var out strings.Builder
+	for i, pair := range pairs {
+		if i > 0 {
+			out.WriteByte('&')
+		}
+		out.WriteString(keys[i])
+		if strings.Contains(pair, "=") {
+			out.WriteByte('=')
+			out.WriteString(values[i])
+		}
+	}
+	return out.String(), nil
+}
+
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// prefixedBody is a request body whose captured prefix was read ahead
+type prefixedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// errReader returns err, the error the captured prefix was read with, once
+// the prefix is consumed, and reads from reader afterwards when there was none
+type errReader struct {
+	err    error
+	reader io.Reader
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	return r.reader.Read(p)
+}
+
+// responseRecorder captures the status code and the first maxBodySize bytes
+// of the body written by a handler
+type responseRecorder struct {
+	http.ResponseWriter
+	maxBodySize int64
+	statusCode  int
+	body        bytes.Buffer
+	truncated   bool
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	if r.statusCode == 0 {
+		r.statusCode = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if r.statusCode == 0 {
+		r.statusCode = http.StatusOK
+	}
+
+	if remaining := r.maxBodySize - int64(r.body.Len()); remaining < int64(len(p)) {
+		r.body.Write(p[:max(remaining, 0)])
+		r.truncated = true
+	} else {
+		r.body.Write(p)
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+// Flush sends the buffered response to the client, handlers that stream
+// type-assert their writer to http.Flusher
+func (r *responseRecorder) Flush() {
+	if r.statusCode == 0 {
+		r.statusCode = http.StatusOK
+	}
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack hands the connection over to the handler, e.g. for websockets. What
+// is written to it afterwards is not part of the exchange
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the other optional interfaces
+// of the underlying writer
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
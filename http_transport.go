@@ -0,0 +1,305 @@
+package piiscrubber
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	_defaultMaxBufferSize = 1024 * 1024
+	// form bodies are streamed in batches of this many pairs
+	_formBatchPairs = 256
+)
+
+// TransportPolicy tells a scrubbing Transport what to rewrite in the requests
+// sent to a host
+type TransportPolicy struct {
+	Scrubber Scrubber
+	// ScrubHeaders are the headers whose values are scrubbed, other headers
+	// are sent as they are
+	ScrubHeaders []string
+	// RedactHeaders are the headers whose values are replaced with <REDACTED>
+	RedactHeaders []string
+}
+
+// TransportOptions ...
+type TransportOptions struct {
+	// Policies are keyed by host name, e.g. "api.example.com", or by a
+	// wildcard matching its subdomains, e.g. "*.example.com"
+	Policies map[string]*TransportPolicy
+	// DefaultPolicy applies to hosts without a policy, requests to them are
+	// sent as they are when it is nil
+	DefaultPolicy *TransportPolicy
+	// MaxBufferSize is the size up to which bodies are scrubbed in memory,
+	// larger bodies are scrubbed while they are sent. Defaults to 1MiB
+	MaxBufferSize int64
+}
+
+type transport struct {
+	next          http.RoundTripper
+	policies      map[string]*transportPolicy
+	defaultPolicy *transportPolicy
+	maxBufferSize int64
+}
+
+type transportPolicy struct {
+	scrubber      Scrubber
+	scrubHeaders  []string
+	redactHeaders []string
+}
+
+// NewTransport returns an http.RoundTripper that scrubs the query string, the
+// selected headers and the JSON, form-encoded or text body of each request
+// before passing it on to next, e.g. for clients of third party APIs that must
+// never see PII. Bodies of other content types are sent as they are. next
+// defaults to http.DefaultTransport
+func NewTransport(next http.RoundTripper, opts *TransportOptions) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if opts == nil {
+		opts = &TransportOptions{}
+	}
+
+	t := &transport{
+		next:          next,
+		policies:      make(map[string]*transportPolicy, len(opts.Policies)),
+		defaultPolicy: newTransportPolicy(opts.DefaultPolicy),
+		maxBufferSize: opts.MaxBufferSize,
+	}
+	for host, policy := range opts.Policies {
+		t.policies[strings.ToLower(host)] = newTransportPolicy(policy)
+	}
+	if t.maxBufferSize <= 0 {
+		t.maxBufferSize = _defaultMaxBufferSize
+	}
+	return t
+}
+
+func newTransportPolicy(policy *TransportPolicy) *transportPolicy {
+	if policy == nil || policy.Scrubber == nil {
+		return nil
+	}
+
+	p := &transportPolicy{scrubber: policy.Scrubber}
+	for _, header := range policy.ScrubHeaders {
+		p.scrubHeaders = append(p.scrubHeaders, http.CanonicalHeaderKey(header))
+	}
+	for _, header := range policy.RedactHeaders {
+		p.redactHeaders = append(p.redactHeaders, http.CanonicalHeaderKey(header))
+	}
+	return p
+}
+
+// policyFor returns the policy of host, an exact match is preferred over the
+// longest matching wildcard
+func (t *transport) policyFor(host string) *transportPolicy {
+	host = strings.ToLower(host)
+	if policy, ok := t.policies[host]; ok {
+		return policy
+	}
+	for domain := host; ; {
+		dot := strings.IndexByte(domain, '.')
+		if dot < 0 {
+			return t.defaultPolicy
+		}
+		domain = domain[dot+1:]
+		if policy, ok := t.policies["*."+domain]; ok {
+			return policy
+		}
+	}
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	policy := t.policyFor(r.URL.Hostname())
+	if policy == nil {
+		return t.next.RoundTrip(r)
+	}
+
+	scrubbed, err := t.scrubRequest(r, policy)
+	if err != nil {
+		// a RoundTripper closes the body even when it fails
+		if r.Body != nil {
+			r.Body.Close()
+		}
+		return nil, err
+	}
+	return t.next.RoundTrip(scrubbed)
+}
+
+// scrubRequest returns a scrubbed clone of r, RoundTrippers must not modify
+// the requests they are given
+func (t *transport) scrubRequest(r *http.Request, policy *transportPolicy) (*http.Request, error) {
+	scrubbed := r.Clone(r.Context())
+
+	if r.URL.RawQuery != "" {
+		query, err := scrubQuery(policy.scrubber, r.URL.RawQuery)
+		if err != nil {
+			return nil, err
+		}
+		scrubbed.URL.RawQuery = query
+	}
+
+	if err := policy.scrubHeader(scrubbed.Header); err != nil {
+		return nil, err
+	}
+
+	if r.Body == nil || r.Body == http.NoBody {
+		return scrubbed, nil
+	}
+
+	scrubBody := bodyScrubberFor(mediaTypeOf(r))
+	if scrubBody == nil {
+		return scrubbed, nil
+	}
+
+	prefix, err := io.ReadAll(io.LimitReader(r.Body, t.maxBufferSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(prefix)) <= t.maxBufferSize {
+		r.Body.Close()
+
+		if isJSONMediaType(mediaTypeOf(r)) && !json.Valid(prefix) {
+			// sent as is by the caller, so scrub it the way text is
+			scrubBody = scrubTextStream
+		}
+
+		var out bytes.Buffer
+		if err := scrubBody(policy.scrubber, bytes.NewReader(prefix), &out); err != nil {
+			return nil, err
+		}
+		body := out.Bytes()
+		scrubbed.Body = io.NopCloser(bytes.NewReader(body))
+		scrubbed.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+		scrubbed.ContentLength = int64(len(body))
+		return scrubbed, nil
+	}
+
+	// too large to hold, scrub it while it is sent
+	body := io.MultiReader(bytes.NewReader(prefix), r.Body)
+	reader, writer := io.Pipe()
+	go func() {
+		defer r.Body.Close()
+		writer.CloseWithError(scrubBody(policy.scrubber, body, writer))
+	}()
+
+	scrubbed.Body = reader
+	// the scrubbed body can't be read again
+	scrubbed.GetBody = nil
+	scrubbed.ContentLength = -1
+	scrubbed.Header.Del("Content-Length")
+	return scrubbed, nil
+}
+
+func (p *transportPolicy) scrubHeader(header http.Header) error {
+	for _, key := range p.redactHeaders {
+		for i := range header[key] {
+			header[key][i] = _redactedValue
+		}
+	}
+
+	var texts []string
+	for _, key := range p.scrubHeaders {
+		texts = append(texts, header[key]...)
+	}
+	if len(texts) == 0 {
+		return nil
+	}
+
+	scrubbedTexts, err := p.scrubber.ScrubTexts(texts)
+	if err != nil {
+		return err
+	}
+	next := 0
+	for _, key := range p.scrubHeaders {
+		for i := range header[key] {
+			header[key][i] = scrubbedTexts[next]
+			next++
+		}
+	}
+	return nil
+}
+
+func mediaTypeOf(r *http.Request) string {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType
+}
+
+// bodyScrubberFor returns the function that scrubs bodies of mediaType while
+// they are copied, nil when they can't be scrubbed
+func bodyScrubberFor(mediaType string) func(Scrubber, io.Reader, io.Writer) error {
+	switch {
+	case isJSONMediaType(mediaType):
+		return scrubJSONStream
+	case mediaType == "application/x-www-form-urlencoded":
+		return scrubFormStream
+	case strings.HasPrefix(mediaType, "text/"):
+		return scrubTextStream
+	}
+	return nil
+}
+
+// scrubFormStream scrubs a form body in batches of pairs
+func scrubFormStream(s Scrubber, r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, _defaultMaxBufferSize)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexByte(data, '&'); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	})
+
+	batch := make([]string, 0, _formBatchPairs)
+	written := false
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		query, err := scrubQuery(s, strings.Join(batch, "&"))
+		if err != nil {
+			return err
+		}
+		if written {
+			query = "&" + query
+		}
+		written = true
+		batch = batch[:0]
+		_, err = io.WriteString(w, query)
+		return err
+	}
+
+	for scanner.Scan() {
+		batch = append(batch, scanner.Text())
+		if len(batch) == _formBatchPairs {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+// scrubTextStream scrubs a text body line by line
+func scrubTextStream(s Scrubber, r io.Reader, w io.Writer) error {
+	writer := NewWriter(w, s, nil)
+	if _, err := io.Copy(writer, r); err != nil {
+		return err
+	}
+	return writer.Close()
+}
@@ -0,0 +1,99 @@
+// Package policyconfig reads the scrubbing policies of the pii-scrubber
+// commands, which are the policy files of piiscrubber.LoadPolicy extended
+// with the rules of JSON and CSV inputs
+package policyconfig
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+)
+
+// Config is a scrubbing policy, e.g.
+//
+//	entities: [EMAIL, PHONE, SSN]
+//	ignored_entities: [STRICT_LINK]
+//	masking:
+//	  PHONE: {mask_with_char: "*", unmasked_suffix: 4}
+//	json_rules:
+//	  - {path: $..password, action: drop}
+//	csv_rules:
+//	  - {name: email, action: redact}
+//	  - {index: 3, action: keep}
+//
+// Every field of piiscrubber.LoadPolicy is accepted, in YAML or JSON
+type Config struct {
+	// Params are read by piiscrubber.LoadPolicy
+	Params piiscrubber.Params
+	// JSONRules apply to json and ndjson inputs, CSVRules to csv and tsv
+	// inputs
+	JSONRules []piiscrubber.JSONRule
+	CSVRules  []piiscrubber.CSVColumnRule
+}
+
+// Load reads the Config in the file at path, the default policy when path is
+// empty
+func Load(path string) (*Config, error) {
+	if path == "" {
+		return Read(strings.NewReader(""))
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	config, err := Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("in config %v: %v", path, err)
+	}
+	return config, nil
+}
+
+// Read reads a Config from r
+func Read(r io.Reader) (*Config, error) {
+	config := &Config{}
+	params, err := piiscrubber.LoadPolicyWithOptions(r, &piiscrubber.PolicyOptions{
+		Extensions: map[string]interface{}{
+			"json_rules": &config.JSONRules,
+			"csv_rules":  &config.CSVRules,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	config.Params = params
+	return config, nil
+}
+
+// MaskedParams returns the Params of the scrubber, mask is how the entities
+// the policy does not mask are masked: placeholder, redact or char
+func (c *Config) MaskedParams(mask string, maskChar rune) (piiscrubber.Params, error) {
+	params := c.Params
+	params.Config = make(map[piiscrubber.Entity]*piiscrubber.EntityConfig, len(c.Params.Config))
+	for entity, config := range c.Params.Config {
+		params.Config[entity] = config
+	}
+
+	for _, entity := range params.BlacklistedEntities {
+		// the masking of the policy wins
+		if _, ok := params.Config[entity]; ok {
+			continue
+		}
+		switch mask {
+		case "placeholder":
+		case "redact":
+			replaceWith := "<REDACTED>"
+			params.Config[entity] = &piiscrubber.EntityConfig{ReplaceWith: &replaceWith}
+		case "char":
+			params.Config[entity] = &piiscrubber.EntityConfig{MaskWithChar: &maskChar}
+		default:
+			return params, fmt.Errorf("unknown masking mode: %q, expected placeholder, redact or char", mask)
+		}
+	}
+	return params, nil
+}
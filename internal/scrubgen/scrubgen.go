@@ -0,0 +1,492 @@
+// Package scrubgen generates reflection-free ScrubPII methods for structs
+// tagged with pii:"true". The generated code mirrors what ScrubStruct does
+// for the same value, see cmd/pii-scrubgen
+package scrubgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultOutput is the name of the generated file
+const DefaultOutput = "pii_scrub_gen.go"
+
+const (
+	_piiTag          = "pii"
+	_piiTagValueTrue = "true"
+
+	_collectMethod = "scrubPIICollect"
+	_importPath    = "github.com/aavaz-ai/pii-scrubber"
+)
+
+// predeclared types that can't hold a string ScrubStruct would scrub
+var _basicTypes = map[string]bool{
+	"bool": true, "byte": true, "rune": true, "uintptr": true,
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"float32": true, "float64": true, "complex64": true, "complex128": true,
+}
+
+type typeDecl struct {
+	name string
+	expr ast.Expr
+	file *ast.File
+}
+
+type generator struct {
+	pkgName string
+	types   map[string]*typeDecl
+	// imports used by the generated code, by local name
+	imports map[string]string
+	buf     bytes.Buffer
+	vars    int
+}
+
+// Generate parses the package in dir and returns the source of the ScrubPII
+// methods for typeNames, or for every struct with a pii tag when typeNames is
+// empty. Files named output are ignored so that regenerating is stable
+func Generate(dir string, typeNames []string, output string) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go") && info.Name() != output
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected exactly one package in %v, found %d", dir, len(pkgs))
+	}
+
+	g := &generator{
+		types:   make(map[string]*typeDecl),
+		imports: map[string]string{"piiscrubber": _importPath},
+	}
+	for name, pkg := range pkgs {
+		g.pkgName = name
+		for _, file := range pkg.Files {
+			g.collectTypes(file)
+		}
+	}
+
+	targets := typeNames
+	if len(targets) == 0 {
+		for name, decl := range g.types {
+			if hasPIITag(decl.expr) {
+				targets = append(targets, name)
+			}
+		}
+		sort.Strings(targets)
+	}
+	for _, name := range targets {
+		decl, ok := g.types[name]
+		if !ok {
+			return nil, fmt.Errorf("type %v not found in %v", name, dir)
+		}
+		if _, ok := decl.expr.(*ast.StructType); !ok {
+			return nil, fmt.Errorf("type %v is not a struct", name)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no struct with a pii tag found in %v", dir)
+	}
+
+	for _, name := range targets {
+		g.genScrubPII(name)
+	}
+	for _, name := range g.reachableStructs(targets) {
+		if err := g.genCollect(g.types[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	return g.source()
+}
+
+func (g *generator) collectTypes(file *ast.File) {
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			// generic types and aliases are left to ScrubStruct
+			if typeSpec.TypeParams != nil || typeSpec.Assign.IsValid() {
+				continue
+			}
+			g.types[typeSpec.Name.Name] = &typeDecl{
+				name: typeSpec.Name.Name,
+				expr: typeSpec.Type,
+				file: file,
+			}
+		}
+	}
+}
+
+func hasPIITag(expr ast.Expr) bool {
+	structType, ok := expr.(*ast.StructType)
+	if !ok {
+		return false
+	}
+	for _, field := range structType.Fields.List {
+		if fieldHasPIITag(field) {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldHasPIITag(field *ast.Field) bool {
+	if field.Tag == nil {
+		return false
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return false
+	}
+	return reflect.StructTag(tag).Get(_piiTag) == _piiTagValueTrue
+}
+
+// reachableStructs returns the local structs whose fields the generated code
+// walks, starting from targets
+func (g *generator) reachableStructs(targets []string) []string {
+	seen := make(map[string]bool)
+	var visit func(expr ast.Expr)
+	visit = func(expr ast.Expr) {
+		switch t := expr.(type) {
+		case *ast.Ident:
+			decl, ok := g.types[t.Name]
+			if !ok || seen[t.Name] {
+				return
+			}
+			if structType, ok := decl.expr.(*ast.StructType); ok {
+				seen[t.Name] = true
+				for _, field := range structType.Fields.List {
+					visit(field.Type)
+				}
+				return
+			}
+			seen[t.Name] = true
+			visit(decl.expr)
+			delete(seen, t.Name)
+		case *ast.StarExpr:
+			visit(t.X)
+		case *ast.ArrayType:
+			visit(t.Elt)
+		case *ast.ParenExpr:
+			visit(t.X)
+		}
+	}
+	for _, name := range targets {
+		visit(&ast.Ident{Name: name})
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		if _, ok := g.types[name].expr.(*ast.StructType); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) genScrubPII(name string) {
+	g.printf("// ScrubPII scrubs the pii tagged fields of v in place, with the same result\n")
+	g.printf("// as ScrubStruct. Slices, maps and pointers are replaced by scrubbed copies,\n")
+	g.printf("// so values shared with other copies of v are left untouched\n")
+	g.printf("func (v *%v) ScrubPII(s piiscrubber.Scrubber) error {\n", name)
+	g.printf("if piiscrubber.NeedsScrubStruct(s) {\n")
+	g.printf("copy, err := s.ScrubStruct(*v)\n")
+	g.printf("if err != nil {\nreturn err\n}\n")
+	g.printf("*v = copy.(%v)\n", name)
+	g.printf("return nil\n")
+	g.printf("}\n")
+	g.printf("var batch piiscrubber.TextBatch\n")
+	g.printf("if err := v.%v(s, &batch, false); err != nil {\nreturn err\n}\n", _collectMethod)
+	g.printf("return batch.Scrub(s)\n")
+	g.printf("}\n\n")
+}
+
+func (g *generator) genCollect(decl *typeDecl) error {
+	structType := decl.expr.(*ast.StructType)
+
+	g.vars = 0
+	g.printf("func (v *%v) %v(s piiscrubber.Scrubber, batch *piiscrubber.TextBatch, scoped bool) error {\n", decl.name, _collectMethod)
+	for _, field := range structType.Fields.List {
+		scope := "scoped"
+		if fieldHasPIITag(field) {
+			scope = "true"
+		}
+
+		names := make([]string, 0, len(field.Names))
+		for _, name := range field.Names {
+			names = append(names, name.Name)
+		}
+		if len(field.Names) == 0 {
+			names = append(names, embeddedName(field.Type))
+		}
+
+		for _, name := range names {
+			// ScrubStruct copies unexported fields as they are, and fails on
+			// tagged ones
+			if !ast.IsExported(name) {
+				if fieldHasPIITag(field) {
+					return fmt.Errorf("field %v.%v: unexported fields tagged with pii:\"true\" can't be scrubbed", decl.name, name)
+				}
+				continue
+			}
+			if err := g.genValue("v."+name, field.Type, scope, decl.file); err != nil {
+				return fmt.Errorf("field %v.%v: %v", decl.name, name, err)
+			}
+		}
+	}
+	g.printf("return nil\n")
+	g.printf("}\n\n")
+	return nil
+}
+
+func embeddedName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return embeddedName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.Ident:
+		return t.Name
+	}
+	return "_"
+}
+
+func (g *generator) newVar() string {
+	g.vars++
+	return fmt.Sprintf("c%d", g.vars)
+}
+
+// typeString prints expr for use in the generated file, registering the
+// imports it needs
+func (g *generator) typeString(expr ast.Expr, file *ast.File) string {
+	ast.Inspect(expr, func(node ast.Node) bool {
+		selector, ok := node.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if pkg, ok := selector.X.(*ast.Ident); ok {
+			if path := importPath(file, pkg.Name); path != "" {
+				g.imports[pkg.Name] = path
+			}
+		}
+		return false
+	})
+	return types.ExprString(expr)
+}
+
+func importPath(file *ast.File, name string) string {
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		local := path[strings.LastIndex(path, "/")+1:]
+		if spec.Name != nil {
+			local = spec.Name.Name
+		}
+		if local == name {
+			return path
+		}
+	}
+	return ""
+}
+
+// genValue emits the code that handles the addressable expression x of type
+// expr. scope is a Go expression telling whether x is inside a pii tagged
+// field
+func (g *generator) genValue(x string, expr ast.Expr, scope string, file *ast.File) error {
+	switch t := expr.(type) {
+	case *ast.ParenExpr:
+		return g.genValue(x, t.X, scope, file)
+
+	case *ast.Ident:
+		if t.Name == "string" {
+			g.genString("&"+x, scope)
+			return nil
+		}
+		if _basicTypes[t.Name] {
+			return nil
+		}
+		decl, ok := g.types[t.Name]
+		if !ok {
+			// error, any and other predeclared interfaces
+			g.genFallback(x, expr, scope, file, true)
+			return nil
+		}
+		return g.genNamed(x, decl, scope)
+
+	case *ast.StarExpr:
+		if ident, ok := t.X.(*ast.Ident); ok && _basicTypes[ident.Name] {
+			return nil
+		}
+		c := g.newVar()
+		g.printf("if %v != nil {\n", x)
+		g.printf("%v := *%v\n", c, x)
+		if err := g.genValue(c, t.X, scope, file); err != nil {
+			return err
+		}
+		g.printf("%v = &%v\n", x, c)
+		g.printf("}\n")
+		return nil
+
+	case *ast.ArrayType:
+		if t.Len != nil {
+			return g.genElements(x, t.Elt, scope, file)
+		}
+		return g.genSlice(x, g.typeString(expr, file), t.Elt, scope, file)
+
+	case *ast.MapType:
+		g.genFallback(x, expr, scope, file, false)
+		return nil
+
+	case *ast.InterfaceType, *ast.SelectorExpr:
+		g.genFallback(x, expr, scope, file, true)
+		return nil
+
+	case *ast.ChanType, *ast.FuncType:
+		return nil
+	}
+
+	return fmt.Errorf("unsupported type %v", types.ExprString(expr))
+}
+
+func (g *generator) genString(ptr, scope string) {
+	if scope == "true" {
+		g.printf("batch.Add(%v)\n", ptr)
+		return
+	}
+	g.printf("if %v {\nbatch.Add(%v)\n}\n", scope, ptr)
+}
+
+func (g *generator) genNamed(x string, decl *typeDecl, scope string) error {
+	switch t := decl.expr.(type) {
+	case *ast.StructType:
+		g.printf("if err := %v.%v(s, batch, %v); err != nil {\nreturn err\n}\n", x, _collectMethod, scope)
+		return nil
+
+	case *ast.Ident:
+		if t.Name == "string" {
+			g.genString("(*string)(&"+x+")", scope)
+			return nil
+		}
+		if _basicTypes[t.Name] {
+			return nil
+		}
+		if underlying, ok := g.types[t.Name]; ok {
+			// conversions between the two named types are always allowed
+			c := g.newVar()
+			g.printf("%v := %v(%v)\n", c, underlying.name, x)
+			if err := g.genNamed(c, underlying, scope); err != nil {
+				return err
+			}
+			g.printf("%v = %v(%v)\n", x, decl.name, c)
+			return nil
+		}
+
+	case *ast.ArrayType:
+		if t.Len != nil {
+			return g.genElements(x, t.Elt, scope, decl.file)
+		}
+		return g.genSlice(x, decl.name, t.Elt, scope, decl.file)
+
+	case *ast.ChanType, *ast.FuncType:
+		return nil
+	}
+
+	g.genFallback(x, &ast.Ident{Name: decl.name}, scope, decl.file, true)
+	return nil
+}
+
+// genSlice copies the slice like ScrubStruct does, so nil slices become empty
+// ones, and handles every element of the copy
+func (g *generator) genSlice(x, typeName string, elem ast.Expr, scope string, file *ast.File) error {
+	c := g.newVar()
+	g.printf("%v := make(%v, len(%v), cap(%v))\n", c, typeName, x, x)
+	g.printf("copy(%v, %v)\n", c, x)
+	if err := g.genElements(c, elem, scope, file); err != nil {
+		return err
+	}
+	g.printf("%v = %v\n", x, c)
+	return nil
+}
+
+// genElements handles every element of the addressable array or slice x in
+// place, arrays are values so x is already a copy
+func (g *generator) genElements(x string, elem ast.Expr, scope string, file *ast.File) error {
+	i := g.newVar()
+	start := g.buf.Len()
+	g.printf("for %v := range %v {\n", i, x)
+	body := g.buf.Len()
+	if err := g.genValue(fmt.Sprintf("%v[%v]", x, i), elem, scope, file); err != nil {
+		return err
+	}
+	if g.buf.Len() == body {
+		// nothing to do for the elements
+		g.buf.Truncate(start)
+	} else {
+		g.printf("}\n")
+	}
+	return nil
+}
+
+// genFallback hands x to ScrubStruct through piiscrubber.ScrubValue
+func (g *generator) genFallback(x string, expr ast.Expr, scope string, file *ast.File, nilable bool) {
+	typeName := g.typeString(expr, file)
+	c := g.newVar()
+	g.printf("%v, err := piiscrubber.ScrubValue(s, %v, %v)\n", c, x, scope)
+	g.printf("if err != nil {\nreturn err\n}\n")
+	if nilable {
+		g.printf("if %v != nil {\n%v = %v.(%v)\n}\n", c, x, c, typeName)
+		return
+	}
+	g.printf("%v = %v.(%v)\n", x, c, typeName)
+}
+
+func (g *generator) source() ([]byte, error) {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by pii-scrubgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %v\n\n", g.pkgName)
+
+	names := make([]string, 0, len(g.imports))
+	for name := range g.imports {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return g.imports[names[i]] < g.imports[names[j]]
+	})
+	fmt.Fprintf(&out, "import (\n")
+	for _, name := range names {
+		path := g.imports[name]
+		if path[strings.LastIndex(path, "/")+1:] == name {
+			fmt.Fprintf(&out, "%q\n", path)
+		} else {
+			fmt.Fprintf(&out, "%v %q\n", name, path)
+		}
+	}
+	fmt.Fprintf(&out, ")\n\n")
+	out.Write(g.buf.Bytes())
+
+	source, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %v\n%s", err, out.Bytes())
+	}
+	return source, nil
+}
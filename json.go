@@ -0,0 +1,389 @@
+package piiscrubber
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+const (
+	// a batch of pending strings is scrubbed once it holds this many strings
+	// or this many bytes of output
+	_jsonBatchTexts = 256
+	_jsonBatchBytes = 32 * 1024
+)
+
+var (
+	_jsonNewline = []byte{'\n'}
+	_jsonComma   = []byte{','}
+	_jsonColon   = []byte{':'}
+	_jsonTrue    = []byte("true")
+	_jsonFalse   = []byte("false")
+	_jsonNull    = []byte("null")
+)
+
+// jsonFrame is an object or array the stream scrubber is inside of
+type jsonFrame struct {
+	object bool
+	// count is the number of elements, or keys, written so far
+	count int
+	// expectKey is set in objects when the next token is a key
+	expectKey bool
+	// state is what to do with the values of the frame, valueState with the
+	// value of the key just read
+	state      parseState
+	valueState parseState
+	// index is the index of the next element of an array, dropped elements
+	// included
+	index int
+	// paths are the states of the JSON path rules inside the frame, and
+	// valuePaths the ones of the value of the key just read
+	paths      []jsonPathState
+	valuePaths []jsonPathState
+	// dropValue is set when the value of the key just read is dropped
+	dropValue bool
+}
+
+// jsonPiece is a piece of output, either literal bytes or a string waiting
+// to be scrubbed
+type jsonPiece struct {
+	literal []byte
+	text    int
+}
+
+// jsonStreamScrubber rewrites a stream of JSON values token by token, so that
+// large documents are never held in memory whole. The order of keys and the
+// formatting of numbers are preserved. Strings are scrubbed in batches, the
+// KeyRules of the scrubber apply to the values of the keys they match
+type jsonStreamScrubber struct {
+	scrubber Scrubber
+	keyRules []compiledKeyRule
+	scanKeys bool
+	// paths is nil without JSON path rules
+	paths *jsonPathMatcher
+
+	decoder *json.Decoder
+	w       io.Writer
+	stack   []*jsonFrame
+	// skipDepth is the depth of the dropped container being skipped
+	skipDepth int
+	// terminate ends every top level value with a newline instead of only
+	// separating them, as in newline-delimited JSON
+	terminate bool
+
+	pieces       []jsonPiece
+	texts        []string
+	pendingBytes int
+	out          []byte
+}
+
+// scrubJSONStream scrubs the JSON values read from r and writes them to w,
+// top level values are separated by newlines
+func scrubJSONStream(s Scrubber, r io.Reader, w io.Writer) error {
+	return newJSONStreamScrubber(s, nil, r, w).run()
+}
+
+// scrubJSONPrefix scrubs the JSON values read from r like scrubJSONStream,
+// up to where they stop being valid JSON, e.g. where a captured body was cut
+// off. The rest is left out rather than reported as an error
+func scrubJSONPrefix(s Scrubber, r io.Reader, w io.Writer) error {
+	j := newJSONStreamScrubber(s, nil, r, w)
+	if err := j.run(); err != nil && !errors.Is(err, ErrInvalidJSON) {
+		return err
+	}
+	// the values read before the invalid token are still pending
+	return j.flush()
+}
+
+func newJSONStreamScrubber(s Scrubber, paths *jsonPathMatcher, r io.Reader, w io.Writer) *jsonStreamScrubber {
+	j := &jsonStreamScrubber{
+		scrubber: s,
+		paths:    paths,
+		decoder:  json.NewDecoder(r),
+		w:        w,
+	}
+	if internal, ok := s.(*scrubber); ok {
+		j.keyRules = internal.keyRules
+		j.scanKeys = internal.scrubMapKeys
+	}
+	j.decoder.UseNumber()
+	return j
+}
+
+func (j *jsonStreamScrubber) run() error {
+	topLevel := 0
+	for {
+		token, err := j.decoder.Token()
+		if err == io.EOF {
+			if len(j.stack) > 0 {
+				return fmt.Errorf("%w: %v", ErrInvalidJSON, io.ErrUnexpectedEOF)
+			}
+			return j.flush()
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v, at byte %v", ErrInvalidJSON, err, j.decoder.InputOffset())
+		}
+
+		if j.skipDepth > 0 {
+			j.skip(token)
+			continue
+		}
+
+		if len(j.stack) == 0 {
+			if topLevel > 0 && !j.terminate {
+				j.writeLiteral(_jsonNewline)
+			}
+			topLevel++
+		}
+
+		if err := j.writeToken(token); err != nil {
+			return err
+		}
+		if len(j.stack) == 0 && j.terminate {
+			j.writeLiteral(_jsonNewline)
+		}
+
+		if j.pendingBytes >= _jsonBatchBytes || len(j.texts) >= _jsonBatchTexts {
+			if err := j.flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// skip drops the tokens of a dropped container up to its end
+func (j *jsonStreamScrubber) skip(token json.Token) {
+	if delim, ok := token.(json.Delim); ok {
+		if delim == '{' || delim == '[' {
+			j.skipDepth++
+		} else {
+			j.skipDepth--
+		}
+	}
+	if j.skipDepth == 0 {
+		j.endValue()
+	}
+}
+
+// drop skips the value starting with token
+func (j *jsonStreamScrubber) drop(token json.Token) {
+	if delim, ok := token.(json.Delim); ok && (delim == '{' || delim == '[') {
+		j.skipDepth = 1
+		return
+	}
+	j.endValue()
+}
+
+func (j *jsonStreamScrubber) writeToken(token json.Token) error {
+	// the state of the value about to be written, strings are scanned unless
+	// a key rule says otherwise
+	state := parseState{hasPIITag: true}
+	var paths []jsonPathState
+
+	if len(j.stack) == 0 && j.paths != nil {
+		var action Action
+		var ok bool
+		paths, action, ok = j.paths.root()
+		if ok {
+			state = state.withAction(action)
+		}
+	}
+
+	if len(j.stack) > 0 {
+		frame := j.stack[len(j.stack)-1]
+		if delim, ok := token.(json.Delim); ok && (delim == '}' || delim == ']') {
+			j.stack = j.stack[:len(j.stack)-1]
+			j.writeLiteral([]byte{byte(delim)})
+			j.endValue()
+			return nil
+		}
+
+		if frame.object && frame.expectKey {
+			key := token.(string)
+			frame.expectKey = false
+			frame.valueState = frame.state
+			if action, ok := matchKeyRules(j.keyRules, key); ok {
+				frame.valueState = frame.valueState.withAction(action)
+			}
+			if j.paths != nil {
+				var action Action
+				var ok bool
+				frame.valuePaths, action, ok = j.paths.step(frame.paths, jsonStep{key: key})
+				frame.dropValue = ok && action == Drop
+				if frame.dropValue {
+					return nil
+				}
+				if ok {
+					frame.valueState = frame.valueState.withAction(action)
+				}
+			}
+
+			if frame.count > 0 {
+				j.writeLiteral(_jsonComma)
+			}
+			if j.scanKeys && (frame.state.hasPIITag || frame.state.redact) {
+				j.writeText(key)
+			} else {
+				j.writeString(key)
+			}
+			j.writeLiteral(_jsonColon)
+			frame.count++
+			return nil
+		}
+
+		if frame.object {
+			if frame.dropValue {
+				j.drop(token)
+				return nil
+			}
+			state, paths = frame.valueState, frame.valuePaths
+		} else {
+			state = frame.state
+			if j.paths != nil {
+				var action Action
+				var ok bool
+				paths, action, ok = j.paths.step(frame.paths, jsonStep{index: frame.index, isIndex: true})
+				frame.index++
+				if ok && action == Drop {
+					j.drop(token)
+					return nil
+				}
+				if ok {
+					state = state.withAction(action)
+				}
+			}
+			if frame.count > 0 {
+				j.writeLiteral(_jsonComma)
+			}
+			frame.count++
+		}
+	}
+
+	switch value := token.(type) {
+	case json.Delim:
+		j.stack = append(j.stack, &jsonFrame{object: value == '{', expectKey: value == '{', state: state, paths: paths})
+		j.writeLiteral([]byte{byte(value)})
+		// containers end with their closing delimiter
+		return nil
+
+	case string:
+		switch {
+		case state.redact:
+			j.writeString(_redactedValue)
+		case state.hasPIITag:
+			j.writeText(value)
+		default:
+			j.writeString(value)
+		}
+
+	case json.Number:
+		if state.redact {
+			j.writeString(_redactedValue)
+		} else {
+			j.writeLiteral([]byte(value))
+		}
+
+	case bool:
+		if state.redact {
+			j.writeString(_redactedValue)
+		} else if value {
+			j.writeLiteral(_jsonTrue)
+		} else {
+			j.writeLiteral(_jsonFalse)
+		}
+
+	case nil:
+		j.writeLiteral(_jsonNull)
+	}
+
+	j.endValue()
+	return nil
+}
+
+// endValue marks the value of the current object key as written
+func (j *jsonStreamScrubber) endValue() {
+	if len(j.stack) > 0 {
+		if frame := j.stack[len(j.stack)-1]; frame.object {
+			frame.expectKey = true
+		}
+	}
+}
+
+func (j *jsonStreamScrubber) writeLiteral(literal []byte) {
+	j.pieces = append(j.pieces, jsonPiece{literal: literal})
+	j.pendingBytes += len(literal)
+}
+
+func (j *jsonStreamScrubber) writeString(text string) {
+	j.writeLiteral(appendQuotedJSON(nil, text))
+}
+
+// writeText queues text for scrubbing
+func (j *jsonStreamScrubber) writeText(text string) {
+	j.pieces = append(j.pieces, jsonPiece{text: len(j.texts)})
+	j.texts = append(j.texts, text)
+	j.pendingBytes += len(text)
+}
+
+// flush scrubs the queued strings and writes the pending output
+func (j *jsonStreamScrubber) flush() error {
+	var scrubbedTexts []string
+	if len(j.texts) > 0 {
+		var err error
+		scrubbedTexts, err = j.scrubber.ScrubTexts(j.texts)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the output buffer is reused, so that a long stream allocates it once
+	out := j.out[:0]
+	for _, piece := range j.pieces {
+		if piece.literal != nil {
+			out = append(out, piece.literal...)
+			continue
+		}
+		out = appendQuotedJSON(out, scrubbedTexts[piece.text])
+	}
+
+	j.pieces, j.texts, j.pendingBytes, j.out = j.pieces[:0], j.texts[:0], 0, out
+	if len(out) == 0 {
+		return nil
+	}
+	_, err := j.w.Write(out)
+	return err
+}
+
+// marshalJSON is json.Marshal without the escaping of <, > and &, which would
+// make the placeholders of masked entities hard to read
+func marshalJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), nil
+}
+
+// quoteJSON ...
+func quoteJSON(text string) []byte {
+	quoted, _ := marshalJSON(text)
+	return quoted
+}
+
+// appendQuotedJSON appends text quoted as quoteJSON does, without going
+// through an encoder when nothing in it needs escaping
+func appendQuotedJSON(dst []byte, text string) []byte {
+	for i := 0; i < len(text); i++ {
+		if c := text[i]; c < 0x20 || c == '"' || c == '\\' || c >= utf8.RuneSelf {
+			return append(dst, quoteJSON(text)...)
+		}
+	}
+	dst = append(dst, '"')
+	dst = append(dst, text...)
+	return append(dst, '"')
+}
@@ -0,0 +1,210 @@
+package piiscrubber
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type jsonPathSegmentKind int
+
+const (
+	jsonPathName jsonPathSegmentKind = iota
+	jsonPathIndex
+	jsonPathWildcard
+)
+
+// jsonPathSegment matches one step of a path, descend makes it match at any
+// depth below the previous segment, as in $..name
+type jsonPathSegment struct {
+	kind    jsonPathSegmentKind
+	name    string
+	index   int
+	descend bool
+}
+
+// jsonStep is a key of an object or an index of an array
+type jsonStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+func (seg jsonPathSegment) matches(step jsonStep) bool {
+	switch seg.kind {
+	case jsonPathName:
+		return !step.isIndex && step.key == seg.name
+	case jsonPathIndex:
+		return step.isIndex && step.index == seg.index
+	}
+	return true
+}
+
+// parseJSONPath parses the subset of JSONPath made of $, .name, ['name'],
+// [n], .*, [*] and the recursive descent ..name, ..['name'], ..[n] and ..*
+func parseJSONPath(path string) ([]jsonPathSegment, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("path must start with $")
+	}
+
+	var segments []jsonPathSegment
+	rest := path[1:]
+	for rest != "" {
+		var seg jsonPathSegment
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			seg.descend = true
+			rest = rest[2:]
+			if strings.HasPrefix(rest, "[") {
+				break
+			}
+			fallthrough
+
+		case strings.HasPrefix(rest, "."):
+			if !seg.descend {
+				rest = rest[1:]
+			}
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			rest = rest[end:]
+			switch name {
+			case "":
+				return nil, fmt.Errorf("empty name in path")
+			case "*":
+				seg.kind = jsonPathWildcard
+			default:
+				seg.kind, seg.name = jsonPathName, name
+			}
+			segments = append(segments, seg)
+			continue
+
+		case !strings.HasPrefix(rest, "["):
+			return nil, fmt.Errorf("unexpected %q in path", rest)
+		}
+
+		// a bracketed segment
+		var err error
+		seg, rest, err = parseJSONPathBracket(seg, rest)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, seg)
+	}
+	return segments, nil
+}
+
+// parseJSONPathBracket parses ['name'], ["name"], [n] or [*] at the start of
+// rest
+func parseJSONPathBracket(seg jsonPathSegment, rest string) (jsonPathSegment, string, error) {
+	rest = rest[1:]
+	if rest != "" && (rest[0] == '\'' || rest[0] == '"') {
+		quote := rest[0]
+		var name strings.Builder
+		for i := 1; i < len(rest); i++ {
+			switch c := rest[i]; {
+			case c == '\\' && i+1 < len(rest):
+				i++
+				name.WriteByte(rest[i])
+			case c == quote:
+				if !strings.HasPrefix(rest[i+1:], "]") {
+					return seg, "", fmt.Errorf("expected ] after quoted name in path")
+				}
+				seg.kind, seg.name = jsonPathName, name.String()
+				return seg, rest[i+2:], nil
+			default:
+				name.WriteByte(c)
+			}
+		}
+		return seg, "", fmt.Errorf("unterminated quoted name in path")
+	}
+
+	end := strings.IndexByte(rest, ']')
+	if end < 0 {
+		return seg, "", fmt.Errorf("unterminated [ in path")
+	}
+	selector := rest[:end]
+	rest = rest[end+1:]
+	if selector == "*" {
+		seg.kind = jsonPathWildcard
+		return seg, rest, nil
+	}
+
+	index, err := strconv.Atoi(selector)
+	if err != nil || index < 0 {
+		return seg, "", fmt.Errorf("unsupported selector [%v] in path, expected a name, an index or *", selector)
+	}
+	seg.kind, seg.index = jsonPathIndex, index
+	return seg, rest, nil
+}
+
+// jsonPathState is a rule whose first pos segments matched the path walked
+// so far
+type jsonPathState struct {
+	rule int
+	pos  int
+}
+
+// jsonPathMatcher follows the paths of a document as it is walked, matching
+// all rules at once
+type jsonPathMatcher struct {
+	rules   [][]jsonPathSegment
+	actions []Action
+}
+
+// root returns the states at the root of a document, and the action of the
+// last rule matching the root itself, if any
+func (m *jsonPathMatcher) root() ([]jsonPathState, Action, bool) {
+	states := make([]jsonPathState, len(m.rules))
+	for i := range m.rules {
+		states[i] = jsonPathState{rule: i}
+	}
+	action, ok := m.match(states)
+	return states, action, ok
+}
+
+// step returns the states after descending into step, and the action of the
+// last rule matching the value there, if any
+func (m *jsonPathMatcher) step(states []jsonPathState, step jsonStep) ([]jsonPathState, Action, bool) {
+	var next []jsonPathState
+	add := func(state jsonPathState) {
+		for _, existing := range next {
+			if existing == state {
+				return
+			}
+		}
+		next = append(next, state)
+	}
+
+	for _, state := range states {
+		segments := m.rules[state.rule]
+		if state.pos == len(segments) {
+			continue
+		}
+		seg := segments[state.pos]
+		if seg.descend {
+			add(state)
+		}
+		if seg.matches(step) {
+			add(jsonPathState{rule: state.rule, pos: state.pos + 1})
+		}
+	}
+
+	action, ok := m.match(next)
+	return next, action, ok
+}
+
+func (m *jsonPathMatcher) match(states []jsonPathState) (Action, bool) {
+	last := -1
+	for _, state := range states {
+		if state.pos == len(m.rules[state.rule]) && state.rule > last {
+			last = state.rule
+		}
+	}
+	if last < 0 {
+		return "", false
+	}
+	return m.actions[last], true
+}
@@ -0,0 +1,83 @@
+package piiscrubber
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Drop removes the values a JSONRule selects, along with their keys. It is
+// only valid in JSON rules
+const Drop Action = "drop"
+
+var (
+	// ErrInvalidJSON ...
+	ErrInvalidJSON = fmt.Errorf("invalid JSON document")
+)
+
+// JSONRule applies Action to the values Path selects and to everything
+// nested in them, unless a rule selects a nested value itself
+//
+// Path is a JSONPath made of $, .name, ['name'], [n], .*, [*] and the
+// recursive descent ..name, e.g. $.user.email, $.items[*].note or
+// $..password. Action is Scan, Redact, Keep or Drop
+type JSONRule struct {
+	Path   string
+	Action Action
+}
+
+// JSONPolicy tells ScrubJSON how to scrub a document. Strings no rule
+// selects are scanned, when several rules select a value the last one wins.
+// The KeyRules of the Scrubber apply as well, JSON rules take precedence
+type JSONPolicy struct {
+	Scrubber Scrubber
+	Rules    []JSONRule
+}
+
+func (p JSONPolicy) compile() (*jsonPathMatcher, error) {
+	if p.Scrubber == nil {
+		return nil, fmt.Errorf("JSON policy has no scrubber")
+	}
+	if len(p.Rules) == 0 {
+		return nil, nil
+	}
+
+	m := &jsonPathMatcher{}
+	for _, rule := range p.Rules {
+		if rule.Action != Drop {
+			if err := rule.Action.isValid(); err != nil {
+				return nil, fmt.Errorf("in JSON rule for path: %v, error: %v", rule.Path, err.Error())
+			}
+		}
+		segments, err := parseJSONPath(rule.Path)
+		if err != nil {
+			return nil, fmt.Errorf("in JSON rule for path: %v, error: %v", rule.Path, err.Error())
+		}
+		if len(segments) == 0 && rule.Action == Drop {
+			return nil, fmt.Errorf("in JSON rule for path: %v, error: the root cannot be dropped", rule.Path)
+		}
+		m.rules = append(m.rules, segments)
+		m.actions = append(m.actions, rule.Action)
+	}
+	return m, nil
+}
+
+// ScrubJSON returns a scrubbed copy of the JSON document data. The order of
+// keys and the formatting of numbers are kept, whitespace between tokens is
+// not. Returns ErrInvalidJSON when data is not a single JSON value
+func ScrubJSON(data []byte, policy JSONPolicy) ([]byte, error) {
+	paths, err := policy.compile()
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(data) {
+		return nil, ErrInvalidJSON
+	}
+
+	var out bytes.Buffer
+	out.Grow(len(data))
+	if err := newJSONStreamScrubber(policy.Scrubber, paths, bytes.NewReader(data), &out).run(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
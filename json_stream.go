@@ -0,0 +1,45 @@
+package piiscrubber
+
+import (
+	"bufio"
+	"io"
+)
+
+// _jsonStreamBufferSize is the size of the buffered writer streams are
+// written through
+const _jsonStreamBufferSize = 64 * 1024
+
+// ScrubJSONStream scrubs a stream of JSON values read from r, such as one
+// huge array, and writes them to w separated by newlines. Memory use grows
+// with the nesting depth and the longest string, not with the size of the
+// stream, and strings are scrubbed in batches. Rules apply from the root of each value,
+// e.g. $[*].email for the elements of an array
+//
+// Values are written as they are scrubbed, so when the stream turns out to be
+// invalid part of it may have been written already. The error returned then
+// wraps ErrInvalidJSON
+func ScrubJSONStream(r io.Reader, w io.Writer, policy JSONPolicy) error {
+	return scrubJSONValues(r, w, policy, false)
+}
+
+// ScrubNDJSON scrubs newline-delimited JSON records read from r and writes
+// them to w, one record per line. Rules apply from the root of each record,
+// e.g. $.email, otherwise it works as ScrubJSONStream does
+func ScrubNDJSON(r io.Reader, w io.Writer, policy JSONPolicy) error {
+	return scrubJSONValues(r, w, policy, true)
+}
+
+func scrubJSONValues(r io.Reader, w io.Writer, policy JSONPolicy, terminate bool) error {
+	paths, err := policy.compile()
+	if err != nil {
+		return err
+	}
+
+	buffered := bufio.NewWriterSize(w, _jsonStreamBufferSize)
+	j := newJSONStreamScrubber(policy.Scrubber, paths, r, buffered)
+	j.terminate = terminate
+	if err := j.run(); err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
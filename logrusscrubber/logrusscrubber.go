@@ -0,0 +1,179 @@
+// Package logrusscrubber provides a logrus.Hook and a logrus.Formatter that
+// scrub the message and the fields of every entry with a
+// piiscrubber.Scrubber
+//
+//	logger.AddHook(logrusscrubber.NewHook(scrubber, nil))
+//	logger.SetFormatter(logrusscrubber.NewFormatter(&logrus.JSONFormatter{}, scrubber, nil))
+//
+// It lives in its own module so that the library doesn't depend on logrus
+package logrusscrubber
+
+import (
+	"fmt"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/sirupsen/logrus"
+)
+
+const _redactedValue = "<REDACTED>"
+
+// Options ...
+type Options struct {
+	// RedactKeys are field keys whose values are always replaced with
+	// <REDACTED>, e.g. "password"
+	RedactKeys []string
+	// ExemptKeys are field keys whose values are never scrubbed, e.g. trace ids
+	// that look like a GUID or SHA1Hex
+	ExemptKeys []string
+}
+
+type entryScrubber struct {
+	scrubber   piiscrubber.Scrubber
+	redactKeys map[string]bool
+	exemptKeys map[string]bool
+}
+
+func newEntryScrubber(s piiscrubber.Scrubber, opts *Options) entryScrubber {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	e := entryScrubber{
+		scrubber:   s,
+		redactKeys: make(map[string]bool, len(opts.RedactKeys)),
+		exemptKeys: make(map[string]bool, len(opts.ExemptKeys)),
+	}
+	for _, key := range opts.RedactKeys {
+		e.redactKeys[key] = true
+	}
+	for _, key := range opts.ExemptKeys {
+		e.exemptKeys[key] = true
+	}
+	return e
+}
+
+// Hook scrubs entries before they are formatted. Hooks added before it see
+// the entries unscrubbed, so it is best added first
+type Hook struct {
+	entryScrubber
+}
+
+// NewHook returns a Hook that scrubs the message and the fields of entries of
+// all levels. Strings and errors are scrubbed with a single ScrubTexts call
+// per entry. Other values, e.g. structs passed with WithField, are scrubbed
+// with ScrubStruct as if they were tagged with pii:"true", the same way
+// piiscrubber.NewSlogHandler scrubs them, since log fields are untrusted
+func NewHook(s piiscrubber.Scrubber, opts *Options) *Hook {
+	return &Hook{entryScrubber: newEntryScrubber(s, opts)}
+}
+
+// Levels ...
+func (h *Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire replaces the message and the fields of entry with scrubbed ones. The
+// fields are replaced rather than modified since logrus shares them between
+// the entries of a logger created with WithFields. logrus writes the entry
+// even when a hook fails, so on errors the message and the values of all
+// fields are replaced with <REDACTED>
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	message, data, err := h.scrubEntry(entry.Message, entry.Data)
+	if err != nil {
+		// never let the entry be written unscrubbed
+		redacted := make(logrus.Fields, len(entry.Data))
+		for key := range entry.Data {
+			redacted[key] = _redactedValue
+		}
+		entry.Message = _redactedValue
+		entry.Data = redacted
+		return err
+	}
+
+	entry.Message = message
+	entry.Data = data
+	return nil
+}
+
+// Formatter scrubs entries before passing them on to another formatter, so
+// that the entries seen by hooks are left unscrubbed
+type Formatter struct {
+	entryScrubber
+	next logrus.Formatter
+}
+
+// NewFormatter returns a Formatter that scrubs the message and the fields of
+// entries the same way NewHook does, then formats them with next
+func NewFormatter(next logrus.Formatter, s piiscrubber.Scrubber, opts *Options) *Formatter {
+	return &Formatter{
+		entryScrubber: newEntryScrubber(s, opts),
+		next:          next,
+	}
+}
+
+// Format ...
+func (f *Formatter) Format(entry *logrus.Entry) ([]byte, error) {
+	message, data, err := f.scrubEntry(entry.Message, entry.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	scrubbed := *entry
+	scrubbed.Message = message
+	scrubbed.Data = data
+	return f.next.Format(&scrubbed)
+}
+
+// scrubEntry returns scrubbed copies of message and data. The texts of all
+// fields are collected first and scrubbed with a single ScrubTexts call
+func (e entryScrubber) scrubEntry(message string, data logrus.Fields) (string, logrus.Fields, error) {
+	texts := []string{message}
+	// textKeys[i] is the key texts[i+1] came from
+	textKeys := make([]string, 0, len(data))
+
+	scrubbed := make(logrus.Fields, len(data))
+	for key, value := range data {
+		if e.exemptKeys[key] {
+			scrubbed[key] = value
+			continue
+		}
+		if e.redactKeys[key] {
+			scrubbed[key] = _redactedValue
+			continue
+		}
+
+		switch v := value.(type) {
+		case string:
+			texts = append(texts, v)
+			textKeys = append(textKeys, key)
+		case error:
+			texts = append(texts, v.Error())
+			textKeys = append(textKeys, key)
+		case fmt.Stringer:
+			// fmt recovers from String panicking on nil pointers
+			texts = append(texts, fmt.Sprint(v))
+			textKeys = append(textKeys, key)
+		default:
+			copy, err := piiscrubber.ScrubValue(e.scrubber, value, true)
+			if err != nil {
+				return "", nil, err
+			}
+			scrubbed[key] = copy
+		}
+	}
+
+	scrubbedTexts, err := e.scrubber.ScrubTexts(texts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	for i, key := range textKeys {
+		scrubbed[key] = scrubbedTexts[i+1]
+	}
+	return scrubbedTexts[0], scrubbed, nil
+}
+
+var (
+	_ logrus.Hook      = &Hook{}
+	_ logrus.Formatter = &Formatter{}
+)
@@ -0,0 +1,125 @@
+package logrusscrubber_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/aavaz-ai/pii-scrubber/logrusscrubber"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	Name  string `pii:"true" json:"name"`
+	Email string `pii:"true" json:"email"`
+	// not tagged, scrubbed all the same since log fields are untrusted
+	Support string `json:"support"`
+}
+
+var _options = &logrusscrubber.Options{
+	RedactKeys: []string{"password"},
+	ExemptKeys: []string{"trace_id"},
+}
+
+func newTestScrubber(t *testing.T) piiscrubber.Scrubber {
+	scrubber, err := piiscrubber.New(piiscrubber.Params{
+		BlacklistedEntities: []piiscrubber.Entity{
+			piiscrubber.Email,
+			piiscrubber.GUID,
+		},
+	})
+	assert.NoError(t, err)
+	return scrubber
+}
+
+func newTestLogger(buf *bytes.Buffer, formatter logrus.Formatter) *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(buf)
+	logger.SetFormatter(formatter)
+	return logger
+}
+
+func logTestEntry(logger *logrus.Logger) {
+	logger.WithFields(logrus.Fields{
+		"password": "hunter2",
+		"trace_id": "0f8fad5b-d9cb-469f-a165-70867728950e",
+		"session":  "0f8fad5b-d9cb-469f-a165-70867728950e",
+		"user":     user{Name: "Jane", Email: "jane@example.com", Support: "help@example.com"},
+		"tags":     []string{"jane@example.com"},
+		"attempt":  3,
+	}).WithError(errors.New("no mailbox jane@example.com")).Info("signup by jane@example.com")
+}
+
+// JSONFormatter escapes < and >
+const _expectedEntry = `{"attempt":3,` +
+	`"error":"no mailbox \u003cEMAIL_ADDRESS\u003e",` +
+	`"level":"info",` +
+	`"msg":"signup by \u003cEMAIL_ADDRESS\u003e",` +
+	`"password":"\u003cREDACTED\u003e",` +
+	`"session":"\u003cGUID\u003e",` +
+	`"tags":["\u003cEMAIL_ADDRESS\u003e"],` +
+	`"trace_id":"0f8fad5b-d9cb-469f-a165-70867728950e",` +
+	`"user":{"name":"Jane","email":"\u003cEMAIL_ADDRESS\u003e","support":"\u003cEMAIL_ADDRESS\u003e"}}` + "\n"
+
+func TestHook(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf, &logrus.JSONFormatter{DisableTimestamp: true})
+	logger.AddHook(logrusscrubber.NewHook(newTestScrubber(t), _options))
+
+	logTestEntry(logger)
+
+	assert.Equal(t, _expectedEntry, buf.String())
+}
+
+func TestFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf, logrusscrubber.NewFormatter(&logrus.JSONFormatter{DisableTimestamp: true}, newTestScrubber(t), _options))
+
+	logTestEntry(logger)
+
+	assert.Equal(t, _expectedEntry, buf.String())
+}
+
+func TestHook_SharedFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf, &logrus.TextFormatter{DisableTimestamp: true})
+	logger.AddHook(logrusscrubber.NewHook(newTestScrubber(t), nil))
+
+	entry := logger.WithField("owner", "jane@example.com")
+	entry.Info("first")
+
+	assert.Equal(t, "jane@example.com", entry.Data["owner"])
+	assert.Equal(t, "level=info msg=first owner=\"<EMAIL_ADDRESS>\"\n", buf.String())
+}
+
+type failingScrubber struct{}
+
+func (failingScrubber) ScrubTexts(texts []string) ([]string, error) {
+	return nil, errors.New("scrubber failed")
+}
+
+func (failingScrubber) ScrubStruct(obj interface{}) (interface{}, error) {
+	return nil, errors.New("scrubber failed")
+}
+
+func TestHook_FailsClosed(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf, &logrus.JSONFormatter{DisableTimestamp: true})
+	logger.AddHook(logrusscrubber.NewHook(failingScrubber{}, _options))
+
+	logTestEntry(logger)
+
+	assert.NotContains(t, buf.String(), "jane@example.com")
+	assert.NotContains(t, buf.String(), "hunter2")
+	assert.Equal(t, `{"attempt":"\u003cREDACTED\u003e",`+
+		`"error":"\u003cREDACTED\u003e",`+
+		`"level":"info",`+
+		`"msg":"\u003cREDACTED\u003e",`+
+		`"password":"\u003cREDACTED\u003e",`+
+		`"session":"\u003cREDACTED\u003e",`+
+		`"tags":"\u003cREDACTED\u003e",`+
+		`"trace_id":"\u003cREDACTED\u003e",`+
+		`"user":"\u003cREDACTED\u003e"}`+"\n", buf.String())
+}
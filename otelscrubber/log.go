@@ -0,0 +1,121 @@
+package otelscrubber
+
+import (
+	"context"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// pendingValue is a log value whose strings were queued for scrubbing, build
+// returns it once the batch is scrubbed
+type pendingValue struct {
+	value log.Value
+	text  *string
+	slice []*pendingValue
+	keys  []string
+	kvs   []*pendingValue
+}
+
+// addValue queues the strings of value for scrubbing according to action.
+// The values of nested maps take the action of the rule matching their key,
+// if any
+func (a *attributeScrubber) addValue(batch *piiscrubber.TextBatch, action piiscrubber.Action, value log.Value) *pendingValue {
+	pending := &pendingValue{value: value}
+
+	switch value.Kind() {
+	case log.KindString:
+		text := value.AsString()
+		addText(batch, action, &text)
+		pending.text = &text
+
+	case log.KindSlice:
+		for _, element := range value.AsSlice() {
+			pending.slice = append(pending.slice, a.addValue(batch, action, element))
+		}
+
+	case log.KindMap:
+		for _, kv := range value.AsMap() {
+			pending.keys = append(pending.keys, kv.Key)
+			pending.kvs = append(pending.kvs, a.addValue(batch, a.actionFor(kv.Key, action), kv.Value))
+		}
+	}
+	return pending
+}
+
+func (p *pendingValue) build() log.Value {
+	switch {
+	case p.text != nil:
+		return log.StringValue(*p.text)
+
+	case p.value.Kind() == log.KindSlice:
+		values := make([]log.Value, len(p.slice))
+		for i, element := range p.slice {
+			values[i] = element.build()
+		}
+		return log.SliceValue(values...)
+
+	case p.value.Kind() == log.KindMap:
+		kvs := make([]log.KeyValue, len(p.kvs))
+		for i, value := range p.kvs {
+			kvs[i] = log.KeyValue{Key: p.keys[i], Value: value.build()}
+		}
+		return log.MapValue(kvs...)
+	}
+	return p.value
+}
+
+// scrubRecord scrubs the body and the attributes of record in place, all of
+// their strings are scrubbed with a single ScrubTexts call
+func (a *attributeScrubber) scrubRecord(record *sdklog.Record) error {
+	var batch piiscrubber.TextBatch
+
+	// the body has no key of its own, only the keys of its maps match rules
+	body := a.addValue(&batch, piiscrubber.Scan, record.Body())
+
+	var keys []string
+	var values []*pendingValue
+	record.WalkAttributes(func(kv log.KeyValue) bool {
+		keys = append(keys, kv.Key)
+		values = append(values, a.addValue(&batch, a.actionFor(kv.Key, piiscrubber.Scan), kv.Value))
+		return true
+	})
+
+	if err := batch.Scrub(a.scrubber); err != nil {
+		return err
+	}
+
+	record.SetBody(body.build())
+	attrs := make([]log.KeyValue, len(keys))
+	for i, key := range keys {
+		attrs[i] = log.KeyValue{Key: key, Value: values[i].build()}
+	}
+	record.SetAttributes(attrs...)
+	return nil
+}
+
+type logProcessor struct {
+	sdklog.Processor
+	scrubber *attributeScrubber
+}
+
+// NewLogProcessor returns a log Processor that passes scrubbed copies of the
+// records emitted on to next, e.g. a batch processor. Records that can't be
+// scrubbed are dropped
+func NewLogProcessor(next sdklog.Processor, s piiscrubber.Scrubber, opts *Options) (sdklog.Processor, error) {
+	scrubber, err := newAttributeScrubber(s, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &logProcessor{Processor: next, scrubber: scrubber}, nil
+}
+
+func (p *logProcessor) OnEmit(ctx context.Context, record *sdklog.Record) error {
+	// processors added after this one may still want the record as it is
+	scrubbed := record.Clone()
+	if err := p.scrubber.scrubRecord(&scrubbed); err != nil {
+		return err
+	}
+	return p.Processor.OnEmit(ctx, &scrubbed)
+}
@@ -0,0 +1,265 @@
+// Package otelscrubber provides OpenTelemetry SDK span processors, span
+// exporters and log processors that scrub span names, attribute values, event
+// attributes and log bodies with a piiscrubber.Scrubber before they are
+// exported
+//
+//	processor, err := otelscrubber.NewSpanProcessor(sdktrace.NewBatchSpanProcessor(exporter), scrubber, nil)
+//	if err != nil {
+//		return err
+//	}
+//	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(processor))
+//
+// It lives in its own module so that the library doesn't depend on the
+// OpenTelemetry SDK
+package otelscrubber
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const _redactedValue = "<REDACTED>"
+
+// Actions specific to telemetry attributes, besides piiscrubber.Scan,
+// piiscrubber.Redact and piiscrubber.Keep
+const (
+	// RedactQuery replaces the query values of a URL with <REDACTED> and
+	// scans the rest of it
+	RedactQuery piiscrubber.Action = "redact_query"
+	// RedactSQLLiterals replaces the string and number literals of a SQL
+	// statement with ? and leaves the rest of it as it is
+	RedactSQLLiterals piiscrubber.Action = "redact_sql_literals"
+)
+
+// AttributeRule applies Action to the values of the attributes whose key
+// matches Pattern, a regular expression. Keep works as an allow rule and
+// Redact as a deny rule, the last matching rule wins
+type AttributeRule struct {
+	Pattern string
+	Action  piiscrubber.Action
+}
+
+// DefaultAttributeRules redact the query strings of URLs and the literals of
+// database statements, following the OpenTelemetry semantic conventions
+var DefaultAttributeRules = []AttributeRule{
+	{Pattern: `^(http\.url|url\.full|url\.query|http\.target)$`, Action: RedactQuery},
+	{Pattern: `^(db\.statement|db\.query\.text)$`, Action: RedactSQLLiterals},
+}
+
+// Options ...
+type Options struct {
+	// AttributeRules apply to span, event, link and log attributes, and to
+	// the keys of log bodies. Attributes no rule matches are scanned.
+	// Defaults to DefaultAttributeRules
+	AttributeRules []AttributeRule
+}
+
+type compiledRule struct {
+	regex  *regexp.Regexp
+	action piiscrubber.Action
+}
+
+// attributeScrubber holds what span and log scrubbing share
+type attributeScrubber struct {
+	scrubber piiscrubber.Scrubber
+	rules    []compiledRule
+}
+
+func newAttributeScrubber(s piiscrubber.Scrubber, opts *Options) (*attributeScrubber, error) {
+	rules := DefaultAttributeRules
+	if opts != nil && opts.AttributeRules != nil {
+		rules = opts.AttributeRules
+	}
+
+	a := &attributeScrubber{scrubber: s}
+	for _, rule := range rules {
+		switch rule.Action {
+		case piiscrubber.Scan, piiscrubber.Redact, piiscrubber.Keep, RedactQuery, RedactSQLLiterals:
+		default:
+			return nil, fmt.Errorf("in attribute rule for pattern: %v, error: unknown action: %q", rule.Pattern, rule.Action)
+		}
+		regex, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("in attribute rule for pattern: %v, error: %v", rule.Pattern, err.Error())
+		}
+		a.rules = append(a.rules, compiledRule{regex: regex, action: rule.Action})
+	}
+	return a, nil
+}
+
+// actionFor returns the action of the last rule matching key, inherited when
+// none matches
+func (a *attributeScrubber) actionFor(key string, inherited piiscrubber.Action) piiscrubber.Action {
+	action := inherited
+	for _, rule := range a.rules {
+		if rule.regex.MatchString(key) {
+			action = rule.action
+		}
+	}
+	return action
+}
+
+// addText queues text for scrubbing according to action, after applying the
+// rewrites of RedactQuery and RedactSQLLiterals
+func addText(batch *piiscrubber.TextBatch, action piiscrubber.Action, text *string) {
+	switch action {
+	case piiscrubber.Keep:
+	case piiscrubber.Redact:
+		*text = _redactedValue
+	case RedactSQLLiterals:
+		*text = redactSQLLiterals(*text)
+	case RedactQuery:
+		*text = redactQuery(*text)
+		batch.Add(text)
+	default:
+		batch.Add(text)
+	}
+}
+
+// pendingAttributes are attributes whose strings were queued for scrubbing,
+// build returns them once the batch is scrubbed
+type pendingAttributes struct {
+	attrs []attribute.KeyValue
+	// texts and slices hold the queued strings of string and string slice
+	// attributes, by the index of the attribute
+	texts  map[int]*string
+	slices map[int][]string
+}
+
+func (a *attributeScrubber) addAttributes(batch *piiscrubber.TextBatch, attrs []attribute.KeyValue) *pendingAttributes {
+	pending := &pendingAttributes{
+		attrs:  attrs,
+		texts:  make(map[int]*string),
+		slices: make(map[int][]string),
+	}
+	for i, attr := range attrs {
+		action := a.actionFor(string(attr.Key), piiscrubber.Scan)
+		switch attr.Value.Type() {
+		case attribute.STRING:
+			text := attr.Value.AsString()
+			addText(batch, action, &text)
+			pending.texts[i] = &text
+		case attribute.STRINGSLICE:
+			slice := attr.Value.AsStringSlice()
+			for j := range slice {
+				addText(batch, action, &slice[j])
+			}
+			pending.slices[i] = slice
+		}
+	}
+	return pending
+}
+
+func (p *pendingAttributes) build() []attribute.KeyValue {
+	if len(p.texts) == 0 && len(p.slices) == 0 {
+		return p.attrs
+	}
+
+	attrs := make([]attribute.KeyValue, len(p.attrs))
+	for i, attr := range p.attrs {
+		switch {
+		case p.texts[i] != nil:
+			attrs[i] = attr.Key.String(*p.texts[i])
+		case p.slices[i] != nil:
+			attrs[i] = attr.Key.StringSlice(p.slices[i])
+		default:
+			attrs[i] = attr
+		}
+	}
+	return attrs
+}
+
+// redactQuery replaces the query values of a URL, keeping their keys
+func redactQuery(text string) string {
+	u, err := url.Parse(text)
+	if err != nil {
+		// not a URL, the whole text is scanned
+		return text
+	}
+	// http.target holds the path and the query only
+	if u.RawQuery == "" {
+		return text
+	}
+
+	pairs := strings.Split(u.RawQuery, "&")
+	for i, pair := range pairs {
+		if key, _, ok := strings.Cut(pair, "="); ok {
+			pairs[i] = key + "=" + _redactedValue
+		}
+	}
+	u.RawQuery = strings.Join(pairs, "&")
+	return u.String()
+}
+
+// redactSQLLiterals replaces the quoted strings and the numbers of a SQL
+// statement with ?. Quoted identifiers and comments are left as they are
+func redactSQLLiterals(statement string) string {
+	var out strings.Builder
+	out.Grow(len(statement))
+
+	for i := 0; i < len(statement); {
+		c := statement[i]
+		switch {
+		case c == '\'':
+			// a string literal, quotes inside it are doubled
+			j := i + 1
+			for j < len(statement) {
+				if statement[j] == '\'' {
+					if j+1 < len(statement) && statement[j+1] == '\'' {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			out.WriteByte('?')
+			i = j + 1
+
+		case c == '"' || c == '`':
+			// a quoted identifier
+			j := strings.IndexByte(statement[i+1:], c)
+			if j < 0 {
+				out.WriteString(statement[i:])
+				return out.String()
+			}
+			out.WriteString(statement[i : i+j+2])
+			i += j + 2
+
+		case c == '-' && strings.HasPrefix(statement[i:], "--"):
+			j := strings.IndexByte(statement[i:], '\n')
+			if j < 0 {
+				out.WriteString(statement[i:])
+				return out.String()
+			}
+			out.WriteString(statement[i : i+j])
+			i += j
+
+		case isDigit(c) && (i == 0 || !isIdentifier(statement[i-1])):
+			j := i
+			for j < len(statement) && (isIdentifier(statement[j]) || statement[j] == '.') {
+				j++
+			}
+			out.WriteByte('?')
+			i = j
+
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return out.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentifier(c byte) bool {
+	return isDigit(c) || c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
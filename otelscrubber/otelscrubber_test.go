@@ -0,0 +1,171 @@
+package otelscrubber_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/aavaz-ai/pii-scrubber/otelscrubber"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestScrubber(t *testing.T) piiscrubber.Scrubber {
+	scrubber, err := piiscrubber.New(piiscrubber.Params{
+		BlacklistedEntities: []piiscrubber.Entity{
+			piiscrubber.Email,
+			piiscrubber.Phone,
+		},
+	})
+	assert.NoError(t, err)
+	return scrubber
+}
+
+var _testOptions = &otelscrubber.Options{
+	AttributeRules: append([]otelscrubber.AttributeRule{
+		{Pattern: `^session\.`, Action: piiscrubber.Redact},
+		{Pattern: `^service\.owner$`, Action: piiscrubber.Keep},
+	}, otelscrubber.DefaultAttributeRules...),
+}
+
+func recordTestSpan(tp *sdktrace.TracerProvider) {
+	_, span := tp.Tracer("test").Start(context.Background(), "signup jane@example.com")
+	span.SetAttributes(
+		attribute.String("enduser.id", "jane@example.com"),
+		attribute.String("http.url", "https://example.com/signup?email=jane%40example.com&page=2"),
+		attribute.String("db.statement", `SELECT * FROM "users" WHERE email = 'jane@example.com' AND age > 30`),
+		attribute.String("session.token", "abc"),
+		attribute.String("service.owner", "team@example.com"),
+		attribute.StringSlice("contacts", []string{"+919140520809"}),
+		attribute.Int("attempt", 3),
+	)
+	span.AddEvent("sent", trace.WithAttributes(attribute.String("to", "john@example.com")))
+	span.RecordError(errors.New("no mailbox jane@example.com"))
+	span.SetStatus(codes.Error, "failed for jane@example.com")
+	span.End()
+}
+
+func assertScrubbedSpan(t *testing.T, spans tracetest.SpanStubs) {
+	if !assert.Len(t, spans, 1) {
+		return
+	}
+	span := spans[0]
+
+	assert.Equal(t, "signup <EMAIL_ADDRESS>", span.Name)
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("enduser.id", "<EMAIL_ADDRESS>"),
+		attribute.String("http.url", "https://example.com/signup?email=<REDACTED>&page=<REDACTED>"),
+		attribute.String("db.statement", `SELECT * FROM "users" WHERE email = ? AND age > ?`),
+		attribute.String("session.token", "<REDACTED>"),
+		attribute.String("service.owner", "team@example.com"),
+		attribute.StringSlice("contacts", []string{"<PHONE_NUMBER>"}),
+		attribute.Int("attempt", 3),
+	}, span.Attributes)
+	assert.Equal(t, attribute.String("to", "<EMAIL_ADDRESS>"), span.Events[0].Attributes[0])
+	assert.Contains(t, span.Events[1].Attributes, attribute.String("exception.message", "no mailbox <EMAIL_ADDRESS>"))
+	assert.Equal(t, "failed for <EMAIL_ADDRESS>", span.Status.Description)
+}
+
+func TestSpanProcessor(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	processor, err := otelscrubber.NewSpanProcessor(sdktrace.NewSimpleSpanProcessor(exporter), newTestScrubber(t), _testOptions)
+	assert.NoError(t, err)
+
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(processor))
+	recordTestSpan(tp)
+
+	assertScrubbedSpan(t, exporter.GetSpans())
+}
+
+func TestSpanExporter(t *testing.T) {
+	inMemory := tracetest.NewInMemoryExporter()
+	exporter, err := otelscrubber.NewSpanExporter(inMemory, newTestScrubber(t), _testOptions)
+	assert.NoError(t, err)
+
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	recordTestSpan(tp)
+
+	assertScrubbedSpan(t, inMemory.GetSpans())
+}
+
+func TestNewSpanProcessor_InvalidRule(t *testing.T) {
+	_, err := otelscrubber.NewSpanProcessor(nil, newTestScrubber(t), &otelscrubber.Options{
+		AttributeRules: []otelscrubber.AttributeRule{{Pattern: "(", Action: piiscrubber.Redact}},
+	})
+	assert.Error(t, err)
+
+	_, err = otelscrubber.NewSpanProcessor(nil, newTestScrubber(t), &otelscrubber.Options{
+		AttributeRules: []otelscrubber.AttributeRule{{Pattern: "db", Action: "drop"}},
+	})
+	assert.Error(t, err)
+}
+
+// recordingExporter keeps the records it is given
+type recordingExporter struct {
+	records []sdklog.Record
+}
+
+func (e *recordingExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	for _, record := range records {
+		e.records = append(e.records, record.Clone())
+	}
+	return nil
+}
+
+func (e *recordingExporter) Shutdown(ctx context.Context) error   { return nil }
+func (e *recordingExporter) ForceFlush(ctx context.Context) error { return nil }
+
+func TestLogProcessor(t *testing.T) {
+	exporter := &recordingExporter{}
+	processor, err := otelscrubber.NewLogProcessor(sdklog.NewSimpleProcessor(exporter), newTestScrubber(t), _testOptions)
+	assert.NoError(t, err)
+
+	lp := sdklog.NewLoggerProvider(sdklog.WithProcessor(processor))
+
+	var record log.Record
+	record.SetBody(log.MapValue(
+		log.String("message", "signup by jane@example.com"),
+		log.Map("session.user", log.String("id", "jane@example.com")),
+	))
+	record.AddAttributes(
+		log.String("enduser.id", "jane@example.com"),
+		log.String("url.full", "https://example.com/?q=jane"),
+		log.Slice("contacts", log.StringValue("+919140520809")),
+		log.Int("attempt", 3),
+	)
+	lp.Logger("test").Emit(context.Background(), record)
+
+	if !assert.Len(t, exporter.records, 1) {
+		return
+	}
+	scrubbed := exporter.records[0]
+
+	assert.True(t, log.MapValue(
+		log.String("message", "signup by <EMAIL_ADDRESS>"),
+		log.Map("session.user", log.String("id", "<REDACTED>")),
+	).Equal(scrubbed.Body()), "got %v", scrubbed.Body())
+
+	var attrs []log.KeyValue
+	scrubbed.WalkAttributes(func(kv log.KeyValue) bool {
+		attrs = append(attrs, kv)
+		return true
+	})
+	expected := []log.KeyValue{
+		log.String("enduser.id", "<EMAIL_ADDRESS>"),
+		log.String("url.full", "https://example.com/?q=<REDACTED>"),
+		log.Slice("contacts", log.StringValue("<PHONE_NUMBER>")),
+		log.Int("attempt", 3),
+	}
+	if assert.Len(t, attrs, len(expected)) {
+		for i := range expected {
+			assert.True(t, expected[i].Equal(attrs[i]), "got %v", attrs[i])
+		}
+	}
+}
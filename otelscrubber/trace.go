@@ -0,0 +1,139 @@
+package otelscrubber
+
+import (
+	"context"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// scrubbedSpan is a read-only span with its name, attributes, events, links
+// and status description replaced by scrubbed ones
+type scrubbedSpan struct {
+	sdktrace.ReadOnlySpan
+	name       string
+	attributes []attribute.KeyValue
+	events     []sdktrace.Event
+	links      []sdktrace.Link
+	status     sdktrace.Status
+}
+
+func (s *scrubbedSpan) Name() string                     { return s.name }
+func (s *scrubbedSpan) Attributes() []attribute.KeyValue { return s.attributes }
+func (s *scrubbedSpan) Events() []sdktrace.Event         { return s.events }
+func (s *scrubbedSpan) Links() []sdktrace.Link           { return s.links }
+func (s *scrubbedSpan) Status() sdktrace.Status          { return s.status }
+
+// scrubSpan returns a scrubbed view of span, all of its strings are scrubbed
+// with a single ScrubTexts call
+func (a *attributeScrubber) scrubSpan(span sdktrace.ReadOnlySpan) (sdktrace.ReadOnlySpan, error) {
+	var batch piiscrubber.TextBatch
+
+	scrubbed := &scrubbedSpan{
+		ReadOnlySpan: span,
+		name:         span.Name(),
+		status:       span.Status(),
+	}
+	batch.Add(&scrubbed.name)
+	batch.Add(&scrubbed.status.Description)
+
+	attributes := a.addAttributes(&batch, span.Attributes())
+
+	events := span.Events()
+	eventAttributes := make([]*pendingAttributes, len(events))
+	scrubbed.events = make([]sdktrace.Event, len(events))
+	for i, event := range events {
+		scrubbed.events[i] = event
+		eventAttributes[i] = a.addAttributes(&batch, event.Attributes)
+	}
+
+	links := span.Links()
+	linkAttributes := make([]*pendingAttributes, len(links))
+	scrubbed.links = make([]sdktrace.Link, len(links))
+	for i, link := range links {
+		scrubbed.links[i] = link
+		linkAttributes[i] = a.addAttributes(&batch, link.Attributes)
+	}
+
+	if err := batch.Scrub(a.scrubber); err != nil {
+		return nil, err
+	}
+
+	scrubbed.attributes = attributes.build()
+	for i := range scrubbed.events {
+		scrubbed.events[i].Attributes = eventAttributes[i].build()
+	}
+	for i := range scrubbed.links {
+		scrubbed.links[i].Attributes = linkAttributes[i].build()
+	}
+	return scrubbed, nil
+}
+
+// scrubSpans scrubs spans, a span that can't be scrubbed is dropped rather
+// than exported as it is
+func (a *attributeScrubber) scrubSpans(spans []sdktrace.ReadOnlySpan) ([]sdktrace.ReadOnlySpan, error) {
+	scrubbed := make([]sdktrace.ReadOnlySpan, 0, len(spans))
+	var firstErr error
+	for _, span := range spans {
+		s, err := a.scrubSpan(span)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		scrubbed = append(scrubbed, s)
+	}
+	return scrubbed, firstErr
+}
+
+type spanProcessor struct {
+	sdktrace.SpanProcessor
+	scrubber *attributeScrubber
+}
+
+// NewSpanProcessor returns a SpanProcessor that passes scrubbed views of the
+// spans that ended on to next, e.g. a batch span processor. Spans that can't
+// be scrubbed are dropped
+func NewSpanProcessor(next sdktrace.SpanProcessor, s piiscrubber.Scrubber, opts *Options) (sdktrace.SpanProcessor, error) {
+	scrubber, err := newAttributeScrubber(s, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &spanProcessor{SpanProcessor: next, scrubber: scrubber}, nil
+}
+
+func (p *spanProcessor) OnEnd(span sdktrace.ReadOnlySpan) {
+	scrubbed, err := p.scrubber.scrubSpan(span)
+	if err != nil {
+		return
+	}
+	p.SpanProcessor.OnEnd(scrubbed)
+}
+
+type spanExporter struct {
+	sdktrace.SpanExporter
+	scrubber *attributeScrubber
+}
+
+// NewSpanExporter returns a SpanExporter that scrubs spans before exporting
+// them with next. Spans that can't be scrubbed are dropped and their error
+// is returned once the others are exported
+func NewSpanExporter(next sdktrace.SpanExporter, s piiscrubber.Scrubber, opts *Options) (sdktrace.SpanExporter, error) {
+	scrubber, err := newAttributeScrubber(s, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &spanExporter{SpanExporter: next, scrubber: scrubber}, nil
+}
+
+func (e *spanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	scrubbed, scrubErr := e.scrubber.scrubSpans(spans)
+	if len(scrubbed) > 0 {
+		if err := e.SpanExporter.ExportSpans(ctx, scrubbed); err != nil {
+			return err
+		}
+	}
+	return scrubErr
+}
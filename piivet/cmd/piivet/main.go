@@ -0,0 +1,13 @@
+// Command piivet runs the piivet analyzer, standalone or as a go vet tool:
+//
+//	go vet -vettool=$(which piivet) ./...
+package main
+
+import (
+	"github.com/aavaz-ai/pii-scrubber/piivet"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(piivet.Analyzer)
+}
@@ -0,0 +1,511 @@
+// Package piivet provides an analyzer that reports values with pii tagged
+// fields flowing into logs, fmt and JSON encoding without going through
+// ScrubStruct
+//
+// It can be run through go vet:
+//
+//	go install github.com/aavaz-ai/pii-scrubber/piivet/cmd/piivet@latest
+//	go vet -vettool=$(which piivet) ./...
+package piivet
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+const (
+	_piiTag          = "pii"
+	_piiTagValueTrue = "true"
+
+	_scrubberPkgPath = "github.com/aavaz-ai/pii-scrubber"
+)
+
+// Analyzer ...
+var Analyzer = &analysis.Analyzer{
+	Name: "piivet",
+	Doc:  "report pii tagged struct fields passed to log, fmt, slog or json.Marshal without ScrubStruct",
+	Run:  run,
+}
+
+// sinks lists the functions and methods, by package path and name, whose
+// arguments end up in logs or serialized output. Methods are keyed by their
+// receiver type name
+var _sinks = map[string]map[string]bool{
+	"fmt": set(
+		"Print", "Printf", "Println",
+		"Sprint", "Sprintf", "Sprintln",
+		"Fprint", "Fprintf", "Fprintln",
+		"Append", "Appendf", "Appendln",
+		"Errorf",
+	),
+	"log": set(
+		"Print", "Printf", "Println",
+		"Fatal", "Fatalf", "Fatalln",
+		"Panic", "Panicf", "Panicln",
+		"Logger.Print", "Logger.Printf", "Logger.Println",
+		"Logger.Fatal", "Logger.Fatalf", "Logger.Fatalln",
+		"Logger.Panic", "Logger.Panicf", "Logger.Panicln",
+	),
+	"log/slog": set(
+		"Debug", "Info", "Warn", "Error", "Log",
+		"DebugContext", "InfoContext", "WarnContext", "ErrorContext",
+		"Any", "Group",
+		"AnyValue", "GroupValue",
+		"Logger.Debug", "Logger.Info", "Logger.Warn", "Logger.Error", "Logger.Log",
+		"Logger.DebugContext", "Logger.InfoContext", "Logger.WarnContext", "Logger.ErrorContext",
+		"Logger.With",
+		"With",
+	),
+	"encoding/json": set(
+		"Marshal", "MarshalIndent",
+		"Encoder.Encode",
+	),
+}
+
+// _scrubbingFuncs return a scrubbed copy of their argument, by qualified name
+// in the pii-scrubber package
+var _scrubbingFuncs = set(
+	"ScrubValue", "ScrubStructWithReport",
+	"Scrubber.ScrubStruct", "FindingsScrubber.ScrubStructWithReport",
+)
+
+// _scrubbingMethods return a scrubbed copy of their argument when their
+// receiver implements piiscrubber.Scrubber
+var _scrubbingMethods = set("ScrubStruct", "ScrubStructWithReport")
+
+// _scrubPIIMethod is the name of the generated methods scrubbing their
+// receiver in place
+const _scrubPIIMethod = "ScrubPII"
+
+func set(names ...string) map[string]bool {
+	m := make(map[string]bool, len(names))
+	for _, name := range names {
+		m[name] = true
+	}
+	return m
+}
+
+type checker struct {
+	pass *analysis.Pass
+	// piiTypes memoizes whether a type holds pii tagged fields
+	piiTypes map[types.Type]bool
+	// scrubbed holds the variables that currently hold a scrubbed copy, at
+	// the point of the walk
+	scrubbed map[types.Object]bool
+	// scrubber is the piiscrubber.Scrubber interface, nil when the package
+	// doesn't import pii-scrubber
+	scrubber *types.Interface
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	c := &checker{
+		pass:     pass,
+		piiTypes: make(map[types.Type]bool),
+		scrubbed: make(map[types.Object]bool),
+		scrubber: scrubberInterface(pass.Pkg),
+	}
+
+	for _, file := range pass.Files {
+		ast.Inspect(file, c.visit)
+	}
+
+	return nil, nil
+}
+
+func scrubberInterface(pkg *types.Package) *types.Interface {
+	for _, imported := range append([]*types.Package{pkg}, pkg.Imports()...) {
+		if imported.Path() != _scrubberPkgPath {
+			continue
+		}
+		if obj, ok := imported.Scope().Lookup("Scrubber").(*types.TypeName); ok {
+			iface, _ := obj.Type().Underlying().(*types.Interface)
+			return iface
+		}
+	}
+	return nil
+}
+
+// visit walks the files in source order, so that a variable is only
+// considered scrubbed after the statement scrubbing it, and until it is
+// assigned again. Branches are not told apart, an assignment in either
+// branch of an if holds after it
+func (c *checker) visit(node ast.Node) bool {
+	switch n := node.(type) {
+	case *ast.AssignStmt:
+		c.walk(n.Rhs...)
+		c.walk(n.Lhs...)
+		if n.Tok != token.ASSIGN && n.Tok != token.DEFINE {
+			return false
+		}
+		scrubbed := c.scrubbedValues(n.Rhs, len(n.Lhs))
+		for i, lhs := range n.Lhs {
+			c.setScrubbed(lhs, scrubbed[i])
+		}
+		return false
+	case *ast.ValueSpec:
+		c.walk(n.Values...)
+		scrubbed := c.scrubbedValues(n.Values, len(n.Names))
+		for i, name := range n.Names {
+			c.setScrubbed(name, scrubbed[i])
+		}
+		return false
+	case *ast.RangeStmt:
+		c.walk(n.X)
+		if n.Key != nil {
+			c.setScrubbed(n.Key, false)
+		}
+		if n.Value != nil {
+			c.setScrubbed(n.Value, false)
+		}
+		ast.Inspect(n.Body, c.visit)
+		return false
+	case *ast.CallExpr:
+		// arguments are evaluated before the call
+		c.walk(n.Fun)
+		c.walk(n.Args...)
+		c.checkCall(n)
+		// generated ScrubPII methods scrub their receiver in place
+		if receiver, ok := c.scrubPIIReceiver(n); ok {
+			c.setScrubbed(receiver, true)
+		}
+		return false
+	}
+	return true
+}
+
+func (c *checker) walk(exprs ...ast.Expr) {
+	for _, expr := range exprs {
+		ast.Inspect(expr, c.visit)
+	}
+}
+
+// scrubbedValues tells which of n assigned variables receive a scrubbed
+// value, values either match the variables one to one or are a single call
+// whose first result is the scrubbed copy
+func (c *checker) scrubbedValues(values []ast.Expr, n int) []bool {
+	scrubbed := make([]bool, n)
+	switch {
+	case len(values) == n:
+		for i, value := range values {
+			scrubbed[i] = c.isScrubbedExpr(value)
+		}
+	case len(values) == 1 && n > 0:
+		scrubbed[0] = c.isScrubbedExpr(values[0])
+	}
+	return scrubbed
+}
+
+func (c *checker) setScrubbed(expr ast.Expr, scrubbed bool) {
+	ident, ok := astutil.Unparen(expr).(*ast.Ident)
+	if !ok {
+		return
+	}
+	obj := c.pass.TypesInfo.ObjectOf(ident)
+	if obj == nil {
+		return
+	}
+	if scrubbed {
+		c.scrubbed[obj] = true
+	} else {
+		delete(c.scrubbed, obj)
+	}
+}
+
+// isScrubbedExpr tells whether expr is the result of a scrubbing call, or
+// derived from a variable holding one
+func (c *checker) isScrubbedExpr(expr ast.Expr) bool {
+	switch e := astutil.Unparen(expr).(type) {
+	case *ast.CallExpr:
+		fn, ok := c.calledFunc(e)
+		return ok && c.isScrubbingFunc(fn)
+	case *ast.TypeAssertExpr:
+		return c.isScrubbedExpr(e.X)
+	case *ast.UnaryExpr:
+		return c.isScrubbedExpr(e.X)
+	case *ast.StarExpr:
+		return c.isScrubbedExpr(e.X)
+	case *ast.SelectorExpr:
+		return c.isScrubbedExpr(e.X)
+	case *ast.IndexExpr:
+		return c.isScrubbedExpr(e.X)
+	case *ast.Ident:
+		return c.scrubbed[c.pass.TypesInfo.ObjectOf(e)]
+	}
+	return false
+}
+
+// isScrubbingFunc tells whether fn returns a scrubbed copy of its argument:
+// the functions and Scrubber methods of the pii-scrubber package, and the
+// methods of other types implementing piiscrubber.Scrubber
+func (c *checker) isScrubbingFunc(fn *types.Func) bool {
+	if fn.Pkg() != nil && fn.Pkg().Path() == _scrubberPkgPath {
+		name, ok := qualifiedName(fn)
+		return ok && _scrubbingFuncs[name]
+	}
+
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil || !_scrubbingMethods[fn.Name()] || c.scrubber == nil {
+		return false
+	}
+	return types.Implements(recv.Type(), c.scrubber)
+}
+
+// scrubPIIReceiver returns the receiver of a call to a generated ScrubPII
+// method, which is identified by its signature:
+// func(piiscrubber.Scrubber) error
+func (c *checker) scrubPIIReceiver(call *ast.CallExpr) (ast.Expr, bool) {
+	selector, ok := astutil.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok || selector.Sel.Name != _scrubPIIMethod {
+		return nil, false
+	}
+	selection, ok := c.pass.TypesInfo.Selections[selector]
+	if !ok || selection.Kind() != types.MethodVal {
+		return nil, false
+	}
+
+	sig := selection.Obj().Type().(*types.Signature)
+	if sig.Params().Len() != 1 || sig.Results().Len() != 1 {
+		return nil, false
+	}
+	if !isScrubberType(sig.Params().At(0).Type()) {
+		return nil, false
+	}
+	if !types.Identical(sig.Results().At(0).Type(), types.Universe.Lookup("error").Type()) {
+		return nil, false
+	}
+	return selector.X, true
+}
+
+func isScrubberType(t types.Type) bool {
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return false
+	}
+	return named.Obj().Pkg().Path() == _scrubberPkgPath && named.Obj().Name() == "Scrubber"
+}
+
+func (c *checker) checkCall(call *ast.CallExpr) {
+	sink, ok := c.sinkName(call)
+	if !ok {
+		return
+	}
+
+	for _, arg := range call.Args {
+		c.checkArg(arg, sink)
+	}
+}
+
+// calledFunc returns the function or method called by call
+func (c *checker) calledFunc(call *ast.CallExpr) (*types.Func, bool) {
+	var ident *ast.Ident
+	switch fun := astutil.Unparen(call.Fun).(type) {
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	case *ast.Ident:
+		ident = fun
+	default:
+		return nil, false
+	}
+
+	fn, ok := c.pass.TypesInfo.ObjectOf(ident).(*types.Func)
+	if !ok || fn.Pkg() == nil {
+		return nil, false
+	}
+	return fn, true
+}
+
+// qualifiedName returns the name of fn within its package, prefixed with the
+// name of its receiver type for methods, e.g. Logger.Info
+func qualifiedName(fn *types.Func) (string, bool) {
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return fn.Name(), true
+	}
+
+	named, ok := types.Unalias(deref(recv.Type())).(*types.Named)
+	if !ok {
+		return "", false
+	}
+	return named.Obj().Name() + "." + fn.Name(), true
+}
+
+// sinkName returns the qualified name of the called function when it is a
+// sink, e.g. log.Printf or slog.Logger.Info
+func (c *checker) sinkName(call *ast.CallExpr) (string, bool) {
+	fn, ok := c.calledFunc(call)
+	if !ok {
+		return "", false
+	}
+
+	name, ok := qualifiedName(fn)
+	if !ok || !_sinks[fn.Pkg().Path()][name] {
+		return "", false
+	}
+	return fn.Pkg().Name() + "." + name, true
+}
+
+func (c *checker) checkArg(arg ast.Expr, sink string) {
+	arg = astutil.Unparen(arg)
+	if c.isScrubbedExpr(arg) {
+		return
+	}
+
+	if field, ok := c.piiField(arg); ok {
+		c.pass.Reportf(arg.Pos(), "pii tagged field %v passed to %v without ScrubStruct", field, sink)
+		return
+	}
+
+	t := c.pass.TypesInfo.TypeOf(arg)
+	if t != nil && c.holdsPII(t) {
+		c.pass.Reportf(arg.Pos(), "%v has pii tagged fields and is passed to %v without ScrubStruct", types.TypeString(t, types.RelativeTo(c.pass.Pkg)), sink)
+	}
+}
+
+// piiField returns the name of the struct field selected by expr, when that
+// field is tagged with pii:"true" or is reached through a tagged field, e.g.
+// u.Address.Location when Address is tagged
+func (c *checker) piiField(expr ast.Expr) (string, bool) {
+	if unary, ok := expr.(*ast.UnaryExpr); ok {
+		expr = astutil.Unparen(unary.X)
+	}
+
+	selector, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+	if name, ok := c.taggedField(selector); ok {
+		return name, true
+	}
+
+	// ScrubStruct scrubs the strings below a tagged field as well
+	t := c.pass.TypesInfo.TypeOf(selector)
+	if t == nil || !holdsString(t, make(map[types.Type]bool)) {
+		return "", false
+	}
+	path := selector.Sel.Name
+	for x := astutil.Unparen(selector.X); ; {
+		switch e := x.(type) {
+		case *ast.SelectorExpr:
+			if name, ok := c.taggedField(e); ok {
+				return name + "." + path, true
+			}
+			path = e.Sel.Name + "." + path
+			x = astutil.Unparen(e.X)
+		case *ast.IndexExpr:
+			x = astutil.Unparen(e.X)
+		case *ast.StarExpr:
+			x = astutil.Unparen(e.X)
+		default:
+			return "", false
+		}
+	}
+}
+
+// taggedField returns the name of the struct field selected by selector,
+// when that field is tagged with pii:"true"
+func (c *checker) taggedField(selector *ast.SelectorExpr) (string, bool) {
+	selection, ok := c.pass.TypesInfo.Selections[selector]
+	if !ok || selection.Kind() != types.FieldVal {
+		return "", false
+	}
+
+	structType, ok := deref(selection.Recv()).Underlying().(*types.Struct)
+	if !ok {
+		return "", false
+	}
+
+	// follow embedded fields down to the struct declaring the field
+	index := selection.Index()
+	for i, fieldIndex := range index {
+		if i == len(index)-1 {
+			if !hasPIITag(structType.Tag(fieldIndex)) {
+				return "", false
+			}
+			return types.TypeString(deref(selection.Recv()), types.RelativeTo(c.pass.Pkg)) + "." + selector.Sel.Name, true
+		}
+		structType, ok = deref(structType.Field(fieldIndex).Type()).Underlying().(*types.Struct)
+		if !ok {
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// holdsString tells whether values of t can contain strings, which are
+// scrubbed when they are below a tagged field
+func holdsString(t types.Type, seen map[types.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		return u.Info()&types.IsString != 0
+	case *types.Pointer:
+		return holdsString(u.Elem(), seen)
+	case *types.Slice:
+		return holdsString(u.Elem(), seen)
+	case *types.Array:
+		return holdsString(u.Elem(), seen)
+	case *types.Map:
+		return holdsString(u.Key(), seen) || holdsString(u.Elem(), seen)
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			if holdsString(u.Field(i).Type(), seen) {
+				return true
+			}
+		}
+	case *types.Interface:
+		return true
+	}
+	return false
+}
+
+// holdsPII tells whether values of t contain pii tagged fields, directly or
+// through pointers, slices, arrays, maps and nested structs
+func (c *checker) holdsPII(t types.Type) bool {
+	if result, ok := c.piiTypes[t]; ok {
+		return result
+	}
+	// assume false while visiting t to break cycles
+	c.piiTypes[t] = false
+
+	result := false
+	switch u := t.Underlying().(type) {
+	case *types.Pointer:
+		result = c.holdsPII(u.Elem())
+	case *types.Slice:
+		result = c.holdsPII(u.Elem())
+	case *types.Array:
+		result = c.holdsPII(u.Elem())
+	case *types.Map:
+		result = c.holdsPII(u.Key()) || c.holdsPII(u.Elem())
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			if hasPIITag(u.Tag(i)) || c.holdsPII(u.Field(i).Type()) {
+				result = true
+				break
+			}
+		}
+	}
+
+	c.piiTypes[t] = result
+	return result
+}
+
+func hasPIITag(tag string) bool {
+	return reflect.StructTag(tag).Get(_piiTag) == _piiTagValueTrue
+}
+
+func deref(t types.Type) types.Type {
+	if ptr, ok := t.Underlying().(*types.Pointer); ok {
+		return ptr.Elem()
+	}
+	return t
+}
@@ -0,0 +1,12 @@
+package piivet_test
+
+import (
+	"testing"
+
+	"github.com/aavaz-ai/pii-scrubber/piivet"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), piivet.Analyzer, "a")
+}
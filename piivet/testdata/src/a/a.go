@@ -0,0 +1,147 @@
+package a
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+)
+
+type Address struct {
+	Location string
+}
+
+type User struct {
+	Name    string   `pii:"true"`
+	Address *Address `pii:"true"`
+	Age     int
+	Role    string
+}
+
+type Team struct {
+	Lead    User
+	Members []*User
+}
+
+type Plain struct {
+	Name string
+}
+
+func (u *User) ScrubPII(s piiscrubber.Scrubber) error { return nil }
+
+func sinks(u User, team Team, p Plain, s piiscrubber.Scrubber) {
+	log.Printf("user: %+v", u)                        // want `User has pii tagged fields and is passed to log.Printf without ScrubStruct`
+	log.Println(&u)                                   // want `\*User has pii tagged fields and is passed to log.Println without ScrubStruct`
+	fmt.Println(team)                                 // want `Team has pii tagged fields and is passed to fmt.Println without ScrubStruct`
+	fmt.Fprintf(os.Stderr, "%v", u.Name)              // want `pii tagged field User.Name passed to fmt.Fprintf without ScrubStruct`
+	_ = fmt.Sprint(team.Lead.Address)                 // want `pii tagged field User.Address passed to fmt.Sprint without ScrubStruct`
+	_, _ = json.Marshal(team.Members)                 // want `\[\]\*User has pii tagged fields and is passed to json.Marshal without ScrubStruct`
+	_ = json.NewEncoder(os.Stdout).Encode(u)          // want `User has pii tagged fields and is passed to json.Encoder.Encode without ScrubStruct`
+	slog.Info("login", "user", u)                     // want `User has pii tagged fields and is passed to slog.Info without ScrubStruct`
+	slog.Default().Warn("login", slog.Any("user", u)) // want `User has pii tagged fields and is passed to slog.Any without ScrubStruct`
+
+	// fields without the tag and types without pii are fine
+	log.Printf("%v %v %v", u.Age, u.Role, p)
+	log.Println(team.Lead.Role)
+}
+
+func scrubbed(u User, s piiscrubber.Scrubber) {
+	out, err := s.ScrubStruct(u)
+	if err != nil {
+		return
+	}
+	log.Printf("user: %+v", out)
+
+	clean := out.(User)
+	log.Printf("user: %+v %v", clean, clean.Name)
+
+	var again, _ = s.ScrubStruct(&u)
+	fmt.Println(again.(*User).Name)
+
+	generated := u
+	if err := generated.ScrubPII(s); err != nil {
+		return
+	}
+	slog.Info("login", "user", generated, "name", generated.Name)
+}
+
+func order(u, other User, s piiscrubber.Scrubber) {
+	log.Printf("user: %+v", u) // want `User has pii tagged fields and is passed to log.Printf without ScrubStruct`
+	if err := u.ScrubPII(s); err != nil {
+		return
+	}
+	log.Printf("user: %+v", u)
+
+	out, _ := s.ScrubStruct(u)
+	log.Println(out)
+	out = other
+	log.Println(out.(User).Name) // want `pii tagged field User.Name passed to log.Println without ScrubStruct`
+}
+
+func findings(u User, s piiscrubber.FindingsScrubber) {
+	out, _, _ := s.ScrubStructWithReport(u)
+	log.Println(out)
+
+	reported, _, _ := piiscrubber.ScrubStructWithReport(s, u)
+	value, _ := piiscrubber.ScrubValue(s, u.Address, true)
+	log.Println(reported, value)
+}
+
+// notScrubber has a ScrubStruct method but doesn't implement Scrubber
+type notScrubber struct{}
+
+func (notScrubber) ScrubStruct(obj interface{}) (interface{}, error) { return obj, nil }
+
+// localScrubber implements Scrubber
+type localScrubber struct{}
+
+func (localScrubber) ScrubTexts(texts []string) ([]string, error)      { return texts, nil }
+func (localScrubber) ScrubStruct(obj interface{}) (interface{}, error) { return obj, nil }
+
+type Profile struct {
+	Bio string `pii:"true"`
+}
+
+// ScrubPII doesn't take a Scrubber, so it is not a generated method
+func (p *Profile) ScrubPII() error { return nil }
+
+func resolved(u User, p Profile) {
+	out, _ := notScrubber{}.ScrubStruct(u)
+	log.Println(out.(User)) // want `User has pii tagged fields and is passed to log.Println without ScrubStruct`
+
+	local, _ := localScrubber{}.ScrubStruct(u)
+	log.Println(local.(User))
+
+	_ = p.ScrubPII()
+	log.Println(p) // want `Profile has pii tagged fields and is passed to log.Println without ScrubStruct`
+}
+
+type Building struct {
+	Floor  int
+	Street string
+}
+
+type Location struct {
+	Building Building
+}
+
+type Customer struct {
+	Home     Location   `pii:"true"`
+	Previous []Location `pii:"true"`
+	Office   Location
+}
+
+func nested(u User, team Team, c Customer) {
+	fmt.Println(u.Address.Location)            // want `pii tagged field User.Address.Location passed to fmt.Println without ScrubStruct`
+	fmt.Println(team.Lead.Address.Location)    // want `pii tagged field User.Address.Location passed to fmt.Println without ScrubStruct`
+	fmt.Println(c.Home.Building.Street)        // want `pii tagged field Customer.Home.Building.Street passed to fmt.Println without ScrubStruct`
+	fmt.Println(c.Home.Building)               // want `pii tagged field Customer.Home.Building passed to fmt.Println without ScrubStruct`
+	fmt.Println(c.Previous[0].Building.Street) // want `pii tagged field Customer.Previous.Building.Street passed to fmt.Println without ScrubStruct`
+
+	// values without strings aren't changed by ScrubStruct, and fields
+	// outside of the tagged ones aren't scrubbed
+	fmt.Println(c.Home.Building.Floor, c.Office.Building.Street)
+}
@@ -0,0 +1,489 @@
+package piiscrubber
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PolicyError is an invalid entry of a policy file, Line and Column are
+// 1-based and point at the entry
+type PolicyError struct {
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %v", e.Line, e.Column, e.Err)
+}
+
+func (e *PolicyError) Unwrap() error {
+	return e.Err
+}
+
+func policyErrorf(node *yaml.Node, format string, args ...interface{}) error {
+	return &PolicyError{Line: node.Line, Column: node.Column, Err: fmt.Errorf(format, args...)}
+}
+
+// LoadPolicy reads Params from a YAML or JSON policy file, e.g.
+//
+//	entities: [EMAIL, PHONE, EMPLOYEE_ID]
+//	ignored_entities: [STRICT_LINK]
+//	allow_list: [support@example.com]
+//	masking:
+//	  PHONE: {mask_with_char: "*", unmasked_suffix: 4}
+//	custom_entities:
+//	  EMPLOYEE_ID:
+//	    patterns: ['EMP-\d{6}']
+//	    placeholder: <EMPLOYEE_ID>
+//	    word_boundaries: true
+//	extra_patterns:
+//	  SSN: {exclusions: ['\bTKT-\d{3}-\d{2}-\d{4}\b']}
+//	field_rules:
+//	  - {type: stripe.Customer, path: Email, action: redact}
+//	key_rules:
+//	  - {pattern: '(?i)^password$', action: redact}
+//	scrub_map_keys: true
+//
+// entities and ignored_entities default to those of NewDefaultScrubber.
+// Custom entities are detected with their patterns and replaced with their
+// placeholder, <NAME> by default, unless masking is set for them, and take
+// the group, word_boundaries and ignore_case of RegexEntityOptions.
+// extra_patterns take the patterns and exclusions of EntityPatterns. Errors
+// about an entry of the file are *PolicyError
+func LoadPolicy(r io.Reader) (Params, error) {
+	return LoadPolicyWithOptions(r, nil)
+}
+
+// PolicyOptions ...
+type PolicyOptions struct {
+	// Extensions are top-level fields of the policy file that are not part of
+	// Params, so that applications keep their own settings in the same file.
+	// Each field is decoded into the value it maps to, which must be a
+	// pointer, with the rules of encoding/json, unknown fields being errors
+	Extensions map[string]interface{}
+}
+
+// LoadPolicyWithOptions reads Params from a YAML or JSON policy file like
+// LoadPolicy, and decodes the extensions of opts, which may be nil
+func LoadPolicyWithOptions(r io.Reader, opts *PolicyOptions) (Params, error) {
+	if opts == nil {
+		opts = &PolicyOptions{}
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Params{}, err
+	}
+	// YAML doesn't allow tabs where JSON files are often indented with them,
+	// and JSON doesn't allow them anywhere else
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		data = bytes.ReplaceAll(data, []byte{'\t'}, []byte{' '})
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return Params{}, fmt.Errorf("invalid policy: %v", err)
+	}
+	params := DefaultParams()
+	if len(doc.Content) == 0 {
+		return params, nil
+	}
+
+	p := &policyLoader{params: params, extensions: opts.Extensions}
+	if err := p.load(doc.Content[0]); err != nil {
+		return Params{}, err
+	}
+	return p.params, nil
+}
+
+// policyLoader decodes the nodes of a policy file into params
+type policyLoader struct {
+	params Params
+	// entities are the nodes of the entities named in the file, checked once
+	// the custom entities are known
+	entities   []*yaml.Node
+	extensions map[string]interface{}
+}
+
+func (p *policyLoader) load(root *yaml.Node) error {
+	decoders := map[string]func(*yaml.Node) error{
+		"entities": func(node *yaml.Node) (err error) {
+			p.params.BlacklistedEntities, err = p.decodeEntities(node)
+			return err
+		},
+		"ignored_entities": func(node *yaml.Node) (err error) {
+			p.params.IgnoredEntities, err = p.decodeEntities(node)
+			return err
+		},
+		"allow_list": func(node *yaml.Node) (err error) {
+			p.params.AllowList, err = decodeStrings(node)
+			return err
+		},
+		"masking":         p.decodeMasking,
+		"custom_entities": p.decodeCustomEntities,
+		"extra_patterns":  p.decodeExtraPatterns,
+		"field_rules":     p.decodeFieldRules,
+		"key_rules":       p.decodeKeyRules,
+		"scrub_map_keys": func(node *yaml.Node) (err error) {
+			p.params.ScrubMapKeys, err = decodeBool(node)
+			return err
+		},
+	}
+	for name, value := range p.extensions {
+		if _, ok := decoders[name]; ok {
+			return fmt.Errorf("policy extension %v is a field of the policy", name)
+		}
+		name, value := name, value
+		decoders[name] = func(node *yaml.Node) error {
+			return decodeExtension(node, name, value)
+		}
+	}
+	if err := decodeMapping(root, decoders); err != nil {
+		return err
+	}
+
+	for _, node := range p.entities {
+		entity := Entity(node.Value)
+		if _, ok := _defaultEntityScrubbers[entity]; ok {
+			continue
+		}
+		if _, ok := p.params.CustomEntityScrubbers[entity]; ok {
+			continue
+		}
+		return policyErrorf(node, "unknown entity: %v, it is neither built in nor in custom_entities", entity)
+	}
+	return nil
+}
+
+func (p *policyLoader) decodeEntities(node *yaml.Node) ([]Entity, error) {
+	nodes, err := decodeSequence(node)
+	if err != nil {
+		return nil, err
+	}
+	entities := make([]Entity, 0, len(nodes))
+	for _, item := range nodes {
+		if _, err := decodeScalar(item, "!!str"); err != nil {
+			return nil, err
+		}
+		p.entities = append(p.entities, item)
+		entities = append(entities, Entity(item.Value))
+	}
+	return entities, nil
+}
+
+func (p *policyLoader) decodeMasking(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return policyErrorf(node, "expected a mapping of entities to their masking")
+	}
+	p.params.Config = make(map[Entity]*EntityConfig)
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		p.entities = append(p.entities, key)
+
+		config := &EntityConfig{}
+		err := decodeMapping(value, map[string]func(*yaml.Node) error{
+			"replace_with": func(node *yaml.Node) error {
+				replaceWith, err := decodeScalar(node, "!!str")
+				config.ReplaceWith = &replaceWith
+				return err
+			},
+			"mask_with_char": func(node *yaml.Node) error {
+				char, err := decodeScalar(node, "!!str")
+				if err != nil {
+					return err
+				}
+				runes := []rune(char)
+				if len(runes) != 1 {
+					return policyErrorf(node, "mask_with_char must be a single character")
+				}
+				config.MaskWithChar = &runes[0]
+				return nil
+			},
+			"unmasked_prefix": func(node *yaml.Node) (err error) {
+				config.UnmaskedPrefixOffset, err = decodeOffset(node)
+				return err
+			},
+			"unmasked_suffix": func(node *yaml.Node) (err error) {
+				config.UnmaskedSuffixOffset, err = decodeOffset(node)
+				return err
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if err := config.isValid(); err != nil {
+			return policyErrorf(value, "in config for entity: %v, error: %v", key.Value, err.Error())
+		}
+		p.params.Config[Entity(key.Value)] = config
+	}
+	return nil
+}
+
+func (p *policyLoader) decodeCustomEntities(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return policyErrorf(node, "expected a mapping of entities to their patterns")
+	}
+	p.params.CustomEntityScrubbers = make(map[Entity]EntityScrubber)
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		opts := &RegexEntityOptions{Placeholder: fmt.Sprintf("<%v>", key.Value)}
+		var patterns []string
+		err := decodeMapping(value, map[string]func(*yaml.Node) error{
+			"patterns": func(node *yaml.Node) (err error) {
+				patterns, err = decodePatterns(node, key.Value)
+				return err
+			},
+			"placeholder": func(node *yaml.Node) (err error) {
+				opts.Placeholder, err = decodeScalar(node, "!!str")
+				return err
+			},
+			"group": func(node *yaml.Node) (err error) {
+				opts.Group, err = decodeOffset(node)
+				return err
+			},
+			"word_boundaries": func(node *yaml.Node) (err error) {
+				opts.WordBoundaries, err = decodeBool(node)
+				return err
+			},
+			"ignore_case": func(node *yaml.Node) (err error) {
+				opts.IgnoreCase, err = decodeBool(node)
+				return err
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if len(patterns) == 0 {
+			return policyErrorf(value, "custom entity %v has no patterns", key.Value)
+		}
+		scrubber, err := NewRegexEntityScrubberWithOptions(opts, patterns...)
+		if err != nil {
+			return policyErrorf(value, "in custom entity: %v, error: %v", key.Value, err)
+		}
+		p.params.CustomEntityScrubbers[Entity(key.Value)] = scrubber
+	}
+	return nil
+}
+
+func (p *policyLoader) decodeExtraPatterns(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return policyErrorf(node, "expected a mapping of entities to their extra patterns")
+	}
+	p.params.ExtraPatterns = make(map[Entity]EntityPatterns)
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		p.entities = append(p.entities, key)
+
+		var patterns EntityPatterns
+		err := decodeMapping(value, map[string]func(*yaml.Node) error{
+			"patterns": func(node *yaml.Node) (err error) {
+				patterns.Patterns, err = decodePatterns(node, key.Value)
+				return err
+			},
+			"exclusions": func(node *yaml.Node) (err error) {
+				patterns.Exclusions, err = decodePatterns(node, key.Value)
+				return err
+			},
+		})
+		if err != nil {
+			return err
+		}
+		p.params.ExtraPatterns[Entity(key.Value)] = patterns
+	}
+	return nil
+}
+
+func (p *policyLoader) decodeFieldRules(node *yaml.Node) error {
+	nodes, err := decodeSequence(node)
+	if err != nil {
+		return err
+	}
+	for _, item := range nodes {
+		var rule FieldRule
+		err := decodeMapping(item, map[string]func(*yaml.Node) error{
+			"type": func(node *yaml.Node) (err error) {
+				rule.TypeName, err = decodeScalar(node, "!!str")
+				return err
+			},
+			"path": func(node *yaml.Node) (err error) {
+				rule.Path, err = decodeScalar(node, "!!str")
+				return err
+			},
+			"action": func(node *yaml.Node) error {
+				action, err := decodeScalar(node, "!!str")
+				rule.Action = Action(action)
+				return err
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if err := validateFieldRules([]FieldRule{rule}); err != nil {
+			return policyErrorf(item, "%v", err)
+		}
+		p.params.FieldRules = append(p.params.FieldRules, rule)
+	}
+	return nil
+}
+
+func (p *policyLoader) decodeKeyRules(node *yaml.Node) error {
+	nodes, err := decodeSequence(node)
+	if err != nil {
+		return err
+	}
+	for _, item := range nodes {
+		var rule KeyRule
+		err := decodeMapping(item, map[string]func(*yaml.Node) error{
+			"pattern": func(node *yaml.Node) (err error) {
+				rule.Pattern, err = decodeScalar(node, "!!str")
+				return err
+			},
+			"action": func(node *yaml.Node) error {
+				action, err := decodeScalar(node, "!!str")
+				rule.Action = Action(action)
+				return err
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := compileKeyRules([]KeyRule{rule}); err != nil {
+			return policyErrorf(item, "%v", err)
+		}
+		p.params.KeyRules = append(p.params.KeyRules, rule)
+	}
+	return nil
+}
+
+// decodeExtension decodes node into value with the rules of encoding/json
+func decodeExtension(node *yaml.Node, name string, value interface{}) error {
+	var generic interface{}
+	if err := node.Decode(&generic); err != nil {
+		return policyErrorf(node, "in %v: %v", name, err)
+	}
+	data, err := json.Marshal(generic)
+	if err != nil {
+		return policyErrorf(node, "in %v: %v", name, err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(value); err != nil {
+		return policyErrorf(node, "in %v: %v", name, err)
+	}
+	return nil
+}
+
+// decodeMapping calls the decoder of every key of the mapping node, keys
+// without a decoder and keys given twice are errors
+func decodeMapping(node *yaml.Node, decoders map[string]func(*yaml.Node) error) error {
+	if node.Kind != yaml.MappingNode {
+		return policyErrorf(node, "expected a mapping")
+	}
+	seen := make(map[string]bool, len(node.Content)/2)
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		decode, ok := decoders[key.Value]
+		if !ok {
+			return policyErrorf(key, "unknown field: %v", key.Value)
+		}
+		if seen[key.Value] {
+			return policyErrorf(key, "field %v is given twice", key.Value)
+		}
+		seen[key.Value] = true
+		if err := decode(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeSequence(node *yaml.Node) ([]*yaml.Node, error) {
+	if node.Kind != yaml.SequenceNode {
+		return nil, policyErrorf(node, "expected a list")
+	}
+	return node.Content, nil
+}
+
+// decodeScalar returns the value of a scalar node of the YAML type tag, e.g.
+// !!int or !!bool. Any scalar but null is a !!str, so that e.g. numbers can
+// be given as placeholders without quotes
+func decodeScalar(node *yaml.Node, tag string) (string, error) {
+	ok := node.Kind == yaml.ScalarNode && node.ShortTag() != "!!null"
+	if tag != "!!str" {
+		ok = ok && node.ShortTag() == tag
+	}
+	if !ok {
+		return "", policyErrorf(node, "expected a %v", scalarTagNames[tag])
+	}
+	return node.Value, nil
+}
+
+var scalarTagNames = map[string]string{
+	"!!str":  "string",
+	"!!bool": "boolean",
+	"!!int":  "number",
+}
+
+func decodeStrings(node *yaml.Node) ([]string, error) {
+	nodes, err := decodeSequence(node)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, 0, len(nodes))
+	for _, item := range nodes {
+		value, err := decodeScalar(item, "!!str")
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// decodePatterns decodes a list of regular expressions of the entity
+func decodePatterns(node *yaml.Node, entity string) ([]string, error) {
+	nodes, err := decodeSequence(node)
+	if err != nil {
+		return nil, err
+	}
+	patterns := make([]string, 0, len(nodes))
+	for _, item := range nodes {
+		if _, err := decodeScalar(item, "!!str"); err != nil {
+			return nil, err
+		}
+		if _, err := regexp.Compile(item.Value); err != nil {
+			return nil, policyErrorf(item, "in pattern of entity: %v, error: %v", entity, err.Error())
+		}
+		patterns = append(patterns, item.Value)
+	}
+	return patterns, nil
+}
+
+// decodeBool accepts the booleans of YAML, e.g. true, True and TRUE
+func decodeBool(node *yaml.Node) (bool, error) {
+	if _, err := decodeScalar(node, "!!bool"); err != nil {
+		return false, err
+	}
+	var value bool
+	if err := node.Decode(&value); err != nil {
+		return false, policyErrorf(node, "expected a %v", scalarTagNames["!!bool"])
+	}
+	return value, nil
+}
+
+func decodeOffset(node *yaml.Node) (int, error) {
+	if _, err := decodeScalar(node, "!!int"); err != nil {
+		return 0, err
+	}
+	var offset int
+	if err := node.Decode(&offset); err != nil || offset < 0 {
+		return 0, policyErrorf(node, "expected a number that is not negative")
+	}
+	return offset, nil
+}
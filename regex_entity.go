@@ -0,0 +1,214 @@
+package piiscrubber
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// RegexEntityOptions ...
+type RegexEntityOptions struct {
+	// Group is the capture group of the patterns that is scrubbed, e.g. 1
+	// scrubs the digits of `id=(\d+)` and keeps the "id=". 0, the default,
+	// scrubs the whole match
+	Group int
+	// WordBoundaries only matches patterns that start and end at a word
+	// boundary, so that e.g. "acme" doesn't match inside "acmeplex"
+	WordBoundaries bool
+	// IgnoreCase matches the patterns case-insensitively
+	IgnoreCase bool
+	// Validators are called with the text of every match, which is only
+	// scrubbed when all of them return true, e.g. to verify a check digit
+	Validators []func(match string) bool
+	// Placeholder replaces the matches of entities without a config,
+	// <REDACTED> by default
+	Placeholder string
+}
+
+// RegexEntityScrubber is an EntityScrubber detecting an entity with regular
+// expressions, which are compiled once when it is created. Matches are
+// replaced with the placeholder, or masked with NativeMasking when the
+// entity has a config
+type RegexEntityScrubber struct {
+	regexes     []*regexp.Regexp
+	group       int
+	validators  []func(match string) bool
+	placeholder string
+}
+
+// NewRegexEntityScrubber returns a RegexEntityScrubber matching any of the
+// patterns
+func NewRegexEntityScrubber(patterns ...string) (*RegexEntityScrubber, error) {
+	return NewRegexEntityScrubberWithOptions(nil, patterns...)
+}
+
+// NewRegexEntityScrubberWithOptions returns a RegexEntityScrubber matching
+// any of the patterns, opts may be nil
+func NewRegexEntityScrubberWithOptions(opts *RegexEntityOptions, patterns ...string) (*RegexEntityScrubber, error) {
+	if opts == nil {
+		opts = &RegexEntityOptions{}
+	}
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("at least one pattern must be specified")
+	}
+	if opts.Group < 0 {
+		return nil, fmt.Errorf("invalid capture group: %v", opts.Group)
+	}
+
+	s := &RegexEntityScrubber{
+		regexes:     make([]*regexp.Regexp, 0, len(patterns)),
+		group:       opts.Group,
+		validators:  opts.Validators,
+		placeholder: opts.Placeholder,
+	}
+	if s.placeholder == "" {
+		s.placeholder = _redactedValue
+	}
+	for _, pattern := range patterns {
+		expr := pattern
+		if opts.WordBoundaries {
+			// a non-capturing group keeps the numbers of the capture groups
+			expr = `\b(?:` + expr + `)\b`
+		}
+		if opts.IgnoreCase {
+			expr = `(?i)` + expr
+		}
+		regex, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("in pattern: %v, error: %v", pattern, err)
+		}
+		if regex.NumSubexp() < opts.Group {
+			return nil, fmt.Errorf("in pattern: %v, error: it has no capture group %v", pattern, opts.Group)
+		}
+		s.regexes = append(s.regexes, regex)
+	}
+	return s, nil
+}
+
+// Match ...
+func (s *RegexEntityScrubber) Match(text string) [][]int {
+	var matches [][]int
+	for _, regex := range s.regexes {
+		for _, submatch := range regex.FindAllStringSubmatchIndex(text, -1) {
+			start, end := submatch[2*s.group], submatch[2*s.group+1]
+			// the group didn't take part in the match, or matched nothing
+			if start < 0 || start == end {
+				continue
+			}
+			if !s.isValid(text[start:end]) {
+				continue
+			}
+			matches = append(matches, []int{start, end})
+		}
+	}
+	return matches
+}
+
+func (s *RegexEntityScrubber) isValid(match string) bool {
+	for _, validate := range s.validators {
+		if !validate(match) {
+			return false
+		}
+	}
+	return true
+}
+
+// Mask ...
+func (s *RegexEntityScrubber) Mask(detectedEntity []byte, config *EntityConfig) []byte {
+	if config == nil {
+		return []byte(s.placeholder)
+	}
+	return NativeMasking(detectedEntity, config)
+}
+
+// EntityPatterns extend the detection of an entity that already has a
+// scrubber, e.g. with a local phone number format
+type EntityPatterns struct {
+	// Patterns detect the entity in addition to its scrubber, their matches
+	// are masked by the scrubber
+	Patterns []string
+	// Validators are called with the text of every match of the entity,
+	// from its scrubber or Patterns, which is only scrubbed when all of them
+	// return true
+	Validators []func(match string) bool
+	// Exclusions veto the matches of the entity that lie within one of
+	// their matches, e.g. ticket IDs that look like SSNs
+	Exclusions []string
+}
+
+// extendedEntityScrubber merges the matches of an entity scrubber with those
+// of extra patterns, so that they are resolved together with the matches of
+// other entities
+type extendedEntityScrubber struct {
+	EntityScrubber
+	extra      *RegexEntityScrubber
+	validators []func(match string) bool
+	exclusions []*regexp.Regexp
+}
+
+func newExtendedEntityScrubber(base EntityScrubber, patterns EntityPatterns) (*extendedEntityScrubber, error) {
+	s := &extendedEntityScrubber{EntityScrubber: base, validators: patterns.Validators}
+	if len(patterns.Patterns) > 0 {
+		extra, err := NewRegexEntityScrubber(patterns.Patterns...)
+		if err != nil {
+			return nil, err
+		}
+		s.extra = extra
+	}
+	for _, pattern := range patterns.Exclusions {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("in exclusion: %v, error: %v", pattern, err)
+		}
+		s.exclusions = append(s.exclusions, regex)
+	}
+	return s, nil
+}
+
+func (s *extendedEntityScrubber) Match(text string) [][]int {
+	matches := s.EntityScrubber.Match(text)
+	var extra [][]int
+	if s.extra != nil {
+		extra = s.extra.Match(text)
+	}
+
+	var excluded [][]int
+	for _, regex := range s.exclusions {
+		excluded = append(excluded, regex.FindAllStringIndex(text, -1)...)
+	}
+
+	// the matches of the scrubber are not modified, it may reuse them
+	valid := make([][]int, 0, len(matches)+len(extra))
+	for _, match := range append(matches[:len(matches):len(matches)], extra...) {
+		// invalid indices are left for the scrubber to report
+		if len(match) != 2 || match[0] < 0 || match[0] >= match[1] || match[1] > len(text) {
+			valid = append(valid, match)
+			continue
+		}
+		if isExcluded(match, excluded) {
+			continue
+		}
+		if !s.isValid(text[match[0]:match[1]]) {
+			continue
+		}
+		valid = append(valid, match)
+	}
+	return valid
+}
+
+func (s *extendedEntityScrubber) isValid(match string) bool {
+	for _, validate := range s.validators {
+		if !validate(match) {
+			return false
+		}
+	}
+	return true
+}
+
+func isExcluded(match []int, excluded [][]int) bool {
+	for _, exclusion := range excluded {
+		if exclusion[0] <= match[0] && match[1] <= exclusion[1] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package piiscrubber
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	// ErrNoFindings ...
+	ErrNoFindings = fmt.Errorf("the scrubber does not report findings, it does not implement FindingsScrubber")
+)
+
+// ScrubTextsWithFindings calls the ScrubTextsWithFindings method of s, or
+// returns ErrNoFindings when s is not a FindingsScrubber
+func ScrubTextsWithFindings(s Scrubber, texts []string) ([]string, [][]Finding, error) {
+	findingsScrubber, ok := s.(FindingsScrubber)
+	if !ok {
+		return nil, nil, ErrNoFindings
+	}
+	return findingsScrubber.ScrubTextsWithFindings(texts)
+}
+
+// ScrubStructWithReport calls the ScrubStructWithReport method of s, or
+// returns ErrNoFindings when s is not a FindingsScrubber
+func ScrubStructWithReport(s Scrubber, obj interface{}) (interface{}, []FieldReport, error) {
+	findingsScrubber, ok := s.(FindingsScrubber)
+	if !ok {
+		return nil, nil, ErrNoFindings
+	}
+	return findingsScrubber.ScrubStructWithReport(obj)
+}
+
+// Finding describes a single entity detected in a text. Start and End are
+// byte offsets into the original (unscrubbed) text, End being exclusive
+type Finding struct {
+	Entity Entity
+	Start  int
+	End    int
+}
+
+// String formats the finding as ENTITY@start-end, e.g. SSN@3-14
+func (f Finding) String() string {
+	return fmt.Sprintf("%v@%d-%d", f.Entity, f.Start, f.End)
+}
+
+// FieldReport lists the findings for one field of a scrubbed object
+//
+// Path uses Go syntax relative to the scrubbed object, e.g.
+// Address.Location, CustomAttributes["PIIKey"] or Items[2].Note. The PII in
+// map keys is scrubbed in it even when ScrubMapKeys is off, e.g.
+// Contacts["<EMAIL_ADDRESS>"], so that reports can be logged
+type FieldReport struct {
+	Path string
+	// InKey is set when the findings are in the map key of Path rather than
+	// in its value, Path then contains the scrubbed key
+	InKey    bool
+	Findings []Finding
+}
+
+// Entities returns the distinct entities found in the field, in order of
+// first appearance
+func (r FieldReport) Entities() []Entity {
+	entities := make([]Entity, 0, len(r.Findings))
+	seen := make(map[Entity]bool, len(r.Findings))
+	for _, finding := range r.Findings {
+		if seen[finding.Entity] {
+			continue
+		}
+		seen[finding.Entity] = true
+		entities = append(entities, finding.Entity)
+	}
+	return entities
+}
+
+// String formats the report as `Path: ENTITY@start-end, ...`, findings in
+// map keys are formatted as `Path (key): ENTITY@start-end, ...`
+func (r FieldReport) String() string {
+	findings := make([]string, 0, len(r.Findings))
+	for _, finding := range r.Findings {
+		findings = append(findings, finding.String())
+	}
+	path := r.Path
+	if r.InKey {
+		path += " (key)"
+	}
+	return fmt.Sprintf("%v: %v", path, strings.Join(findings, ", "))
+}
+
+func fieldPath(parent, field string) string {
+	if parent == "" {
+		return field
+	}
+	return parent + "." + field
+}
+
+func indexPath(parent string, index int) string {
+	return fmt.Sprintf("%v[%d]", parent, index)
+}
+
+func mapKeyPath(parent string, key reflect.Value) string {
+	if key.Kind() == reflect.String {
+		return fmt.Sprintf("%v[%q]", parent, key.String())
+	}
+	return fmt.Sprintf("%v[%v]", parent, key)
+}
+
+func mapKeySegment(key reflect.Value) string {
+	if key.Kind() == reflect.String {
+		return key.String()
+	}
+	return fmt.Sprint(key)
+}
@@ -0,0 +1,275 @@
+package piiscrubber
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Action tells the struct walker what to do with a value selected by a rule
+type Action string
+
+// Possible Actions ...
+const (
+	// Scan scrubs the entities detected in the value, same as the pii tag
+	Scan Action = "scan"
+	// Redact replaces every string in the value with a placeholder, as well
+	// as numbers and booleans held in interfaces. Other values, e.g. typed
+	// int fields, are zeroed
+	Redact Action = "redact"
+	// Keep leaves the value as it is, even inside a pii tagged field
+	Keep Action = "keep"
+)
+
+const _redactedValue = "<REDACTED>"
+
+func (a Action) isValid() error {
+	switch a {
+	case Scan, Redact, Keep:
+		return nil
+	}
+	return fmt.Errorf("unknown action: %q", a)
+}
+
+// FieldRule selects values in objects passed to ScrubStruct without relying
+// on pii tags, which is useful for types from generated code or third-party
+// libraries
+//
+// Path is a dot separated list of struct field names and map keys relative to
+// a value of Type. A `*` segment matches exactly one field or key, `**`
+// matches any number of them, and other segments may use path.Match globs.
+// Slice and array indices are not part of the path, so a rule applies to
+// every element
+//
+// When neither Type nor TypeName is set, the path is relative to the object
+// passed to ScrubStruct, which is how rules for map[string]interface{} trees
+// are written
+type FieldRule struct {
+	Type reflect.Type
+	// TypeName can be used instead of Type where a reflect.Type is not
+	// available, e.g. in configuration files. It is compared against
+	// reflect.Type.String(), e.g. "stripe.Customer"
+	TypeName string
+	Path     string
+	Action   Action
+}
+
+func (r FieldRule) isValid() error {
+	if err := r.Action.isValid(); err != nil {
+		return err
+	}
+	if r.Path == "" {
+		return fmt.Errorf("path must not be empty")
+	}
+	for _, segment := range strings.Split(r.Path, ".") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid path segment %q: %v", segment, err)
+		}
+	}
+	return nil
+}
+
+var (
+	_registeredFieldRulesLock sync.RWMutex
+	_registeredFieldRules     []FieldRule
+)
+
+// RegisterFieldRule registers a rule honoured by every Scrubber, in addition
+// to the pii tags and the rules passed in Params
+//
+// example: RegisterFieldRule(reflect.TypeOf(stripe.Customer{}), "Address.Line1", Redact)
+func RegisterFieldRule(t reflect.Type, path string, action Action) error {
+	rule := FieldRule{
+		Type:   t,
+		Path:   path,
+		Action: action,
+	}
+	if err := rule.isValid(); err != nil {
+		return err
+	}
+
+	_registeredFieldRulesLock.Lock()
+	defer _registeredFieldRulesLock.Unlock()
+	_registeredFieldRules = append(_registeredFieldRules, rule)
+	return nil
+}
+
+func registeredFieldRules() []FieldRule {
+	_registeredFieldRulesLock.RLock()
+	defer _registeredFieldRulesLock.RUnlock()
+	return _registeredFieldRules
+}
+
+func validateFieldRules(rules []FieldRule) error {
+	for _, rule := range rules {
+		if err := rule.isValid(); err != nil {
+			return fmt.Errorf("in field rule for path: %v, error: %v", rule.Path, err.Error())
+		}
+	}
+	return nil
+}
+
+type compiledFieldRule struct {
+	segments []string
+	action   Action
+}
+
+// fieldRuleSet indexes rules by the type their path is relative to
+type fieldRuleSet struct {
+	root       []compiledFieldRule
+	byType     map[reflect.Type][]compiledFieldRule
+	byTypeName map[string][]compiledFieldRule
+}
+
+func newFieldRuleSet(ruleLists ...[]FieldRule) *fieldRuleSet {
+	set := &fieldRuleSet{
+		byType:     make(map[reflect.Type][]compiledFieldRule),
+		byTypeName: make(map[string][]compiledFieldRule),
+	}
+
+	empty := true
+	for _, rules := range ruleLists {
+		for _, rule := range rules {
+			empty = false
+			compiled := compiledFieldRule{
+				segments: strings.Split(rule.Path, "."),
+				action:   rule.Action,
+			}
+			switch {
+			case rule.Type != nil:
+				set.byType[rule.Type] = append(set.byType[rule.Type], compiled)
+			case rule.TypeName != "":
+				set.byTypeName[rule.TypeName] = append(set.byTypeName[rule.TypeName], compiled)
+			default:
+				set.root = append(set.root, compiled)
+			}
+		}
+	}
+
+	if empty {
+		return nil
+	}
+	return set
+}
+
+func (set *fieldRuleSet) rulesFor(t reflect.Type) []compiledFieldRule {
+	rules := set.byType[t]
+	if byName, ok := set.byTypeName[t.String()]; ok {
+		rules = append(rules[:len(rules):len(rules)], byName...)
+	}
+	return rules
+}
+
+// ruleAnchor tracks the path walked since a value that rules are relative to
+type ruleAnchor struct {
+	rules    []compiledFieldRule
+	segments []string
+}
+
+// step returns the anchors after descending into the named field or key
+func stepRuleAnchors(anchors []ruleAnchor, segment string) []ruleAnchor {
+	if len(anchors) == 0 {
+		return anchors
+	}
+
+	stepped := make([]ruleAnchor, 0, len(anchors))
+	for _, anchor := range anchors {
+		segments := make([]string, len(anchor.segments), len(anchor.segments)+1)
+		copy(segments, anchor.segments)
+		stepped = append(stepped, ruleAnchor{
+			rules:    anchor.rules,
+			segments: append(segments, segment),
+		})
+	}
+	return stepped
+}
+
+// matchRuleAnchors returns the action of the last rule matching the current
+// position, if any
+func matchRuleAnchors(anchors []ruleAnchor) (Action, bool) {
+	var action Action
+	found := false
+	for _, anchor := range anchors {
+		if len(anchor.segments) == 0 {
+			continue
+		}
+		for _, rule := range anchor.rules {
+			if matchPathSegments(rule.segments, anchor.segments) {
+				action = rule.action
+				found = true
+			}
+		}
+	}
+	return action, found
+}
+
+func matchPathSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchPathSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(segments) == 0 {
+		return false
+	}
+
+	if matched, _ := path.Match(pattern[0], segments[0]); !matched {
+		return false
+	}
+	return matchPathSegments(pattern[1:], segments[1:])
+}
+
+// KeyRule applies an action to every map value whose key matches Pattern,
+// regardless of pii tags. It is meant for dynamic maps such as decoded JSON,
+// e.g. {Pattern: `(?i)email|phone|ssn|password`, Action: Redact}
+type KeyRule struct {
+	Pattern string
+	Action  Action
+}
+
+type compiledKeyRule struct {
+	regex  *regexp.Regexp
+	action Action
+}
+
+func compileKeyRules(rules []KeyRule) ([]compiledKeyRule, error) {
+	compiled := make([]compiledKeyRule, 0, len(rules))
+	for _, rule := range rules {
+		if err := rule.Action.isValid(); err != nil {
+			return nil, fmt.Errorf("in key rule for pattern: %v, error: %v", rule.Pattern, err.Error())
+		}
+		regex, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("in key rule for pattern: %v, error: %v", rule.Pattern, err.Error())
+		}
+		compiled = append(compiled, compiledKeyRule{
+			regex:  regex,
+			action: rule.Action,
+		})
+	}
+	return compiled, nil
+}
+
+// matchKeyRules returns the action of the last rule matching key, if any
+func matchKeyRules(rules []compiledKeyRule, key string) (Action, bool) {
+	var action Action
+	found := false
+	for _, rule := range rules {
+		if rule.regex.MatchString(key) {
+			action = rule.action
+			found = true
+		}
+	}
+	return action, found
+}
@@ -3,6 +3,7 @@ package piiscrubber
 import (
 	"fmt"
 	"sort"
+	"strings"
 
 	goworker "github.com/anshal21/go-worker"
 )
@@ -13,17 +14,57 @@ type Scrubber interface {
 	ScrubStruct(obj interface{}) (interface{}, error)
 }
 
+// FindingsScrubber is a Scrubber that also reports what it scrubbed, the
+// Scrubbers returned by the constructors implement it
+type FindingsScrubber interface {
+	Scrubber
+
+	// ScrubTextsWithFindings works like ScrubTexts and additionally returns,
+	// for every input text, the entities that were scrubbed out of it
+	ScrubTextsWithFindings(texts []string) ([]string, [][]Finding, error)
+
+	// ScrubStructWithReport works like ScrubStruct and additionally returns
+	// the field paths of obj that contained PII, sorted by path
+	ScrubStructWithReport(obj interface{}) (interface{}, []FieldReport, error)
+}
+
 // Params ...
 type Params struct {
 	BlacklistedEntities []Entity
 	IgnoredEntities     []Entity
 	Config              map[Entity]*EntityConfig
+	// FieldRules are applied by ScrubStruct in addition to the pii tags
+	FieldRules []FieldRule
+	// KeyRules apply an action to map values by the name of their key
+	KeyRules []KeyRule
+	// ScrubMapKeys makes ScrubStruct scrub the string keys of pii tagged maps
+	ScrubMapKeys bool
+	// CustomEntityScrubbers detect entities that are not built in, or
+	// replace the scrubbers of built-in ones
+	CustomEntityScrubbers map[Entity]EntityScrubber
+	// CustomEntityPatterns detect entities that are not built in with regular
+	// expressions, their matches are replaced with <ENTITY> unless the
+	// entity has a config
+	CustomEntityPatterns map[Entity][]string
+	// ExtraPatterns add patterns, validators and exclusions to entities that
+	// have a scrubber, built in or custom, instead of replacing it
+	ExtraPatterns map[Entity]EntityPatterns
+	// AllowList holds values that are never scrubbed, compared
+	// case-insensitively with the text of each match, e.g. the address of a
+	// support mailbox
+	AllowList []string
 }
 
 // New DefaultScrubber ...
 func NewDefaultScrubber() (Scrubber, error) {
-	return &scrubber{
-		blacklistedEntities: []Entity{
+	return New(DefaultParams())
+}
+
+// DefaultParams returns the Params NewDefaultScrubber uses, for scrubbers
+// that only change some of them
+func DefaultParams() Params {
+	return Params{
+		BlacklistedEntities: []Entity{
 			StreetAddress,
 			CreditCard,
 			Phone,
@@ -36,25 +77,50 @@ func NewDefaultScrubber() (Scrubber, error) {
 			MACAddress,
 			IBAN,
 		},
-		ignoredEntities: []Entity{
+		IgnoredEntities: []Entity{
 			StrictLink,
 			GitRepo,
 		},
-	}, nil
+	}
 }
 
 // NewScrubber ...
 func New(params Params) (Scrubber, error) {
-	for key, val := range params.Config {
-		if err := val.isValid(); err != nil {
-			return nil, fmt.Errorf("in config for entity: %v, error: %v", key, err.Error())
+	if err := validateFieldRules(params.FieldRules); err != nil {
+		return nil, err
+	}
+
+	keyRules, err := compileKeyRules(params.KeyRules)
+	if err != nil {
+		return nil, err
+	}
+
+	customScrubbers, err := resolveEntityScrubbers(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateEntities(params, customScrubbers); err != nil {
+		return nil, err
+	}
+
+	var allowList map[string]bool
+	if len(params.AllowList) > 0 {
+		allowList = make(map[string]bool, len(params.AllowList))
+		for _, value := range params.AllowList {
+			allowList[strings.ToLower(value)] = true
 		}
 	}
 
 	return &scrubber{
-		blacklistedEntities: params.BlacklistedEntities,
-		ignoredEntities:     params.IgnoredEntities,
-		config:              params.Config,
+		blacklistedEntities:   params.BlacklistedEntities,
+		ignoredEntities:       params.IgnoredEntities,
+		config:                params.Config,
+		userProvidedScrubbers: customScrubbers,
+		fieldRules:            params.FieldRules,
+		keyRules:              keyRules,
+		scrubMapKeys:          params.ScrubMapKeys,
+		allowList:             allowList,
 	}, nil
 }
 
@@ -64,32 +130,176 @@ type NewWithCustomEntityScrubbersParams struct {
 	IgnoredEntities       []Entity
 	Config                map[Entity]*EntityConfig
 	CustomEntityScrubbers map[Entity]EntityScrubber
+	// CustomEntityPatterns detect entities with regular expressions, see Params
+	CustomEntityPatterns map[Entity][]string
+	// ExtraPatterns extend the detection of entities, see Params
+	ExtraPatterns map[Entity]EntityPatterns
+	// FieldRules are applied by ScrubStruct in addition to the pii tags
+	FieldRules []FieldRule
+	// KeyRules apply an action to map values by the name of their key
+	KeyRules []KeyRule
+	// ScrubMapKeys makes ScrubStruct scrub the string keys of pii tagged maps
+	ScrubMapKeys bool
+	// AllowList holds values that are never scrubbed, see Params
+	AllowList []string
 }
 
 var (
 	// ErrInvalidMatchIndices ...
 	ErrInvalidMatchIndices = fmt.Errorf("invalid match generated by the entity scrubber")
+	// ErrUnknownEntity ...
+	ErrUnknownEntity = fmt.Errorf("unknown entity, it is neither built in nor has a custom scrubber")
+	// ErrConflictingEntity ...
+	ErrConflictingEntity = fmt.Errorf("conflicting settings for entity")
+	// ErrInvalidEntityConfig ...
+	ErrInvalidEntityConfig = fmt.Errorf("invalid entity config")
+	// ErrInvalidEntityPattern ...
+	ErrInvalidEntityPattern = fmt.Errorf("invalid entity pattern")
+	// ErrNilEntityScrubber ...
+	ErrNilEntityScrubber = fmt.Errorf("nil entity scrubber")
 )
 
-// NewWithCustomEntityScrubbers ...
-func NewWithCustomEntityScrubbers(params NewWithCustomEntityScrubbersParams) (Scrubber, error) {
+// EntityError is an invalid setting of an entity, returned by the
+// constructors, or an invalid match of its scrubber, returned when scrubbing.
+// Err wraps ErrUnknownEntity, ErrConflictingEntity, ErrInvalidEntityConfig,
+// ErrInvalidEntityPattern, ErrNilEntityScrubber or ErrInvalidMatchIndices,
+// so that errors.Is tells them apart
+type EntityError struct {
+	Entity Entity
+	Err    error
+}
+
+func (e *EntityError) Error() string {
+	return fmt.Sprintf("for entity: %v, error: %v", e.Entity, e.Err)
+}
+
+func (e *EntityError) Unwrap() error {
+	return e.Err
+}
+
+func entityErrorf(entity Entity, err error, format string, args ...interface{}) error {
+	return &EntityError{Entity: entity, Err: fmt.Errorf("%w: "+format, append([]interface{}{err}, args...)...)}
+}
 
-	for key, val := range params.Config {
+// resolveEntityScrubbers returns the scrubbers of the entities that aren't
+// the built-in ones, from CustomEntityScrubbers, CustomEntityPatterns and
+// ExtraPatterns
+func resolveEntityScrubbers(params Params) (map[Entity]EntityScrubber, error) {
+	for _, entity := range sortedEntities(params.CustomEntityScrubbers) {
+		if params.CustomEntityScrubbers[entity] == nil {
+			return nil, &EntityError{Entity: entity, Err: ErrNilEntityScrubber}
+		}
+	}
+	if len(params.CustomEntityPatterns) == 0 && len(params.ExtraPatterns) == 0 {
+		return params.CustomEntityScrubbers, nil
+	}
+
+	customScrubbers := make(map[Entity]EntityScrubber, len(params.CustomEntityScrubbers)+len(params.CustomEntityPatterns))
+	for entity, scrubber := range params.CustomEntityScrubbers {
+		customScrubbers[entity] = scrubber
+	}
+	for _, entity := range sortedEntities(params.CustomEntityPatterns) {
+		if _, ok := customScrubbers[entity]; ok {
+			return nil, entityErrorf(entity, ErrConflictingEntity, "it has both a custom scrubber and custom patterns")
+		}
+		scrubber, err := NewRegexEntityScrubberWithOptions(&RegexEntityOptions{
+			Placeholder: fmt.Sprintf("<%v>", entity),
+		}, params.CustomEntityPatterns[entity]...)
+		if err != nil {
+			return nil, entityErrorf(entity, ErrInvalidEntityPattern, "%v", err)
+		}
+		customScrubbers[entity] = scrubber
+	}
+	for _, entity := range sortedEntities(params.ExtraPatterns) {
+		base, ok := customScrubbers[entity]
+		if !ok {
+			base, ok = _defaultEntityScrubbers[entity]
+		}
+		if !ok {
+			return nil, entityErrorf(entity, ErrUnknownEntity, "in extra patterns")
+		}
+		scrubber, err := newExtendedEntityScrubber(base, params.ExtraPatterns[entity])
+		if err != nil {
+			return nil, entityErrorf(entity, ErrInvalidEntityPattern, "in extra patterns: %v", err)
+		}
+		customScrubbers[entity] = scrubber
+	}
+	return customScrubbers, nil
+}
+
+// validateEntities checks that every entity of params has a scrubber, that
+// no entity is both blacklisted and ignored, and that the configs of the
+// built-in scrubbers are valid
+func validateEntities(params Params, customScrubbers map[Entity]EntityScrubber) error {
+	known := func(entity Entity) bool {
+		if _, ok := customScrubbers[entity]; ok {
+			return true
+		}
+		_, ok := _defaultEntityScrubbers[entity]
+		return ok
+	}
+
+	for _, entity := range sortedEntities(params.Config) {
+		if !known(entity) {
+			return entityErrorf(entity, ErrUnknownEntity, "in config")
+		}
+		if params.Config[entity] == nil {
+			return entityErrorf(entity, ErrInvalidEntityConfig, "config is nil, leave the entity out instead")
+		}
 		// skip the check for custom scrubbers
-		if _, ok := params.CustomEntityScrubbers[key]; ok {
+		if _, ok := params.CustomEntityScrubbers[entity]; ok {
 			continue
 		}
-		if err := val.isValid(); err != nil {
-			return nil, fmt.Errorf("in config for entity: %v, error: %v", key, err.Error())
+		if err := params.Config[entity].isValid(); err != nil {
+			return entityErrorf(entity, ErrInvalidEntityConfig, "%v", err)
 		}
 	}
 
-	return &scrubber{
-		blacklistedEntities:   params.BlacklistedEntities,
-		ignoredEntities:       params.IgnoredEntities,
-		config:                params.Config,
-		userProvidedScrubbers: params.CustomEntityScrubbers,
-	}, nil
+	blacklisted := make(map[Entity]bool, len(params.BlacklistedEntities))
+	for _, entity := range params.BlacklistedEntities {
+		if !known(entity) {
+			return entityErrorf(entity, ErrUnknownEntity, "in blacklisted entities")
+		}
+		blacklisted[entity] = true
+	}
+	for _, entity := range params.IgnoredEntities {
+		if !known(entity) {
+			return entityErrorf(entity, ErrUnknownEntity, "in ignored entities")
+		}
+		if blacklisted[entity] {
+			return entityErrorf(entity, ErrConflictingEntity, "it is both blacklisted and ignored")
+		}
+	}
+	return nil
+}
+
+// sortedEntities returns the entities of m in order, so that the same
+// params always fail with the same error
+func sortedEntities[V any](m map[Entity]V) []Entity {
+	entities := make([]Entity, 0, len(m))
+	for entity := range m {
+		entities = append(entities, entity)
+	}
+	sort.Slice(entities, func(i, j int) bool {
+		return entities[i] < entities[j]
+	})
+	return entities
+}
+
+// NewWithCustomEntityScrubbers ...
+func NewWithCustomEntityScrubbers(params NewWithCustomEntityScrubbersParams) (Scrubber, error) {
+	return New(Params{
+		BlacklistedEntities:   params.BlacklistedEntities,
+		IgnoredEntities:       params.IgnoredEntities,
+		Config:                params.Config,
+		CustomEntityScrubbers: params.CustomEntityScrubbers,
+		CustomEntityPatterns:  params.CustomEntityPatterns,
+		ExtraPatterns:         params.ExtraPatterns,
+		FieldRules:            params.FieldRules,
+		KeyRules:              params.KeyRules,
+		ScrubMapKeys:          params.ScrubMapKeys,
+		AllowList:             params.AllowList,
+	})
 }
 
 type scrubber struct {
@@ -97,6 +307,11 @@ type scrubber struct {
 	blacklistedEntities   []Entity
 	ignoredEntities       []Entity
 	userProvidedScrubbers map[Entity]EntityScrubber
+	fieldRules            []FieldRule
+	keyRules              []compiledKeyRule
+	scrubMapKeys          bool
+	// allowList holds the lowercased values of Params.AllowList
+	allowList map[string]bool
 }
 
 // Entity ...
@@ -220,8 +435,9 @@ type intermediateResponse struct {
 }
 
 type intermediateScrubbingResponse struct {
-	index int
-	text  string
+	index    int
+	text     string
+	findings []Finding
 }
 
 func (s *scrubber) sortIntervals(intervals []*intermediateResponse) {
@@ -246,8 +462,8 @@ func (s *scrubber) getEntityMatches(entities []Entity, text string) ([]*intermed
 		matches := entityScrubber.Match(text)
 		if (len(matches)) > 0 {
 			for _, match := range matches {
-				if len(match) != 2 || match[0] >= match[1] {
-					return nil, ErrInvalidMatchIndices
+				if len(match) != 2 || match[0] < 0 || match[0] >= match[1] || match[1] > len(text) {
+					return nil, entityErrorf(entity, ErrInvalidMatchIndices, "%v in a text of %v bytes", match, len(text))
 				}
 				intervals = append(intervals, &intermediateResponse{
 					index:    match,
@@ -261,6 +477,15 @@ func (s *scrubber) getEntityMatches(entities []Entity, text string) ([]*intermed
 }
 
 func (s *scrubber) ScrubTexts(texts []string) ([]string, error) {
+	scrubbedTexts, _, err := s.scrubTexts(texts)
+	return scrubbedTexts, err
+}
+
+func (s *scrubber) ScrubTextsWithFindings(texts []string) ([]string, [][]Finding, error) {
+	return s.scrubTexts(texts)
+}
+
+func (s *scrubber) scrubTexts(texts []string) ([]string, [][]Finding, error) {
 
 	wp := goworker.NewWorkerPool(&goworker.WorkerPoolInput{WorkerCount: 4})
 	wp.Start()
@@ -272,77 +497,16 @@ func (s *scrubber) ScrubTexts(texts []string) ([]string, error) {
 
 		futures = append(futures, wp.Add(&goworker.Task{
 			F: func() (interface{}, error) {
-
-				// sort find all the intervals ...
-				intervals, err := s.getEntityMatches(s.blacklistedEntities, text)
+				scrubbedText, findings, err := s.scrubText(text)
 				if err != nil {
 					return nil, err
 				}
-				s.sortIntervals(intervals)
-
-				nonOverlapping := make([]*intermediateResponse, 0, len(intervals))
-
-				if len(intervals) > 0 {
-					nonOverlapping = append(nonOverlapping, intervals[0])
-				}
-
-				// make intervals non overlapping
-				for i := 1; i < len(intervals); i++ {
-					if intervals[i].index[0] <= intervals[i-1].index[1] {
-						if intervals[i-1].index[1] >= intervals[i].index[1] {
-							continue
-						}
-						intervals[i].index[0] = intervals[i-1].index[1] + 1
-					}
-					nonOverlapping = append(nonOverlapping, intervals[i])
-				}
-
-				// remove intervals for ignored entities
-				ignoredIntervals, err := s.getEntityMatches(s.ignoredEntities, text)
-				if err != nil {
-					return nil, err
-				}
-				s.sortIntervals(ignoredIntervals)
-
-				scrubbable := make([]*intermediateResponse, 0)
-				i, j := 0, 0
-				for ; i < len(nonOverlapping) && j < len(ignoredIntervals); j++ {
-					for ; i < len(nonOverlapping) && nonOverlapping[i].index[1] < ignoredIntervals[j].index[0]; i++ {
-						scrubbable = append(scrubbable, nonOverlapping[i])
-					}
-					for ; i < len(nonOverlapping) && nonOverlapping[i].index[0] <= ignoredIntervals[j].index[1]; i++ {
-					}
-				}
-				scrubbable = append(scrubbable, nonOverlapping[i:]...)
-
-				intervals = scrubbable
-
-				intervalsIterator := 0
-				scrubbedText := make([]byte, 0, len(text))
-				textBytes := []byte(text)
-				txtIterator := 0
-				for txtIterator < len(textBytes) {
-					if intervalsIterator < len(intervals) && txtIterator == intervals[intervalsIterator].index[0] {
-						config := _defaultEntityConfigs[intervals[intervalsIterator].entity]
-						if val, ok := s.config[intervals[intervalsIterator].entity]; ok {
-							config = val
-						}
-						replacementBytes := intervals[intervalsIterator].scrubber.Mask(textBytes[intervals[intervalsIterator].index[0]:intervals[intervalsIterator].index[1]], config)
-						scrubbedText = append(scrubbedText, replacementBytes...)
-						txtIterator = intervals[intervalsIterator].index[1]
-						intervalsIterator++
-						continue
-					}
-
-					scrubbedText = append(scrubbedText, textBytes[txtIterator])
-					txtIterator++
-				}
 
 				return &intermediateScrubbingResponse{
-					index: index,
-					text:  string(scrubbedText),
+					index:    index,
+					text:     scrubbedText,
+					findings: findings,
 				}, nil
-
 			},
 		}))
 	}
@@ -355,7 +519,7 @@ func (s *scrubber) ScrubTexts(texts []string) ([]string, error) {
 	for _, future := range futures {
 		fRes, fErr := future.Result(), future.Error()
 		if fErr != nil {
-			return nil, fErr
+			return nil, nil, fErr
 		}
 
 		res := fRes.(*intermediateScrubbingResponse)
@@ -367,14 +531,113 @@ func (s *scrubber) ScrubTexts(texts []string) ([]string, error) {
 	})
 
 	scrubbedTexts := make([]string, 0, len(texts))
+	findings := make([][]Finding, 0, len(texts))
 
 	for _, val := range results {
 		scrubbedTexts = append(scrubbedTexts, val.text)
+		findings = append(findings, val.findings)
+	}
+
+	return scrubbedTexts, findings, nil
+}
+
+func (s *scrubber) scrubText(text string) (string, []Finding, error) {
+	// sort find all the intervals ...
+	intervals, err := s.getEntityMatches(s.blacklistedEntities, text)
+	if err != nil {
+		return "", nil, err
+	}
+	s.sortIntervals(intervals)
+
+	nonOverlapping := make([]*intermediateResponse, 0, len(intervals))
+
+	if len(intervals) > 0 {
+		nonOverlapping = append(nonOverlapping, intervals[0])
+	}
+
+	// make intervals non overlapping
+	for i := 1; i < len(intervals); i++ {
+		if intervals[i].index[0] <= intervals[i-1].index[1] {
+			if intervals[i-1].index[1] >= intervals[i].index[1] {
+				continue
+			}
+			intervals[i].index[0] = intervals[i-1].index[1] + 1
+		}
+		nonOverlapping = append(nonOverlapping, intervals[i])
 	}
 
-	return scrubbedTexts, nil
+	// remove intervals for ignored entities
+	ignoredIntervals, err := s.getEntityMatches(s.ignoredEntities, text)
+	if err != nil {
+		return "", nil, err
+	}
+	s.sortIntervals(ignoredIntervals)
+
+	scrubbable := make([]*intermediateResponse, 0)
+	i, j := 0, 0
+	for ; i < len(nonOverlapping) && j < len(ignoredIntervals); j++ {
+		for ; i < len(nonOverlapping) && nonOverlapping[i].index[1] < ignoredIntervals[j].index[0]; i++ {
+			scrubbable = append(scrubbable, nonOverlapping[i])
+		}
+		for ; i < len(nonOverlapping) && nonOverlapping[i].index[0] <= ignoredIntervals[j].index[1]; i++ {
+		}
+	}
+	scrubbable = append(scrubbable, nonOverlapping[i:]...)
+
+	intervals = scrubbable
+	if s.allowList != nil {
+		intervals = intervals[:0]
+		for _, interval := range scrubbable {
+			if !s.allowList[strings.ToLower(text[interval.index[0]:interval.index[1]])] {
+				intervals = append(intervals, interval)
+			}
+		}
+	}
+
+	findings := make([]Finding, 0, len(intervals))
+	intervalsIterator := 0
+	scrubbedText := make([]byte, 0, len(text))
+	textBytes := []byte(text)
+	txtIterator := 0
+	for txtIterator < len(textBytes) {
+		if intervalsIterator < len(intervals) && txtIterator == intervals[intervalsIterator].index[0] {
+			config := _defaultEntityConfigs[intervals[intervalsIterator].entity]
+			if val, ok := s.config[intervals[intervalsIterator].entity]; ok {
+				config = val
+			}
+			findings = append(findings, Finding{
+				Entity: intervals[intervalsIterator].entity,
+				Start:  intervals[intervalsIterator].index[0],
+				End:    intervals[intervalsIterator].index[1],
+			})
+			replacementBytes := intervals[intervalsIterator].scrubber.Mask(textBytes[intervals[intervalsIterator].index[0]:intervals[intervalsIterator].index[1]], config)
+			scrubbedText = append(scrubbedText, replacementBytes...)
+			txtIterator = intervals[intervalsIterator].index[1]
+			intervalsIterator++
+			continue
+		}
+
+		scrubbedText = append(scrubbedText, textBytes[txtIterator])
+		txtIterator++
+	}
+
+	return string(scrubbedText), findings, nil
 }
 
 func (s *scrubber) ScrubStruct(obj interface{}) (interface{}, error) {
-	return s.parse(obj)
+	return s.parse(obj, nil)
+}
+
+func (s *scrubber) ScrubStructWithReport(obj interface{}) (interface{}, []FieldReport, error) {
+	report := make([]FieldReport, 0)
+	copy, err := s.parse(obj, &report)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sort.SliceStable(report, func(i, j int) bool {
+		return report[i].Path < report[j].Path
+	})
+
+	return copy, report, nil
 }
@@ -0,0 +1,201 @@
+package piiscrubber
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+)
+
+// Redactor turns a sensitive value into a form that is safe to print
+type Redactor interface {
+	Redact(value string) string
+}
+
+// RedactorFunc ...
+type RedactorFunc func(value string) string
+
+// Redact ...
+func (f RedactorFunc) Redact(value string) string {
+	return f(value)
+}
+
+// PlaceholderRedactor replaces the whole value with placeholder
+func PlaceholderRedactor(placeholder string) Redactor {
+	return RedactorFunc(func(string) string {
+		return placeholder
+	})
+}
+
+// HashRedactor replaces the value with a keyed hash of it, so that equal
+// values can still be correlated without being revealed
+func HashRedactor(key []byte) Redactor {
+	return RedactorFunc(func(value string) string {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(value))
+		return fmt.Sprintf("<HASH:%v>", hex.EncodeToString(mac.Sum(nil)[:8]))
+	})
+}
+
+// MaskRedactor masks the value the same way a detected entity is masked with
+// config, e.g. {MaskWithChar: &x, UnmaskedSuffixOffset: 4} keeps the last 4
+// characters
+func MaskRedactor(config *EntityConfig) (Redactor, error) {
+	if config == nil {
+		return nil, fmt.Errorf("%w: config must be specified", ErrInvalidEntityConfig)
+	}
+	if err := config.isValid(); err != nil {
+		return nil, err
+	}
+	return RedactorFunc(func(value string) string {
+		return string(NativeMasking([]byte(value), config))
+	}), nil
+}
+
+// ScrubberRedactor scrubs the entities detected by s out of the value and
+// leaves the rest of it readable. The value is replaced entirely if s fails
+func ScrubberRedactor(s Scrubber) Redactor {
+	return RedactorFunc(func(value string) string {
+		scrubbedTexts, err := s.ScrubTexts([]string{value})
+		if err != nil {
+			return _redactedValue
+		}
+		return scrubbedTexts[0]
+	})
+}
+
+var (
+	_defaultRedactorLock sync.RWMutex
+	_defaultRedactor     = PlaceholderRedactor(_redactedValue)
+)
+
+// SetDefaultRedactor sets the Redactor used by Sensitive values that were not
+// given one. The default replaces the value with <REDACTED>
+func SetDefaultRedactor(r Redactor) {
+	_defaultRedactorLock.Lock()
+	defer _defaultRedactorLock.Unlock()
+	_defaultRedactor = r
+}
+
+func defaultRedactor() Redactor {
+	_defaultRedactorLock.RLock()
+	defer _defaultRedactorLock.RUnlock()
+	return _defaultRedactor
+}
+
+// Sensitive holds a value that must not end up in logs or serialized output
+// by accident. fmt, encoding/json, encoding and log/slog only ever see its
+// redacted form, Reveal returns the real value
+type Sensitive[T any] struct {
+	value    T
+	redactor Redactor
+}
+
+// SensitiveString ...
+type SensitiveString = Sensitive[string]
+
+// NewSensitive wraps value, redacting it with the default Redactor
+func NewSensitive[T any](value T) Sensitive[T] {
+	return Sensitive[T]{value: value}
+}
+
+// WithRedactor returns a copy of s redacted with r
+func (s Sensitive[T]) WithRedactor(r Redactor) Sensitive[T] {
+	s.redactor = r
+	return s
+}
+
+// Reveal returns the real value
+func (s Sensitive[T]) Reveal() T {
+	return s.value
+}
+
+// Redacted returns the form of the value that is safe to print
+func (s Sensitive[T]) Redacted() string {
+	redactor := s.redactor
+	if redactor == nil {
+		redactor = defaultRedactor()
+	}
+
+	var value string
+	switch v := any(s.value).(type) {
+	case string:
+		value = v
+	case fmt.Stringer:
+		value = v.String()
+	default:
+		value = fmt.Sprint(v)
+	}
+	return redactor.Redact(value)
+}
+
+// String ...
+func (s Sensitive[T]) String() string {
+	return s.Redacted()
+}
+
+// GoString ...
+func (s Sensitive[T]) GoString() string {
+	return strconv.Quote(s.Redacted())
+}
+
+// Format writes the redacted form for every verb, so that %v, %+v, %#v, %s
+// and %q never reveal the value
+func (s Sensitive[T]) Format(f fmt.State, verb rune) {
+	switch {
+	case verb == 'q', verb == 'v' && f.Flag('#'):
+		fmt.Fprint(f, s.GoString())
+	default:
+		fmt.Fprint(f, s.Redacted())
+	}
+}
+
+// MarshalJSON ...
+func (s Sensitive[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Redacted())
+}
+
+// UnmarshalJSON decodes the real value, so that Sensitive fields can be
+// filled from requests
+func (s *Sensitive[T]) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &s.value)
+}
+
+// MarshalText ...
+func (s Sensitive[T]) MarshalText() ([]byte, error) {
+	return []byte(s.Redacted()), nil
+}
+
+// UnmarshalText decodes the real value, T must be a string or implement
+// encoding.TextUnmarshaler
+func (s *Sensitive[T]) UnmarshalText(text []byte) error {
+	switch v := any(&s.value).(type) {
+	case *string:
+		*v = string(text)
+		return nil
+	case encoding.TextUnmarshaler:
+		return v.UnmarshalText(text)
+	}
+	return fmt.Errorf("cannot unmarshal text into Sensitive[%T]", s.value)
+}
+
+// LogValue ...
+func (s Sensitive[T]) LogValue() slog.Value {
+	return slog.StringValue(s.Redacted())
+}
+
+var (
+	_ fmt.Stringer             = Sensitive[string]{}
+	_ fmt.GoStringer           = Sensitive[string]{}
+	_ fmt.Formatter            = Sensitive[string]{}
+	_ json.Marshaler           = Sensitive[string]{}
+	_ json.Unmarshaler         = &Sensitive[string]{}
+	_ encoding.TextMarshaler   = Sensitive[string]{}
+	_ encoding.TextUnmarshaler = &Sensitive[string]{}
+	_ slog.LogValuer           = Sensitive[string]{}
+)
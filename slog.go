@@ -0,0 +1,180 @@
+package piiscrubber
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// SlogHandlerOptions ...
+type SlogHandlerOptions struct {
+	// RedactKeys are attribute keys whose values are always replaced with
+	// <REDACTED>, e.g. "password". Keys of grouped attributes can also be
+	// given with their group, e.g. "user.email"
+	RedactKeys []string
+	// ExemptKeys are attribute keys whose values are never scrubbed, e.g.
+	// trace ids that look like a GUID or SHA1Hex
+	ExemptKeys []string
+}
+
+type slogHandler struct {
+	next       slog.Handler
+	scrubber   Scrubber
+	redactKeys map[string]bool
+	exemptKeys map[string]bool
+	// groups opened with WithGroup, used to match grouped keys
+	groups []string
+}
+
+// NewSlogHandler returns a slog.Handler that scrubs the message and the
+// attributes of every record before passing it on to next. String attributes,
+// grouped attributes and values implementing slog.LogValuer are scrubbed with
+// a single ScrubTexts call per record, other values are scrubbed with
+// ScrubStruct as if they were tagged with pii:"true"
+func NewSlogHandler(next slog.Handler, s Scrubber, opts *SlogHandlerOptions) slog.Handler {
+	if opts == nil {
+		opts = &SlogHandlerOptions{}
+	}
+
+	h := &slogHandler{
+		next:       next,
+		scrubber:   s,
+		redactKeys: make(map[string]bool, len(opts.RedactKeys)),
+		exemptKeys: make(map[string]bool, len(opts.ExemptKeys)),
+	}
+	for _, key := range opts.RedactKeys {
+		h.redactKeys[key] = true
+	}
+	for _, key := range opts.ExemptKeys {
+		h.exemptKeys[key] = true
+	}
+	return h
+}
+
+func (h *slogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *slogHandler) Handle(ctx context.Context, record slog.Record) error {
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+
+	message, attrs, err := h.scrubAttrs(record.Message, attrs)
+	if err != nil {
+		return err
+	}
+
+	scrubbed := slog.NewRecord(record.Time, record.Level, message, record.PC)
+	scrubbed.AddAttrs(attrs...)
+	return h.next.Handle(ctx, scrubbed)
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	_, scrubbed, err := h.scrubAttrs("", attrs)
+	if err != nil {
+		// never hand unscrubbed attributes over
+		scrubbed = make([]slog.Attr, 0, len(attrs))
+		for _, attr := range attrs {
+			scrubbed = append(scrubbed, slog.String(attr.Key, _redactedValue))
+		}
+	}
+
+	copy := *h
+	copy.next = h.next.WithAttrs(scrubbed)
+	return &copy
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	copy := *h
+	copy.next = h.next.WithGroup(name)
+	copy.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	return &copy
+}
+
+// scrubAttrs scrubs message and attrs with a single ScrubTexts call. The
+// attributes are walked twice: the first walk resolves them and collects
+// their strings, the second one puts the scrubbed strings back in the same
+// order
+func (h *slogHandler) scrubAttrs(message string, attrs []slog.Attr) (string, []slog.Attr, error) {
+	texts := []string{message}
+	var walkErr error
+	resolved := h.rewriteAttrs(attrs, h.groups, func(text string) string {
+		texts = append(texts, text)
+		return text
+	}, func(value slog.Value) slog.Value {
+		scrubbed, err := ScrubValue(h.scrubber, value.Any(), true)
+		if err != nil {
+			walkErr = err
+			return slog.StringValue(_redactedValue)
+		}
+		return slog.AnyValue(scrubbed)
+	})
+	if walkErr != nil {
+		return "", nil, walkErr
+	}
+
+	scrubbedTexts, err := h.scrubber.ScrubTexts(texts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	next := 1
+	scrubbed := h.rewriteAttrs(resolved, h.groups, func(string) string {
+		if next >= len(scrubbedTexts) {
+			return _redactedValue
+		}
+		text := scrubbedTexts[next]
+		next++
+		return text
+	}, func(value slog.Value) slog.Value {
+		return value
+	})
+
+	return scrubbedTexts[0], scrubbed, nil
+}
+
+// rewriteAttrs returns a copy of attrs with resolved values, string values
+// replaced by text and other values by value
+func (h *slogHandler) rewriteAttrs(attrs []slog.Attr, groups []string, text func(string) string, value func(slog.Value) slog.Value) []slog.Attr {
+	rewritten := make([]slog.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		rewritten = append(rewritten, h.rewriteAttr(attr, groups, text, value))
+	}
+	return rewritten
+}
+
+func (h *slogHandler) rewriteAttr(attr slog.Attr, groups []string, text func(string) string, value func(slog.Value) slog.Value) slog.Attr {
+	path := strings.Join(append(groups[:len(groups):len(groups)], attr.Key), ".")
+	if h.exemptKeys[attr.Key] || h.exemptKeys[path] {
+		return attr
+	}
+	if h.redactKeys[attr.Key] || h.redactKeys[path] {
+		return slog.String(attr.Key, _redactedValue)
+	}
+
+	attr.Value = attr.Value.Resolve()
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		attr.Value = slog.StringValue(text(attr.Value.String()))
+
+	case slog.KindGroup:
+		// inline groups have no key of their own
+		groupPath := groups
+		if attr.Key != "" {
+			groupPath = append(groups[:len(groups):len(groups)], attr.Key)
+		}
+		members := h.rewriteAttrs(attr.Value.Group(), groupPath, text, value)
+		attr.Value = slog.GroupValue(members...)
+
+	case slog.KindAny:
+		if err, ok := attr.Value.Any().(error); ok {
+			attr.Value = slog.StringValue(text(err.Error()))
+			break
+		}
+		attr.Value = value(attr.Value)
+	}
+	return attr
+}
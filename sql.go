@@ -0,0 +1,92 @@
+package piiscrubber
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrNoSQLScrubber ...
+	ErrNoSQLScrubber = fmt.Errorf("no scrubber was set with SetSQLScrubber")
+)
+
+var (
+	_sqlScrubberLock sync.RWMutex
+	_sqlScrubber     Scrubber
+)
+
+// SetSQLScrubber sets the Scrubber that ScrubbedString values are scrubbed
+// with when they are written to a database
+func SetSQLScrubber(s Scrubber) {
+	_sqlScrubberLock.Lock()
+	defer _sqlScrubberLock.Unlock()
+	_sqlScrubber = s
+}
+
+func sqlScrubber() Scrubber {
+	_sqlScrubberLock.RLock()
+	defer _sqlScrubberLock.RUnlock()
+	return _sqlScrubber
+}
+
+// ScrubbedString is a string column that is scrubbed with the Scrubber set
+// with SetSQLScrubber whenever it is written, so the raw value never reaches
+// the database. Writing it fails with ErrNoSQLScrubber when no Scrubber was
+// set
+type ScrubbedString string
+
+// Value ...
+func (s ScrubbedString) Value() (driver.Value, error) {
+	scrubber := sqlScrubber()
+	if scrubber == nil {
+		return nil, ErrNoSQLScrubber
+	}
+	scrubbedTexts, err := scrubber.ScrubTexts([]string{string(s)})
+	if err != nil {
+		return nil, err
+	}
+	return scrubbedTexts[0], nil
+}
+
+// Scan reads the value as it is stored, i.e. already scrubbed
+func (s *ScrubbedString) Scan(src interface{}) error {
+	var value sql.NullString
+	if err := value.Scan(src); err != nil {
+		return err
+	}
+	*s = ScrubbedString(value.String)
+	return nil
+}
+
+// NullScrubbedString is a ScrubbedString that may be NULL
+type NullScrubbedString struct {
+	String ScrubbedString
+	Valid  bool
+}
+
+// Value ...
+func (s NullScrubbedString) Value() (driver.Value, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	return s.String.Value()
+}
+
+// Scan ...
+func (s *NullScrubbedString) Scan(src interface{}) error {
+	var value sql.NullString
+	if err := value.Scan(src); err != nil {
+		return err
+	}
+	s.String, s.Valid = ScrubbedString(value.String), value.Valid
+	return nil
+}
+
+var (
+	_ driver.Valuer = ScrubbedString("")
+	_ sql.Scanner   = (*ScrubbedString)(nil)
+	_ driver.Valuer = NullScrubbedString{}
+	_ sql.Scanner   = (*NullScrubbedString)(nil)
+)
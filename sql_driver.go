@@ -0,0 +1,987 @@
+package piiscrubber
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrUnmappedSQLParameters ...
+	ErrUnmappedSQLParameters = fmt.Errorf("cannot map the parameters or string literals of a write to a scrubbed table to its columns")
+)
+
+// SQLDriverOptions ...
+type SQLDriverOptions struct {
+	// Columns map "table.column" to the action applied to the parameters that
+	// INSERT, REPLACE and UPDATE statements bind to the column, e.g.
+	// "users.email": Scan. "table.*" applies to every column of the table.
+	// Names are case insensitive and tables are matched without their schema
+	Columns map[string]Action
+}
+
+// sqlPolicies holds the actions of the configured columns by table and
+// column, "*" standing for every column
+type sqlPolicies struct {
+	scrubber Scrubber
+	tables   map[string]map[string]Action
+}
+
+func newSQLPolicies(s Scrubber, opts *SQLDriverOptions) (*sqlPolicies, error) {
+	p := &sqlPolicies{
+		scrubber: s,
+		tables:   make(map[string]map[string]Action),
+	}
+	if opts == nil {
+		return p, nil
+	}
+
+	for key, action := range opts.Columns {
+		if err := action.isValid(); err != nil {
+			return nil, fmt.Errorf("in column policy for: %v, error: %v", key, err.Error())
+		}
+		dot := strings.LastIndexByte(key, '.')
+		if dot <= 0 || dot == len(key)-1 {
+			return nil, fmt.Errorf("in column policy for: %v, error: expected table.column", key)
+		}
+
+		table, column := strings.ToLower(key[:dot]), strings.ToLower(key[dot+1:])
+		if i := strings.LastIndexByte(table, '.'); i >= 0 {
+			table = table[i+1:]
+		}
+		if p.tables[table] == nil {
+			p.tables[table] = make(map[string]Action)
+		}
+		p.tables[table][column] = action
+	}
+	return p, nil
+}
+
+// sqlBindings are the actions applied to the parameters of a statement, by
+// ordinal and by name, and to the string literals of the statement
+type sqlBindings struct {
+	byOrdinal map[int]Action
+	byName    map[string]Action
+	literals  []sqlBoundLiteral
+}
+
+// sqlBoundLiteral is a string literal bound to a configured column
+type sqlBoundLiteral struct {
+	token  sqlToken
+	action Action
+}
+
+func (b *sqlBindings) add(param sqlParam, action Action) {
+	if action == Keep {
+		return
+	}
+	// a parameter bound to several columns gets the strictest action
+	if b.byOrdinal[param.ordinal] != Redact {
+		b.byOrdinal[param.ordinal] = action
+	}
+	if param.name != "" && b.byName[param.name] != Redact {
+		b.byName[param.name] = action
+	}
+}
+
+func (b *sqlBindings) addLiteral(token sqlToken, action Action) {
+	if action == Keep {
+		return
+	}
+	b.literals = append(b.literals, sqlBoundLiteral{token: token, action: action})
+}
+
+// bind returns the actions for the parameters and the string literals of
+// query, nil when none of them is bound to a configured column. Writes to
+// configured tables whose parameters or string literals can't be mapped to
+// columns are rejected rather than executed as they are
+func (p *sqlPolicies) bind(query string) (*sqlBindings, error) {
+	if len(p.tables) == 0 {
+		return nil, nil
+	}
+
+	b := &sqlBindings{
+		byOrdinal: make(map[int]Action),
+		byName:    make(map[string]Action),
+	}
+	for _, write := range parseSQLWrites(query) {
+		columns, ok := p.tables[write.table]
+		if !ok {
+			continue
+		}
+		if !write.mapped {
+			return nil, fmt.Errorf("%w: %v", ErrUnmappedSQLParameters, write.table)
+		}
+		for _, binding := range write.bindings {
+			action, ok := columns[binding.column]
+			if !ok {
+				action, ok = columns["*"]
+			}
+			if !ok {
+				continue
+			}
+			for _, param := range binding.params {
+				b.add(param, action)
+			}
+			for _, literal := range binding.literals {
+				b.addLiteral(literal, action)
+			}
+		}
+	}
+
+	if len(b.byOrdinal) == 0 && len(b.literals) == 0 {
+		return nil, nil
+	}
+	return b, nil
+}
+
+// scrubQuery returns query with the string literals bound to configured
+// columns scrubbed or redacted, all of them scrubbed with a single
+// ScrubTexts call
+func (p *sqlPolicies) scrubQuery(b *sqlBindings, query string) (string, error) {
+	if b == nil || len(b.literals) == 0 {
+		return query, nil
+	}
+
+	var batch TextBatch
+	texts := make([]string, len(b.literals))
+	for i, literal := range b.literals {
+		if literal.action == Redact {
+			texts[i] = _redactedValue
+			continue
+		}
+		texts[i] = literal.token.text
+		batch.Add(&texts[i])
+	}
+	if err := batch.Scrub(p.scrubber); err != nil {
+		return "", err
+	}
+
+	var scrubbed strings.Builder
+	last := 0
+	for i, literal := range b.literals {
+		scrubbed.WriteString(query[last:literal.token.start])
+		scrubbed.WriteString("'" + strings.ReplaceAll(texts[i], "'", "''") + "'")
+		last = literal.token.end
+	}
+	scrubbed.WriteString(query[last:])
+	return scrubbed.String(), nil
+}
+
+// scrubArgs returns a copy of args with the values of bound parameters
+// scrubbed or redacted, all of them scrubbed with a single ScrubTexts call.
+// Only string and []byte values are rewritten
+func (p *sqlPolicies) scrubArgs(b *sqlBindings, args []driver.NamedValue) ([]driver.NamedValue, error) {
+	if b == nil {
+		return args, nil
+	}
+
+	scrubbed := make([]driver.NamedValue, len(args))
+	copy(scrubbed, args)
+
+	var batch TextBatch
+	texts := make(map[int]*string)
+	for i, arg := range scrubbed {
+		action := b.byOrdinal[arg.Ordinal]
+		if arg.Name != "" {
+			action = b.byName[arg.Name]
+		}
+		if action != Scan && action != Redact {
+			continue
+		}
+
+		var text string
+		switch value := arg.Value.(type) {
+		case string:
+			text = value
+		case []byte:
+			text = string(value)
+		default:
+			continue
+		}
+
+		if action == Redact {
+			text = _redactedValue
+		} else {
+			batch.Add(&text)
+		}
+		texts[i] = &text
+	}
+
+	if err := batch.Scrub(p.scrubber); err != nil {
+		return nil, err
+	}
+	for i, text := range texts {
+		if _, ok := scrubbed[i].Value.([]byte); ok {
+			scrubbed[i].Value = []byte(*text)
+		} else {
+			scrubbed[i].Value = *text
+		}
+	}
+	return scrubbed, nil
+}
+
+// WrapSQLDriver returns a driver that scrubs the parameters INSERT, REPLACE
+// and UPDATE statements bind to the columns configured in opts before
+// passing them on to next, so that raw PII never reaches those columns
+// whichever code path writes them. String literals bound to the columns are
+// scrubbed in the statement itself, other literals are left as they are
+//
+// example: d, err := WrapSQLDriver(&sqlite.Driver{}, scrubber, opts); sql.Register("sqlite-scrubbed", d)
+func WrapSQLDriver(next driver.Driver, s Scrubber, opts *SQLDriverOptions) (driver.Driver, error) {
+	policies, err := newSQLPolicies(s, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlDriver{next: next, policies: policies}, nil
+}
+
+// NewSQLConnector returns a connector that scrubs parameters the same way as
+// WrapSQLDriver, for use with sql.OpenDB
+func NewSQLConnector(next driver.Connector, s Scrubber, opts *SQLDriverOptions) (driver.Connector, error) {
+	policies, err := newSQLPolicies(s, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConnector{
+		next:   next,
+		driver: &sqlDriver{next: next.Driver(), policies: policies},
+	}, nil
+}
+
+type sqlDriver struct {
+	next     driver.Driver
+	policies *sqlPolicies
+}
+
+func (d *sqlDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.next.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{Conn: conn, policies: d.policies}, nil
+}
+
+func (d *sqlDriver) OpenConnector(name string) (driver.Connector, error) {
+	if driverContext, ok := d.next.(driver.DriverContext); ok {
+		connector, err := driverContext.OpenConnector(name)
+		if err != nil {
+			return nil, err
+		}
+		return &sqlConnector{next: connector, driver: d}, nil
+	}
+	return &sqlConnector{next: &dsnConnector{name: name, driver: d.next}, driver: d}, nil
+}
+
+// dsnConnector connects with drivers that don't implement DriverContext
+type dsnConnector struct {
+	name   string
+	driver driver.Driver
+}
+
+func (c *dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.name)
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type sqlConnector struct {
+	next   driver.Connector
+	driver *sqlDriver
+}
+
+func (c *sqlConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.next.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{Conn: conn, policies: c.driver.policies}, nil
+}
+
+func (c *sqlConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// sqlConn passes the optional interfaces of the wrapped connection through,
+// falling back to what database/sql does when they are not implemented
+type sqlConn struct {
+	driver.Conn
+	policies *sqlPolicies
+}
+
+func (c *sqlConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *sqlConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	bindings, err := c.policies.bind(query)
+	if err != nil {
+		return nil, err
+	}
+	query, err = c.policies.scrubQuery(bindings, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var stmt driver.Stmt
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sqlStmt{Stmt: stmt, bindings: bindings, policies: c.policies}, nil
+}
+
+func (c *sqlConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		// database/sql prepares the statement instead
+		return nil, driver.ErrSkip
+	}
+	bindings, err := c.policies.bind(query)
+	if err != nil {
+		return nil, err
+	}
+	query, err = c.policies.scrubQuery(bindings, query)
+	if err != nil {
+		return nil, err
+	}
+	args, err = c.policies.scrubArgs(bindings, args)
+	if err != nil {
+		return nil, err
+	}
+	return execer.ExecContext(ctx, query, args)
+}
+
+func (c *sqlConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	bindings, err := c.policies.bind(query)
+	if err != nil {
+		return nil, err
+	}
+	query, err = c.policies.scrubQuery(bindings, query)
+	if err != nil {
+		return nil, err
+	}
+	args, err = c.policies.scrubArgs(bindings, args)
+	if err != nil {
+		return nil, err
+	}
+	return queryer.QueryContext(ctx, query, args)
+}
+
+func (c *sqlConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != 0 || opts.ReadOnly {
+		return nil, fmt.Errorf("driver does not support transaction options")
+	}
+	return c.Conn.Begin() //nolint:staticcheck // the driver has no BeginTx
+}
+
+func (c *sqlConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *sqlConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *sqlConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *sqlConn) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+type sqlStmt struct {
+	driver.Stmt
+	bindings *sqlBindings
+	policies *sqlPolicies
+}
+
+func (s *sqlStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *sqlStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	args, err := s.policies.scrubArgs(s.bindings, args)
+	if err != nil {
+		return nil, err
+	}
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		return execer.ExecContext(ctx, args)
+	}
+	values, err := ordinalValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Stmt.Exec(values) //nolint:staticcheck // the driver has no ExecContext
+}
+
+func (s *sqlStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *sqlStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	args, err := s.policies.scrubArgs(s.bindings, args)
+	if err != nil {
+		return nil, err
+	}
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		return queryer.QueryContext(ctx, args)
+	}
+	values, err := ordinalValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Stmt.Query(values) //nolint:staticcheck // the driver has no QueryContext
+}
+
+func (s *sqlStmt) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+func namedValues(values []driver.Value) []driver.NamedValue {
+	args := make([]driver.NamedValue, len(values))
+	for i, value := range values {
+		args[i] = driver.NamedValue{Ordinal: i + 1, Value: value}
+	}
+	return args
+}
+
+func ordinalValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, fmt.Errorf("driver does not support named parameters")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
+
+var (
+	_ driver.DriverContext      = &sqlDriver{}
+	_ driver.Connector          = &sqlConnector{}
+	_ driver.ConnPrepareContext = &sqlConn{}
+	_ driver.ExecerContext      = &sqlConn{}
+	_ driver.QueryerContext     = &sqlConn{}
+	_ driver.ConnBeginTx        = &sqlConn{}
+	_ driver.Pinger             = &sqlConn{}
+	_ driver.SessionResetter    = &sqlConn{}
+	_ driver.Validator          = &sqlConn{}
+	_ driver.NamedValueChecker  = &sqlConn{}
+	_ driver.StmtExecContext    = &sqlStmt{}
+	_ driver.StmtQueryContext   = &sqlStmt{}
+	_ driver.NamedValueChecker  = &sqlStmt{}
+)
+
+// sqlParam is a placeholder, named placeholders also get the ordinal SQLite
+// gives them so that they can be bound by position
+type sqlParam struct {
+	ordinal int
+	name    string
+}
+
+type sqlTokenKind int
+
+const (
+	sqlWord sqlTokenKind = iota
+	// a quoted identifier, never a keyword
+	sqlQuoted
+	sqlPlaceholder
+	sqlLiteral
+	// a quoted string literal, text is its unquoted value
+	sqlString
+	sqlPunct
+)
+
+type sqlToken struct {
+	kind  sqlTokenKind
+	text  string
+	param sqlParam
+	// start and end are the offsets of string literals in the query
+	start, end int
+}
+
+// is reports whether the token is the keyword
+func (t sqlToken) is(keyword string) bool {
+	return t.kind == sqlWord && strings.EqualFold(t.text, keyword)
+}
+
+func (t sqlToken) isIdentifier() bool {
+	return t.kind == sqlWord || t.kind == sqlQuoted
+}
+
+// tokenizeSQL splits query into the tokens needed to find the columns
+// parameters are bound to. It supports ?, ?NNN, $NNN, :name, @name and
+// $name placeholders
+func tokenizeSQL(query string) []sqlToken {
+	var tokens []sqlToken
+	maxOrdinal := 0
+	ordinals := make(map[string]int)
+
+	addParam := func(name string, ordinal int) {
+		switch {
+		case ordinal > 0:
+		case name != "" && ordinals[name] > 0:
+			ordinal = ordinals[name]
+		default:
+			ordinal = maxOrdinal + 1
+		}
+		if name != "" {
+			ordinals[name] = ordinal
+		}
+		if ordinal > maxOrdinal {
+			maxOrdinal = ordinal
+		}
+		tokens = append(tokens, sqlToken{kind: sqlPlaceholder, param: sqlParam{ordinal: ordinal, name: name}})
+	}
+	// scanWord returns the end of the identifier starting at i
+	scanWord := func(i int) int {
+		for i < len(query) && isSQLIdentifier(query[i]) {
+			i++
+		}
+		return i
+	}
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return tokens
+			}
+			i += end + 1
+
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 4
+
+		case c == '\'':
+			j := i + 1
+			for j < len(query) {
+				if query[j] == '\'' {
+					if j+1 < len(query) && query[j+1] == '\'' {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			tokens = append(tokens, sqlToken{
+				kind:  sqlString,
+				text:  strings.ReplaceAll(query[i+1:j], "''", "'"),
+				start: i,
+				end:   min(j+1, len(query)),
+			})
+			i = j + 1
+
+		case c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			end := strings.IndexByte(query[i+1:], closing)
+			if end < 0 {
+				end = len(query) - i - 1
+			}
+			tokens = append(tokens, sqlToken{kind: sqlQuoted, text: query[i+1 : i+1+end]})
+			i += end + 2
+
+		case c == '?' || c == '$':
+			j := i + 1
+			for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			switch {
+			case j > i+1:
+				ordinal, _ := strconv.Atoi(query[i+1 : j])
+				addParam("", ordinal)
+				i = j
+			case c == '$' && j < len(query) && isSQLIdentifier(query[j]):
+				j = scanWord(j)
+				addParam(query[i+1:j], 0)
+				i = j
+			case c == '?':
+				addParam("", 0)
+				i++
+			default:
+				tokens = append(tokens, sqlToken{kind: sqlPunct, text: string(c)})
+				i++
+			}
+
+		case (c == ':' || c == '@') && i+1 < len(query) && isSQLIdentifier(query[i+1]):
+			j := scanWord(i + 1)
+			addParam(query[i+1:j], 0)
+			i = j
+
+		case c == ':' && strings.HasPrefix(query[i:], "::"):
+			// a PostgreSQL cast, the type that follows is not a parameter
+			tokens = append(tokens, sqlToken{kind: sqlPunct, text: "::"})
+			i += 2
+
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(query) && (isSQLIdentifier(query[j]) || query[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlLiteral})
+			i = j
+
+		case isSQLIdentifier(c):
+			j := scanWord(i)
+			tokens = append(tokens, sqlToken{kind: sqlWord, text: query[i:j]})
+			i = j
+
+		default:
+			tokens = append(tokens, sqlToken{kind: sqlPunct, text: string(c)})
+			i++
+		}
+	}
+	return tokens
+}
+
+func isSQLIdentifier(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c >= 0x80
+}
+
+// sqlWrite is an INSERT, REPLACE or UPDATE statement with the parameters
+// and the string literals bound to each column. mapped is false when some of
+// its parameters or string literals could not be mapped to columns
+type sqlWrite struct {
+	table    string
+	bindings []sqlBinding
+	mapped   bool
+}
+
+type sqlBinding struct {
+	column   string
+	params   []sqlParam
+	literals []sqlToken
+}
+
+// parseSQLWrites returns the writes among the statements of query
+func parseSQLWrites(query string) []sqlWrite {
+	var writes []sqlWrite
+	statementsWithParams := 0
+	tokens := tokenizeSQL(query)
+	for len(tokens) > 0 {
+		end := indexSQLToken(tokens, 0, func(t sqlToken) bool { return t.kind == sqlPunct && t.text == ";" })
+		if end < 0 {
+			end = len(tokens)
+		}
+		if hasSQLToken(tokens[:end], sqlPlaceholder) {
+			statementsWithParams++
+		}
+		if write, ok := parseSQLWrite(tokens[:end]); ok {
+			writes = append(writes, write)
+		}
+		tokens = tokens[min(end+1, len(tokens)):]
+	}
+
+	if statementsWithParams > 1 {
+		// drivers don't agree on how the parameters of several statements
+		// are numbered
+		for i := range writes {
+			writes[i].mapped = false
+		}
+	}
+	return writes
+}
+
+func parseSQLWrite(tokens []sqlToken) (sqlWrite, bool) {
+	// the first top level keyword is the statement, which skips the common
+	// table expressions of a WITH clause
+	start := indexSQLToken(tokens, 0, func(t sqlToken) bool {
+		return t.is("INSERT") || t.is("REPLACE") || t.is("UPDATE") ||
+			t.is("SELECT") || t.is("DELETE") || t.is("VALUES")
+	})
+	if start < 0 {
+		return sqlWrite{}, false
+	}
+
+	p := &sqlParser{tokens: tokens, i: start + 1}
+	var write sqlWrite
+	if tokens[start].is("UPDATE") {
+		write = p.parseUpdate()
+	} else if tokens[start].is("INSERT") || tokens[start].is("REPLACE") {
+		write = p.parseInsert()
+	} else {
+		return sqlWrite{}, false
+	}
+
+	if !write.mapped {
+		// without parameters or string literals, e.g. INSERT ... SELECT from
+		// another table, the statement brings no values of its own
+		write.mapped = !hasSQLToken(tokens, sqlPlaceholder) && !hasSQLToken(tokens, sqlString)
+	}
+	return write, write.table != ""
+}
+
+func hasSQLToken(tokens []sqlToken, kind sqlTokenKind) bool {
+	for _, t := range tokens {
+		if t.kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// indexSQLToken returns the index of the first token from start that is not
+// nested in parentheses and matches, or -1
+func indexSQLToken(tokens []sqlToken, start int, match func(sqlToken) bool) int {
+	depth := 0
+	for i := start; i < len(tokens); i++ {
+		t := tokens[i]
+		if depth == 0 && match(t) {
+			return i
+		}
+		if t.kind == sqlPunct {
+			switch t.text {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			}
+		}
+	}
+	return -1
+}
+
+type sqlParser struct {
+	tokens []sqlToken
+	i      int
+}
+
+func (p *sqlParser) peek() sqlToken {
+	if p.i >= len(p.tokens) {
+		return sqlToken{kind: sqlPunct}
+	}
+	return p.tokens[p.i]
+}
+
+func (p *sqlParser) punct(text string) bool {
+	if t := p.peek(); t.kind == sqlPunct && t.text == text {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *sqlParser) keyword(keywords ...string) bool {
+	start := p.i
+	for _, keyword := range keywords {
+		if !p.peek().is(keyword) {
+			p.i = start
+			return false
+		}
+		p.i++
+	}
+	return true
+}
+
+// name parses a possibly qualified name and returns its last part in lower
+// case
+func (p *sqlParser) name() (string, bool) {
+	if !p.peek().isIdentifier() {
+		return "", false
+	}
+	name := p.peek().text
+	p.i++
+	for p.punct(".") {
+		if !p.peek().isIdentifier() {
+			return "", false
+		}
+		name = p.peek().text
+		p.i++
+	}
+	return strings.ToLower(name), true
+}
+
+// expression returns the parameters and the string literals of the
+// expression up to the next top level comma or closing parenthesis, or a
+// keyword ending a SET list, bound to column
+func (p *sqlParser) expression(column string) sqlBinding {
+	binding := sqlBinding{column: column}
+	depth := 0
+	for ; p.i < len(p.tokens); p.i++ {
+		t := p.tokens[p.i]
+		if depth == 0 && (t.kind == sqlPunct && (t.text == "," || t.text == ")") ||
+			t.is("WHERE") || t.is("FROM") || t.is("RETURNING") || t.is("ORDER") || t.is("LIMIT")) {
+			break
+		}
+		switch {
+		case t.kind == sqlPlaceholder:
+			binding.params = append(binding.params, t.param)
+		case t.kind == sqlString:
+			binding.literals = append(binding.literals, t)
+		case t.kind == sqlPunct && t.text == "(":
+			depth++
+		case t.kind == sqlPunct && t.text == ")":
+			depth--
+		}
+	}
+	return binding
+}
+
+// setList parses "column = expression, ..."
+func (p *sqlParser) setList(write *sqlWrite) bool {
+	for {
+		column, ok := p.name()
+		if !ok || !p.punct("=") {
+			// e.g. a (a, b) = (?, ?) tuple
+			return false
+		}
+		write.bindings = append(write.bindings, p.expression(column))
+		if !p.punct(",") {
+			return true
+		}
+	}
+}
+
+func (p *sqlParser) parseInsert() sqlWrite {
+	var write sqlWrite
+	if p.keyword("OR") {
+		p.i++
+	}
+	p.keyword("IGNORE")
+	p.keyword("INTO")
+
+	table, ok := p.name()
+	if !ok {
+		return write
+	}
+	write.table = table
+	if p.keyword("AS") {
+		p.i++
+	}
+
+	var columns []string
+	if p.punct("(") {
+		for {
+			column, ok := p.name()
+			if !ok {
+				return write
+			}
+			columns = append(columns, column)
+			if p.punct(")") {
+				break
+			}
+			if !p.punct(",") {
+				return write
+			}
+		}
+	}
+
+	if p.keyword("DEFAULT", "VALUES") {
+		write.mapped = true
+		return write
+	}
+	if !p.keyword("VALUES") && !p.keyword("VALUE") || columns == nil {
+		// e.g. INSERT ... SELECT
+		return write
+	}
+	for {
+		if !p.punct("(") {
+			return write
+		}
+		for i := 0; ; i++ {
+			if i >= len(columns) {
+				return write
+			}
+			write.bindings = append(write.bindings, p.expression(columns[i]))
+			if p.punct(")") {
+				if i != len(columns)-1 {
+					return write
+				}
+				break
+			}
+			if !p.punct(",") {
+				return write
+			}
+		}
+		if !p.punct(",") {
+			break
+		}
+	}
+
+	// upserts set columns of the same table
+	for p.i < len(p.tokens) {
+		if p.keyword("DO", "UPDATE", "SET") || p.keyword("DUPLICATE", "KEY", "UPDATE") {
+			if !p.setList(&write) {
+				return write
+			}
+			continue
+		}
+		if p.peek().is("RETURNING") {
+			break
+		}
+		p.i++
+	}
+	write.mapped = true
+	return write
+}
+
+func (p *sqlParser) parseUpdate() sqlWrite {
+	var write sqlWrite
+	if p.keyword("OR") {
+		p.i++
+	}
+	p.keyword("LOW_PRIORITY")
+	p.keyword("IGNORE")
+	p.keyword("ONLY")
+
+	table, ok := p.name()
+	if !ok {
+		return write
+	}
+	write.table = table
+	if p.keyword("AS") || !p.peek().is("SET") {
+		// an alias
+		p.i++
+	}
+
+	if !p.keyword("SET") || !p.setList(&write) {
+		// e.g. a MySQL UPDATE with joins
+		return write
+	}
+	write.mapped = true
+	return write
+}
@@ -1,7 +1,9 @@
 package piiscrubber
 
 import (
+	"fmt"
 	"reflect"
+	"sort"
 )
 
 // Borrowed from: https://gist.github.com/hvoecking/10772475
@@ -11,17 +13,52 @@ const (
 	_piiTagValueTrue = "true"
 )
 
+var (
+	// ErrUnexportedPIIField ...
+	ErrUnexportedPIIField = fmt.Errorf("cannot scrub an unexported field tagged with pii:\"true\", export it or move the tag")
+)
+
 type taggedField struct {
 	text string
 	ref  reflect.Value
 }
 
-func (s *scrubber) parse(obj interface{}) (interface{}, error) {
+// parseState carries what parseRecursive needs to know about the value it is
+// visiting besides the value itself
+type parseState struct {
+	hasPIITag bool
+	// path of the visited value relative to the parsed object
+	path string
+	// report collects findings per field path, nil when no report is wanted
+	report *[]FieldReport
+	// redact is set when a field rule asked for the value to be redacted
+	redact bool
+	// rules and anchors are only set when there are field rules to apply
+	rules   *fieldRuleSet
+	anchors []ruleAnchor
+}
+
+func (s *scrubber) parse(obj interface{}, report *[]FieldReport) (interface{}, error) {
+	return s.parseValue(obj, false, report)
+}
+
+// parseValue parses obj as if it were in a pii tagged field when tagged is
+// set, paths and field rules are relative to obj
+func (s *scrubber) parseValue(obj interface{}, tagged bool, report *[]FieldReport) (interface{}, error) {
 	// Wrap the original in a reflect.Value
 	original := reflect.ValueOf(obj)
 
+	state := parseState{
+		hasPIITag: tagged,
+		report:    report,
+		rules:     newFieldRuleSet(registeredFieldRules(), s.fieldRules),
+	}
+	if state.rules != nil && len(state.rules.root) > 0 {
+		state.anchors = []ruleAnchor{{rules: state.rules.root}}
+	}
+
 	copy := reflect.New(original.Type()).Elem()
-	if err := s.parseRecursive(copy, original, false); err != nil {
+	if err := s.parseRecursive(copy, original, state); err != nil {
 		return nil, err
 	}
 
@@ -29,7 +66,41 @@ func (s *scrubber) parse(obj interface{}) (interface{}, error) {
 	return copy.Interface(), nil
 }
 
-func (s *scrubber) parseRecursive(copy, original reflect.Value, hasPIITag bool) error {
+// applyFieldRules anchors the rules registered for the type of original and
+// applies the action of the rule matching the current position
+func (s *scrubber) applyFieldRules(original reflect.Value, state parseState) parseState {
+	if kind := original.Kind(); kind != reflect.Ptr && kind != reflect.Interface {
+		if rules := state.rules.rulesFor(original.Type()); len(rules) > 0 {
+			anchors := make([]ruleAnchor, 0, len(state.anchors)+1)
+			anchors = append(anchors, state.anchors...)
+			state.anchors = append(anchors, ruleAnchor{rules: rules})
+		}
+	}
+
+	if action, ok := matchRuleAnchors(state.anchors); ok {
+		state = state.withAction(action)
+	}
+
+	return state
+}
+
+func (state parseState) withAction(action Action) parseState {
+	switch action {
+	case Scan:
+		state.hasPIITag, state.redact = true, false
+	case Redact:
+		state.redact = true
+	case Keep:
+		state.hasPIITag, state.redact = false, false
+	}
+	return state
+}
+
+func (s *scrubber) parseRecursive(copy, original reflect.Value, state parseState) error {
+
+	if state.rules != nil {
+		state = s.applyFieldRules(original, state)
+	}
 
 	switch original.Kind() {
 	// The first cases handle nested structures and parse them recursively
@@ -47,7 +118,7 @@ func (s *scrubber) parseRecursive(copy, original reflect.Value, hasPIITag bool)
 		// Allocate a new object and set the pointer to it
 		copy.Set(reflect.New(originalValue.Type()))
 		// Unwrap the newly created pointer
-		if err := s.parseRecursive(copy.Elem(), originalValue, hasPIITag); err != nil {
+		if err := s.parseRecursive(copy.Elem(), originalValue, state); err != nil {
 			return err
 		}
 
@@ -58,10 +129,20 @@ func (s *scrubber) parseRecursive(copy, original reflect.Value, hasPIITag bool)
 	case reflect.Interface:
 		// Get rid of the wrapping interface
 		originalValue := original.Elem()
+		// Check if the interface is nil
+		if !originalValue.IsValid() {
+			return nil
+		}
+		// Redacted scalars are replaced with the placeholder when the
+		// interface can hold it, e.g. numbers in a map[string]interface{}
+		if state.redact && isScalarKind(originalValue.Kind()) && _redactedType.AssignableTo(copy.Type()) {
+			copy.Set(reflect.ValueOf(_redactedValue))
+			return nil
+		}
 		// Create a new object. Now new gives us a pointer, but we want the value it
 		// points to, so we have to call Elem() to unwrap it
 		copyValue := reflect.New(originalValue.Type()).Elem()
-		if err := s.parseRecursive(copyValue, originalValue, hasPIITag); err != nil {
+		if err := s.parseRecursive(copyValue, originalValue, state); err != nil {
 			return err
 		}
 		copy.Set(copyValue)
@@ -70,13 +151,26 @@ func (s *scrubber) parseRecursive(copy, original reflect.Value, hasPIITag bool)
 	case reflect.Struct:
 		t := original.Type()
 
+		// Unexported fields can't be set one by one, start from a shallow copy
+		// so that they are carried over as they are. Tagged ones would be
+		// carried over unscrubbed, which is an error rather than a leak
+		copy.Set(original)
+
 		for i := 0; i < original.NumField(); i++ {
-			piiTagForField := hasPIITag
+			if !t.Field(i).IsExported() {
+				if t.Field(i).Tag.Get(_piiTag) == _piiTagValueTrue {
+					return fmt.Errorf("%w: %v.%v", ErrUnexportedPIIField, t, t.Field(i).Name)
+				}
+				continue
+			}
+			fieldState := state
+			fieldState.path = fieldPath(state.path, t.Field(i).Name)
+			fieldState.anchors = stepRuleAnchors(state.anchors, t.Field(i).Name)
 			tagVal := t.Field(i).Tag.Get(_piiTag)
-			if tagVal == "true" {
-				piiTagForField = true
+			if tagVal == _piiTagValueTrue {
+				fieldState.hasPIITag = true
 			}
-			if err := s.parseRecursive(copy.Field(i), original.Field(i), piiTagForField); err != nil {
+			if err := s.parseRecursive(copy.Field(i), original.Field(i), fieldState); err != nil {
 				return err
 			}
 		}
@@ -85,7 +179,19 @@ func (s *scrubber) parseRecursive(copy, original reflect.Value, hasPIITag bool)
 	case reflect.Slice:
 		copy.Set(reflect.MakeSlice(original.Type(), original.Len(), original.Cap()))
 		for i := 0; i < original.Len(); i++ {
-			if err := s.parseRecursive(copy.Index(i), original.Index(i), hasPIITag); err != nil {
+			elemState := state
+			elemState.path = indexPath(state.path, i)
+			if err := s.parseRecursive(copy.Index(i), original.Index(i), elemState); err != nil {
+				return err
+			}
+		}
+
+	// If it is an array we parse each element of the copy in place
+	case reflect.Array:
+		for i := 0; i < original.Len(); i++ {
+			elemState := state
+			elemState.path = indexPath(state.path, i)
+			if err := s.parseRecursive(copy.Index(i), original.Index(i), elemState); err != nil {
 				return err
 			}
 		}
@@ -93,14 +199,42 @@ func (s *scrubber) parseRecursive(copy, original reflect.Value, hasPIITag bool)
 	// If it is a map we create a new map and parse each value
 	case reflect.Map:
 		copy.Set(reflect.MakeMap(original.Type()))
-		for _, key := range original.MapKeys() {
+		keys := original.MapKeys()
+		if s.scrubMapKeys && original.Type().Key().Kind() == reflect.String {
+			// scrubbed keys may collide, sort so that the suffixes added to
+			// resolve the collisions are stable
+			sort.Slice(keys, func(i, j int) bool {
+				return keys[i].String() < keys[j].String()
+			})
+		}
+		reportKeys, err := s.reportMapKeys(keys, state)
+		if err != nil {
+			return err
+		}
+		for i, key := range keys {
 			originalValue := original.MapIndex(key)
 			// New gives us a pointer, but again we want the value
 			copyValue := reflect.New(originalValue.Type()).Elem()
-			if err := s.parseRecursive(copyValue, originalValue, hasPIITag); err != nil {
+			copyKey, err := s.parseMapKey(copy, key, state)
+			if err != nil {
+				return err
+			}
+			valueState := state
+			valueState.path = mapKeyPath(state.path, copyKey)
+			if reportKeys != nil && copyKey.Interface() == key.Interface() {
+				// the key is kept in the copy, but not in the report
+				valueState.path = state.path + reportKeys[i]
+			}
+			valueState.anchors = stepRuleAnchors(state.anchors, mapKeySegment(key))
+			if key.Kind() == reflect.String {
+				if action, ok := matchKeyRules(s.keyRules, key.String()); ok {
+					valueState = valueState.withAction(action)
+				}
+			}
+			if err := s.parseRecursive(copyValue, originalValue, valueState); err != nil {
 				return err
 			}
-			copy.SetMapIndex(key, copyValue)
+			copy.SetMapIndex(copyKey, copyValue)
 		}
 
 	// Otherwise we cannot traverse anywhere so this finishes the the recursion
@@ -110,19 +244,99 @@ func (s *scrubber) parseRecursive(copy, original reflect.Value, hasPIITag bool)
 		// TODO: this is not optimal way to do it
 		// In future figure out way to update all the string fields at once
 		text := original.String()
-		if hasPIITag {
-			scrubbedTexts, err := s.ScrubTexts([]string{text})
+		if state.redact {
+			text = _redactedValue
+		} else if state.hasPIITag {
+			scrubbedTexts, findings, err := s.scrubTexts([]string{text})
 			if err != nil {
 				return err
 			}
 			text = scrubbedTexts[0]
+			if state.report != nil && len(findings[0]) > 0 {
+				*state.report = append(*state.report, FieldReport{
+					Path:     state.path,
+					Findings: findings[0],
+				})
+			}
 		}
 		copy.SetString(text)
 
-	// And everything else will simply be taken from the original
+	// And everything else will simply be taken from the original, redacted
+	// values are zeroed since they can't hold the placeholder
 	default:
+		if state.redact {
+			copy.Set(reflect.Zero(original.Type()))
+			return nil
+		}
 		copy.Set(original)
 	}
 
 	return nil
 }
+
+var _redactedType = reflect.TypeOf(_redactedValue)
+
+// isScalarKind reports whether values of kind hold a single value that
+// parseRecursive copies as it is
+func isScalarKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	}
+	return false
+}
+
+// reportMapKeys returns the path segments of keys with the PII in them
+// scrubbed, so that reports can be logged even when maps keep their keys.
+// nil when no report is wanted
+func (s *scrubber) reportMapKeys(keys []reflect.Value, state parseState) ([]string, error) {
+	if state.report == nil || len(keys) == 0 {
+		return nil, nil
+	}
+
+	segments := make([]string, len(keys))
+	for i, key := range keys {
+		segments[i] = mapKeyPath("", key)
+	}
+	scrubbedTexts, _, err := s.scrubTexts(segments)
+	if err != nil {
+		return nil, err
+	}
+	return scrubbedTexts, nil
+}
+
+// parseMapKey returns the key to use in the copied map. String keys in
+// pii tagged maps are scrubbed when the scrubber was asked to, other keys are
+// returned as they are
+func (s *scrubber) parseMapKey(copy, key reflect.Value, state parseState) (reflect.Value, error) {
+	if !s.scrubMapKeys || key.Kind() != reflect.String || !(state.hasPIITag || state.redact) {
+		return key, nil
+	}
+
+	scrubbedTexts, findings, err := s.scrubTexts([]string{key.String()})
+	if err != nil {
+		return key, err
+	}
+	if len(findings[0]) == 0 {
+		return key, nil
+	}
+
+	copyKey := reflect.New(key.Type()).Elem()
+	copyKey.SetString(scrubbedTexts[0])
+	// different keys may scrub to the same text, e.g. two email addresses
+	for i := 2; copy.MapIndex(copyKey).IsValid(); i++ {
+		copyKey.SetString(fmt.Sprintf("%v (%d)", scrubbedTexts[0], i))
+	}
+
+	if state.report != nil {
+		*state.report = append(*state.report, FieldReport{
+			Path:     mapKeyPath(state.path, copyKey),
+			InKey:    true,
+			Findings: findings[0],
+		})
+	}
+	return copyKey, nil
+}
@@ -0,0 +1,45 @@
+package test
+
+import (
+	"testing"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/aavaz-ai/pii-scrubber/tests/codegen"
+)
+
+var _codegenUser = codegen.User{
+	Name:     "Anshal +9140528009",
+	Position: "Software Engineer",
+	Address: &codegen.Address{
+		Location: "My 488-23-3729",
+		ZipCode:  "22132",
+	},
+	Email: "abc@gmail.com",
+	Notes: []string{"call +9140528009", "write to abc@gmail.com", "nothing"},
+	Profile: codegen.Profile{
+		Bio:      "write to abc@gmail.com",
+		Contacts: []codegen.Email{"abc@gmail.com", "def@gmail.com"},
+	},
+	Extra: "488-23-3729",
+}
+
+func Benchmark_ScrubStruct(b *testing.B) {
+	scrubber, _ := piiscrubber.NewDefaultScrubber()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := scrubber.ScrubStruct(_codegenUser); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_GeneratedScrubPII(b *testing.B) {
+	scrubber, _ := piiscrubber.NewDefaultScrubber()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		user := _codegenUser
+		if err := user.ScrubPII(scrubber); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+)
+
+// _dictionaryTerms is the number of names in the benchmark dictionary
+const _dictionaryTerms = 50000
+
+func Benchmark_DictionaryEntityScrubber_Match(b *testing.B) {
+	terms := make([]string, 0, _dictionaryTerms)
+	for i := 0; i < _dictionaryTerms; i++ {
+		terms = append(terms, fmt.Sprintf("Customer %d Holdings", i))
+	}
+	s := piiscrubber.NewDictionaryEntityScrubber(terms, &piiscrubber.DictionaryEntityOptions{Fuzzy: true})
+	text := strings.Repeat("Our account manager met customer 4242 holdings and customer 123-holdings today. ", 100)
+
+	b.SetBytes(int64(len(text)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if len(s.Match(text)) != 200 {
+			b.Fatal("unexpected number of matches")
+		}
+	}
+}
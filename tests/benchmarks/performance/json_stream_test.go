@@ -0,0 +1,94 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+)
+
+// _jsonRecords is the number of records in the benchmark inputs
+const _jsonRecords = 1000
+
+func jsonBenchmarkRecord(i int) string {
+	return fmt.Sprintf(`{"id":%d,"name":"User %d","email":"user%d@example.com","password":"hunter%d",`+
+		`"address":{"street":"1561 Duis Rd.","city":"Pomona","phone":"(750) 558-3965"},`+
+		`"orders":[{"sku":"A-%d","qty":2,"price":19.99},{"sku":"B-%d","qty":1,"price":5.00}],`+
+		`"note":"call me at +919140528009 after 5pm"}`, i, i, i, i, i, i)
+}
+
+func jsonBenchmarkPolicy(b *testing.B, rules ...piiscrubber.JSONRule) piiscrubber.JSONPolicy {
+	scrubber, err := piiscrubber.NewDefaultScrubber()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return piiscrubber.JSONPolicy{Scrubber: scrubber, Rules: rules}
+}
+
+func Benchmark_ScrubNDJSON(b *testing.B) {
+	var input bytes.Buffer
+	for i := 0; i < _jsonRecords; i++ {
+		input.WriteString(jsonBenchmarkRecord(i))
+		input.WriteByte('\n')
+	}
+	policy := jsonBenchmarkPolicy(b,
+		piiscrubber.JSONRule{Path: "$.orders", Action: piiscrubber.Keep},
+		piiscrubber.JSONRule{Path: "$.password", Action: piiscrubber.Drop},
+	)
+
+	b.SetBytes(int64(input.Len()))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if err := piiscrubber.ScrubNDJSON(bytes.NewReader(input.Bytes()), io.Discard, policy); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_ScrubJSONStream_Array(b *testing.B) {
+	var input bytes.Buffer
+	input.WriteByte('[')
+	for i := 0; i < _jsonRecords; i++ {
+		if i > 0 {
+			input.WriteByte(',')
+		}
+		input.WriteString(jsonBenchmarkRecord(i))
+	}
+	input.WriteByte(']')
+	policy := jsonBenchmarkPolicy(b,
+		piiscrubber.JSONRule{Path: "$[*].orders", Action: piiscrubber.Keep},
+		piiscrubber.JSONRule{Path: "$..password", Action: piiscrubber.Drop},
+	)
+
+	b.SetBytes(int64(input.Len()))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if err := piiscrubber.ScrubJSONStream(bytes.NewReader(input.Bytes()), io.Discard, policy); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Benchmark_ScrubNDJSON_KeepAll measures the overhead of the stream itself,
+// no string is scanned
+func Benchmark_ScrubNDJSON_KeepAll(b *testing.B) {
+	var input bytes.Buffer
+	for i := 0; i < _jsonRecords; i++ {
+		input.WriteString(jsonBenchmarkRecord(i))
+		input.WriteByte('\n')
+	}
+	policy := jsonBenchmarkPolicy(b, piiscrubber.JSONRule{Path: "$", Action: piiscrubber.Keep})
+
+	b.SetBytes(int64(input.Len()))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if err := piiscrubber.ScrubNDJSON(bytes.NewReader(input.Bytes()), io.Discard, policy); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
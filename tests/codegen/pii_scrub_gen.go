@@ -0,0 +1,147 @@
+// Code generated by pii-scrubgen. DO NOT EDIT.
+
+package codegen
+
+import (
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+)
+
+// ScrubPII scrubs the pii tagged fields of v in place, with the same result
+// as ScrubStruct. Slices, maps and pointers are replaced by scrubbed copies,
+// so values shared with other copies of v are left untouched
+func (v *Profile) ScrubPII(s piiscrubber.Scrubber) error {
+	if piiscrubber.NeedsScrubStruct(s) {
+		copy, err := s.ScrubStruct(*v)
+		if err != nil {
+			return err
+		}
+		*v = copy.(Profile)
+		return nil
+	}
+	var batch piiscrubber.TextBatch
+	if err := v.scrubPIICollect(s, &batch, false); err != nil {
+		return err
+	}
+	return batch.Scrub(s)
+}
+
+// ScrubPII scrubs the pii tagged fields of v in place, with the same result
+// as ScrubStruct. Slices, maps and pointers are replaced by scrubbed copies,
+// so values shared with other copies of v are left untouched
+func (v *User) ScrubPII(s piiscrubber.Scrubber) error {
+	if piiscrubber.NeedsScrubStruct(s) {
+		copy, err := s.ScrubStruct(*v)
+		if err != nil {
+			return err
+		}
+		*v = copy.(User)
+		return nil
+	}
+	var batch piiscrubber.TextBatch
+	if err := v.scrubPIICollect(s, &batch, false); err != nil {
+		return err
+	}
+	return batch.Scrub(s)
+}
+
+func (v *Address) scrubPIICollect(s piiscrubber.Scrubber, batch *piiscrubber.TextBatch, scoped bool) error {
+	if scoped {
+		batch.Add(&v.Location)
+	}
+	if scoped {
+		batch.Add(&v.ZipCode)
+	}
+	return nil
+}
+
+func (v *Profile) scrubPIICollect(s piiscrubber.Scrubber, batch *piiscrubber.TextBatch, scoped bool) error {
+	batch.Add(&v.Bio)
+	if scoped {
+		batch.Add(&v.Website)
+	}
+	c1 := make([]Email, len(v.Contacts), cap(v.Contacts))
+	copy(c1, v.Contacts)
+	for c2 := range c1 {
+		batch.Add((*string)(&c1[c2]))
+	}
+	v.Contacts = c1
+	return nil
+}
+
+func (v *User) scrubPIICollect(s piiscrubber.Scrubber, batch *piiscrubber.TextBatch, scoped bool) error {
+	batch.Add(&v.Name)
+	c1, err := piiscrubber.ScrubValue(s, v.CustomAttributes, true)
+	if err != nil {
+		return err
+	}
+	v.CustomAttributes = c1.(map[string]string)
+	if scoped {
+		batch.Add(&v.Position)
+	}
+	if v.Address != nil {
+		c2 := *v.Address
+		if err := c2.scrubPIICollect(s, batch, true); err != nil {
+			return err
+		}
+		v.Address = &c2
+	}
+	batch.Add((*string)(&v.Email))
+	c3 := make([]string, len(v.Notes), cap(v.Notes))
+	copy(c3, v.Notes)
+	for c4 := range c3 {
+		batch.Add(&c3[c4])
+	}
+	v.Notes = c3
+	c5 := make(Tags, len(v.Tags), cap(v.Tags))
+	copy(c5, v.Tags)
+	for c6 := range c5 {
+		if scoped {
+			batch.Add(&c5[c6])
+		}
+	}
+	v.Tags = c5
+	if err := v.Profile.scrubPIICollect(s, batch, scoped); err != nil {
+		return err
+	}
+	c7 := make([]*Address, len(v.Previous), cap(v.Previous))
+	copy(c7, v.Previous)
+	for c8 := range c7 {
+		if c7[c8] != nil {
+			c9 := *c7[c8]
+			if err := c9.scrubPIICollect(s, batch, true); err != nil {
+				return err
+			}
+			c7[c8] = &c9
+		}
+	}
+	v.Previous = c7
+	c10, err := piiscrubber.ScrubValue(s, v.Extra, true)
+	if err != nil {
+		return err
+	}
+	if c10 != nil {
+		v.Extra = c10.(interface{})
+	}
+	c11, err := piiscrubber.ScrubValue(s, v.Scores, scoped)
+	if err != nil {
+		return err
+	}
+	v.Scores = c11.(map[string]int)
+	for c12 := range v.Codes {
+		batch.Add(&v.Codes[c12])
+	}
+	if v.Nickname != nil {
+		c13 := *v.Nickname
+		batch.Add(&c13)
+		v.Nickname = &c13
+	}
+	c14 := make([]Address, len(v.Addresses), cap(v.Addresses))
+	copy(c14, v.Addresses)
+	for c15 := range c14 {
+		if err := c14[c15].scrubPIICollect(s, batch, scoped); err != nil {
+			return err
+		}
+	}
+	v.Addresses = c14
+	return nil
+}
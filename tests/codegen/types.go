@@ -0,0 +1,44 @@
+// Package codegen holds the structs used to check that the code generated by
+// cmd/pii-scrubgen matches ScrubStruct
+package codegen
+
+//go:generate go run ../../cmd/pii-scrubgen
+
+// Email ...
+type Email string
+
+// Tags ...
+type Tags []string
+
+// Address ...
+type Address struct {
+	Location string
+	ZipCode  string
+	Floor    int
+}
+
+// Profile ...
+type Profile struct {
+	Bio      string `pii:"true"`
+	Website  string
+	Contacts []Email `pii:"true"`
+}
+
+// User ...
+type User struct {
+	Name             string            `pii:"true"`
+	CustomAttributes map[string]string `pii:"true"`
+	Age              int
+	Position         string
+	Address          *Address `pii:"true"`
+	Email            Email    `pii:"true"`
+	Notes            []string `pii:"true"`
+	Tags             Tags
+	Profile          Profile
+	Previous         []*Address  `pii:"true"`
+	Extra            interface{} `pii:"true"`
+	Scores           map[string]int
+	Codes            [2]string `pii:"true"`
+	Nickname         *string   `pii:"true"`
+	Addresses        []Address
+}
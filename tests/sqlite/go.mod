@@ -0,0 +1,27 @@
+module github.com/aavaz-ai/pii-scrubber/tests/sqlite
+
+go 1.21
+
+require (
+	github.com/aavaz-ai/pii-scrubber v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.8.1
+	modernc.org/sqlite v1.34.5
+)
+
+require (
+	github.com/anshal21/go-worker v1.1.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/dustin/go-humanize v1.0.1 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/ncruces/go-strftime v0.1.9 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
+	golang.org/x/sys v0.22.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+	modernc.org/libc v1.55.3 // indirect
+	modernc.org/mathutil v1.6.0 // indirect
+	modernc.org/memory v1.8.0 // indirect
+)
+
+replace github.com/aavaz-ai/pii-scrubber => ../../
@@ -0,0 +1,253 @@
+package test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/stretchr/testify/assert"
+	"modernc.org/sqlite"
+)
+
+const _schema = `
+CREATE TABLE users (
+	id INTEGER PRIMARY KEY,
+	name TEXT,
+	email TEXT UNIQUE,
+	bio BLOB,
+	password TEXT,
+	age INTEGER
+);
+CREATE TABLE events (id INTEGER PRIMARY KEY, payload TEXT);
+CREATE TABLE audit (id INTEGER PRIMARY KEY, payload TEXT);
+`
+
+func newTestScrubber(t *testing.T) piiscrubber.Scrubber {
+	scrubber, err := piiscrubber.New(piiscrubber.Params{
+		BlacklistedEntities: []piiscrubber.Entity{
+			piiscrubber.Email,
+			piiscrubber.Phone,
+		},
+	})
+	assert.NoError(t, err)
+	return scrubber
+}
+
+func openTestDB(t *testing.T, driverName string) *sql.DB {
+	db, err := sql.Open(driverName, "file::memory:")
+	assert.NoError(t, err)
+	// every connection to :memory: is a database of its own
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(_schema)
+	assert.NoError(t, err)
+	return db
+}
+
+func Test_ScrubbedString(t *testing.T) {
+	db := openTestDB(t, "sqlite")
+
+	piiscrubber.SetSQLScrubber(nil)
+	_, err := db.Exec("INSERT INTO events (payload) VALUES (?)", piiscrubber.ScrubbedString("jane@example.com"))
+	assert.ErrorIs(t, err, piiscrubber.ErrNoSQLScrubber)
+
+	piiscrubber.SetSQLScrubber(newTestScrubber(t))
+	defer piiscrubber.SetSQLScrubber(nil)
+
+	_, err = db.Exec("INSERT INTO events (id, payload) VALUES (1, ?), (2, ?)",
+		piiscrubber.ScrubbedString("signup by jane@example.com"),
+		piiscrubber.NullScrubbedString{},
+	)
+	assert.NoError(t, err)
+
+	var payload piiscrubber.ScrubbedString
+	assert.NoError(t, db.QueryRow("SELECT payload FROM events WHERE id = 1").Scan(&payload))
+	assert.Equal(t, piiscrubber.ScrubbedString("signup by <EMAIL_ADDRESS>"), payload)
+
+	var nullPayload piiscrubber.NullScrubbedString
+	assert.NoError(t, db.QueryRow("SELECT payload FROM events WHERE id = 2").Scan(&nullPayload))
+	assert.False(t, nullPayload.Valid)
+}
+
+var _registered = false
+
+func openScrubbedDB(t *testing.T) *sql.DB {
+	if !_registered {
+		d, err := piiscrubber.WrapSQLDriver(&sqlite.Driver{}, newTestScrubber(t), &piiscrubber.SQLDriverOptions{
+			Columns: map[string]piiscrubber.Action{
+				"users.email":       piiscrubber.Scan,
+				"users.bio":         piiscrubber.Scan,
+				"main.users.name":   piiscrubber.Scan,
+				"users.password":    piiscrubber.Redact,
+				"events.*":          piiscrubber.Scan,
+				"events.id":         piiscrubber.Keep,
+				"analytics.users.x": piiscrubber.Keep,
+			},
+		})
+		assert.NoError(t, err)
+		sql.Register("sqlite-scrubbed", d)
+		_registered = true
+	}
+	return openTestDB(t, "sqlite-scrubbed")
+}
+
+type user struct {
+	Name     string
+	Email    string
+	Bio      []byte
+	Password string
+	Age      int
+}
+
+func queryUser(t *testing.T, db *sql.DB, id int) user {
+	var u user
+	err := db.QueryRow(`SELECT coalesce(name, ''), coalesce(email, ''), bio, coalesce(password, ''), coalesce(age, 0)
+		FROM users WHERE id = ?`, id).
+		Scan(&u.Name, &u.Email, &u.Bio, &u.Password, &u.Age)
+	assert.NoError(t, err)
+	return u
+}
+
+func Test_SQLDriver_ScrubsBoundColumns(t *testing.T) {
+	db := openScrubbedDB(t)
+
+	_, err := db.Exec(`INSERT INTO "users" (id, name, email, bio, password, age) VALUES (1, ?, lower(?), ?, ?, ?)`,
+		"Jane +919140520809", "Jane@Example.com", []byte("mail jane@example.com"), "hunter2", 30)
+	assert.NoError(t, err)
+	assert.Equal(t, user{
+		Name:     "Jane <PHONE_NUMBER>",
+		Email:    "<email_address>",
+		Bio:      []byte("mail <EMAIL_ADDRESS>"),
+		Password: "<REDACTED>",
+		Age:      30,
+	}, queryUser(t, db, 1))
+
+	// named parameters, in a different order than the columns
+	_, err = db.Exec("INSERT INTO users (id, name, email) VALUES (2, :name, :email), (3, @name, 'x')",
+		sql.Named("email", "john@example.com"),
+		sql.Named("name", "John"),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "<EMAIL_ADDRESS>", queryUser(t, db, 2).Email)
+	assert.Equal(t, "John", queryUser(t, db, 2).Name)
+
+	// parameters outside of the assignments, e.g. in WHERE, are left as they are
+	_, err = db.Exec("UPDATE users AS u SET email = ?2, age = ?3 WHERE u.id = ?1", 2, "john@example.org", 41)
+	assert.NoError(t, err)
+	assert.Equal(t, "<EMAIL_ADDRESS>", queryUser(t, db, 2).Email)
+	assert.Equal(t, 41, queryUser(t, db, 2).Age)
+
+	_, err = db.Exec(`INSERT INTO users (id, email) VALUES (2, ?)
+		ON CONFLICT (id) DO UPDATE SET name = ?, password = ? RETURNING id`,
+		"john@example.com", "call +919140520809", "secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "call <PHONE_NUMBER>", queryUser(t, db, 2).Name)
+	assert.Equal(t, "<REDACTED>", queryUser(t, db, 2).Password)
+
+	// reads are not rewritten
+	var count int
+	assert.NoError(t, db.QueryRow("SELECT count(*) FROM users WHERE name = ?", "call <PHONE_NUMBER>").Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+func Test_SQLDriver_PreparedStatementsAndTransactions(t *testing.T) {
+	db := openScrubbedDB(t)
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	assert.NoError(t, err)
+	stmt, err := tx.Prepare("WITH x AS (SELECT ? AS v) INSERT INTO events (id, payload) VALUES (?, ?)")
+	assert.NoError(t, err)
+	for i, payload := range []string{"jane@example.com", "john@example.com"} {
+		_, err = stmt.Exec("jane@example.com", i+1, payload)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, stmt.Close())
+	assert.NoError(t, tx.Commit())
+
+	// tables without policies are written as they are
+	_, err = db.Exec("INSERT INTO audit (payload) VALUES (?)", "jane@example.com")
+	assert.NoError(t, err)
+	_, err = db.Exec("UPDATE events SET payload = ? WHERE id = 2", "+919140520809")
+	assert.NoError(t, err)
+
+	rows, err := db.Query("SELECT payload FROM events UNION ALL SELECT payload FROM audit")
+	assert.NoError(t, err)
+	defer rows.Close()
+	var payloads []string
+	for rows.Next() {
+		var payload string
+		assert.NoError(t, rows.Scan(&payload))
+		payloads = append(payloads, payload)
+	}
+	assert.ElementsMatch(t, []string{"<EMAIL_ADDRESS>", "<PHONE_NUMBER>", "jane@example.com"}, payloads)
+}
+
+func Test_SQLDriver_RejectsUnmappedWrites(t *testing.T) {
+	db := openScrubbedDB(t)
+
+	for _, query := range []string{
+		"INSERT INTO users VALUES (1, ?, ?, NULL, NULL, 30)",
+		"INSERT INTO users (name, email) SELECT ?, ?",
+		"UPDATE users SET (name, email) = (?, ?)",
+		// drivers number the parameters of several statements differently
+		"INSERT INTO audit (payload) VALUES (?); UPDATE users SET name = ?",
+		// string literals can't be mapped to columns either
+		"INSERT INTO users VALUES (1, 'Jane', 'jane@example.com', NULL, NULL, 30)",
+		"INSERT INTO users (name, email) SELECT 'Jane', 'jane@example.com'",
+	} {
+		_, err := db.Exec(query, "Jane", "jane@example.com")
+		assert.True(t, errors.Is(err, piiscrubber.ErrUnmappedSQLParameters), "%v: %v", query, err)
+
+		_, err = db.Prepare(query)
+		assert.True(t, errors.Is(err, piiscrubber.ErrUnmappedSQLParameters), "%v: %v", query, err)
+	}
+
+	// without parameters or string literals there is nothing to map
+	_, err := db.Exec("INSERT INTO users VALUES (1, NULL, NULL, NULL, NULL, 30)")
+	assert.NoError(t, err)
+	_, err = db.Exec("INSERT INTO users (id, name) SELECT id + 1, name FROM users")
+	assert.NoError(t, err)
+}
+
+func Test_SQLDriver_ScrubsBoundLiterals(t *testing.T) {
+	db := openScrubbedDB(t)
+
+	_, err := db.Exec(`INSERT INTO users (id, name, email, password, age) VALUES (1, 'O''Brien +919140520809', lower('Jane@Example.com'), 'hunter2', 30)`)
+	assert.NoError(t, err)
+	assert.Equal(t, user{
+		Name:     "O'Brien <PHONE_NUMBER>",
+		Email:    "<email_address>",
+		Password: "<REDACTED>",
+		Age:      30,
+	}, queryUser(t, db, 1))
+
+	// literals outside of the assignments, e.g. in WHERE, are left as they are
+	_, err = db.Exec("UPDATE users SET email = 'jane@example.org', name = ? WHERE email = '<email_address>'", "Jane")
+	assert.NoError(t, err)
+	assert.Equal(t, "<EMAIL_ADDRESS>", queryUser(t, db, 1).Email)
+	assert.Equal(t, "Jane", queryUser(t, db, 1).Name)
+
+	stmt, err := db.Prepare("INSERT INTO events (id, payload) VALUES (2, 'call +919140520809')")
+	assert.NoError(t, err)
+	_, err = stmt.Exec()
+	assert.NoError(t, err)
+	assert.NoError(t, stmt.Close())
+
+	var payload string
+	assert.NoError(t, db.QueryRow("SELECT payload FROM events WHERE id = 2").Scan(&payload))
+	assert.Equal(t, "call <PHONE_NUMBER>", payload)
+}
+
+func Test_WrapSQLDriver_InvalidOptions(t *testing.T) {
+	for _, columns := range []map[string]piiscrubber.Action{
+		{"email": piiscrubber.Scan},
+		{"users.": piiscrubber.Scan},
+		{"users.email": "hash"},
+	} {
+		_, err := piiscrubber.WrapSQLDriver(&sqlite.Driver{}, newTestScrubber(t), &piiscrubber.SQLDriverOptions{Columns: columns})
+		assert.Error(t, err, "%v", columns)
+	}
+}
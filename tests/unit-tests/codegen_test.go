@@ -0,0 +1,121 @@
+package test
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/aavaz-ai/pii-scrubber/internal/scrubgen"
+	"github.com/aavaz-ai/pii-scrubber/tests/codegen"
+	"github.com/stretchr/testify/assert"
+)
+
+func sampleCodegenUser() codegen.User {
+	nickname := "jj 488-23-3729"
+	return codegen.User{
+		Name: "Anshal +9140528009",
+		CustomAttributes: map[string]string{
+			"PIIKey": "Hello here is my credit card 6011553157232994",
+		},
+		Age:      10,
+		Position: "Software Engineer abc@gmail.com",
+		Address: &codegen.Address{
+			Location: "My 488-23-3729",
+			ZipCode:  "22132",
+			Floor:    3,
+		},
+		Email: "abc@gmail.com",
+		Notes: []string{"call +9140528009", "nothing"},
+		Tags:  codegen.Tags{"abc@gmail.com"},
+		Profile: codegen.Profile{
+			Bio:      "write to abc@gmail.com",
+			Website:  "abc@gmail.com",
+			Contacts: []codegen.Email{"abc@gmail.com", "def@gmail.com"},
+		},
+		Previous: []*codegen.Address{{Location: "488-23-3729"}, nil},
+		Extra: map[string]interface{}{
+			"nested": []interface{}{"abc@gmail.com"},
+		},
+		Scores:    map[string]int{"abc@gmail.com": 1},
+		Codes:     [2]string{"abc@gmail.com", ""},
+		Nickname:  &nickname,
+		Addresses: []codegen.Address{{Location: "abc@gmail.com"}},
+	}
+}
+
+func Test_GeneratedScrubPII_MatchesScrubStruct(t *testing.T) {
+	scrubber, err := piiscrubber.New(piiscrubber.Params{
+		BlacklistedEntities: []piiscrubber.Entity{
+			piiscrubber.CreditCard,
+			piiscrubber.Phone,
+			piiscrubber.Email,
+			piiscrubber.SSN,
+			piiscrubber.ZipCode,
+		},
+	})
+	assert.NoError(t, err)
+
+	original := sampleCodegenUser()
+
+	expected, err := scrubber.ScrubStruct(original)
+	assert.NoError(t, err)
+
+	generated := original
+	assert.NoError(t, generated.ScrubPII(scrubber))
+	assert.Equal(t, expected, generated)
+	assert.Equal(t, [2]string{"<EMAIL_ADDRESS>", ""}, generated.Codes)
+
+	// the original and the values it shares with the copy are left untouched
+	assert.Equal(t, sampleCodegenUser(), original)
+
+	// nil slices and maps are turned into empty ones, like ScrubStruct does
+	empty := codegen.User{Extra: "abc@gmail.com"}
+	expected, err = scrubber.ScrubStruct(empty)
+	assert.NoError(t, err)
+	assert.NoError(t, empty.ScrubPII(scrubber))
+	assert.Equal(t, expected, empty)
+}
+
+func Test_GeneratedScrubPII_FieldRules(t *testing.T) {
+	scrubber, err := piiscrubber.New(piiscrubber.Params{
+		BlacklistedEntities: []piiscrubber.Entity{
+			piiscrubber.CreditCard,
+			piiscrubber.Phone,
+			piiscrubber.Email,
+			piiscrubber.SSN,
+			piiscrubber.ZipCode,
+		},
+		FieldRules: []piiscrubber.FieldRule{
+			{Path: "Position", Action: piiscrubber.Redact},
+			{Path: "Extra.nested", Action: piiscrubber.Keep},
+			{Type: reflect.TypeOf(codegen.Address{}), Path: "Location", Action: piiscrubber.Keep},
+			{TypeName: "codegen.Profile", Path: "Website", Action: piiscrubber.Redact},
+		},
+	})
+	assert.NoError(t, err)
+
+	original := sampleCodegenUser()
+
+	expected, err := scrubber.ScrubStruct(original)
+	assert.NoError(t, err)
+
+	generated := original
+	assert.NoError(t, generated.ScrubPII(scrubber))
+	assert.Equal(t, expected, generated)
+
+	assert.Equal(t, "<REDACTED>", generated.Position)
+	assert.Equal(t, "<REDACTED>", generated.Profile.Website)
+	assert.Equal(t, "My 488-23-3729", generated.Address.Location)
+	assert.Equal(t, map[string]interface{}{"nested": []interface{}{"abc@gmail.com"}}, generated.Extra)
+}
+
+func Test_GeneratedScrubPII_UpToDate(t *testing.T) {
+	source, err := scrubgen.Generate("../codegen", nil, scrubgen.DefaultOutput)
+	assert.NoError(t, err)
+
+	checkedIn, err := os.ReadFile("../codegen/" + scrubgen.DefaultOutput)
+	assert.NoError(t, err)
+
+	assert.Equal(t, string(checkedIn), string(source), "run go generate ./tests/codegen/...")
+}
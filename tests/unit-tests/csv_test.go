@@ -0,0 +1,188 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ScrubCSV_Rules(t *testing.T) {
+	policy := piiscrubber.CSVPolicy{
+		Scrubber: newJSONTestScrubber(t),
+		Rules: []piiscrubber.CSVColumnRule{
+			{Name: "Name", Action: piiscrubber.Redact},
+			{Name: "customer id", Action: piiscrubber.Hash},
+			{Name: "password", Action: piiscrubber.Drop},
+			{Index: 4, Action: piiscrubber.Keep},
+		},
+		HashKey: []byte("key"),
+	}
+
+	input := "name,Customer ID,password,note,contact\n" +
+		"Jane,42,hunter2,\"call +919140520809\nor mail jane@example.com\",jane@example.com\n" +
+		"John,42,x,\"said \"\"hi\"\", bye\",\n" +
+		"Joe,,y\n"
+	var out bytes.Buffer
+	reports, err := piiscrubber.ScrubCSV(strings.NewReader(input), &out, policy)
+	assert.NoError(t, err)
+
+	hash := piiscrubber.HashRedactor([]byte("key")).Redact("42")
+	assert.Equal(t, "name,Customer ID,note,contact\n"+
+		"<REDACTED>,"+hash+",\"call <PHONE_NUMBER>\nor mail <EMAIL_ADDRESS>\",jane@example.com\n"+
+		"<REDACTED>,"+hash+",\"said \"\"hi\"\", bye\",\n"+
+		"<REDACTED>,\n", out.String())
+
+	assert.Equal(t, []piiscrubber.CSVColumnReport{
+		{Index: 0, Name: "name", Action: piiscrubber.Redact, Values: 3, Changed: 3},
+		{Index: 1, Name: "Customer ID", Action: piiscrubber.Hash, Values: 2, Changed: 2},
+		{Index: 2, Name: "password", Action: piiscrubber.Drop},
+		{Index: 3, Name: "note", Action: piiscrubber.Scan, Values: 2, Changed: 1,
+			Findings: map[piiscrubber.Entity]int{piiscrubber.Email: 1, piiscrubber.Phone: 1}},
+		{Index: 4, Name: "contact", Action: piiscrubber.Keep, Values: 1},
+	}, reports)
+	assert.Equal(t, []piiscrubber.Entity{piiscrubber.Email, piiscrubber.Phone}, reports[3].Entities())
+}
+
+func Test_ScrubCSV_TSVWithoutHeader(t *testing.T) {
+	policy := piiscrubber.CSVPolicy{
+		Scrubber: newJSONTestScrubber(t),
+		Comma:    '\t',
+		Rules:    []piiscrubber.CSVColumnRule{{Index: 0, Action: piiscrubber.Keep}},
+	}
+
+	// the first row has an email in it, so it can't be a header
+	var out bytes.Buffer
+	_, err := piiscrubber.ScrubCSV(strings.NewReader("1\tjane@example.com\n2\tnone, really\n"), &out, policy)
+	assert.NoError(t, err)
+	assert.Equal(t, "1\t<EMAIL_ADDRESS>\n2\tnone, really\n", out.String())
+
+	// rules by name need a header
+	policy.Rules = []piiscrubber.CSVColumnRule{{Name: "email", Action: piiscrubber.Redact}}
+	_, err = piiscrubber.ScrubCSV(strings.NewReader("1\tjane@example.com\n"), &out, policy)
+	assert.Error(t, err)
+}
+
+func Test_ScrubCSV_DataRowLikeHeader(t *testing.T) {
+	policy := piiscrubber.CSVPolicy{
+		Scrubber: newJSONTestScrubber(t),
+		Rules: []piiscrubber.CSVColumnRule{
+			{Index: 0, Action: piiscrubber.Redact},
+			{Index: 1, Action: piiscrubber.Hash},
+			{Index: 2, Action: piiscrubber.Drop},
+		},
+		HashKey: []byte("key"),
+	}
+	hash := piiscrubber.HashRedactor([]byte("key")).Redact
+
+	// the first row is data that looks like a header, the rules by index
+	// apply to it all the same
+	input := "Jane,Doe,secret,Berlin\nJohn,Smith,hidden,Paris\n"
+	var out bytes.Buffer
+	_, err := piiscrubber.ScrubCSV(strings.NewReader(input), &out, policy)
+	assert.NoError(t, err)
+	assert.Equal(t, "<REDACTED>,"+hash("Doe")+",Berlin\n"+
+		"<REDACTED>,"+hash("Smith")+",Paris\n", out.String())
+
+	// a declared header is written as it is
+	policy.Header = piiscrubber.HasCSVHeader
+	out.Reset()
+	_, err = piiscrubber.ScrubCSV(strings.NewReader("first,last,password,city\n"+input), &out, policy)
+	assert.NoError(t, err)
+	assert.Equal(t, "first,last,city\n"+
+		"<REDACTED>,"+hash("Doe")+",Berlin\n"+
+		"<REDACTED>,"+hash("Smith")+",Paris\n", out.String())
+}
+
+// mailScrubber implements only Scrubber and scrubs a single address
+type mailScrubber struct{}
+
+func (mailScrubber) ScrubTexts(texts []string) ([]string, error) {
+	scrubbed := make([]string, 0, len(texts))
+	for _, text := range texts {
+		scrubbed = append(scrubbed, strings.ReplaceAll(text, "jane@example.com", "<EMAIL>"))
+	}
+	return scrubbed, nil
+}
+
+func (mailScrubber) ScrubStruct(obj interface{}) (interface{}, error) {
+	return obj, nil
+}
+
+func Test_ScrubCSV_NoFindingsScrubber(t *testing.T) {
+	policy := piiscrubber.CSVPolicy{Scrubber: mailScrubber{}}
+
+	var out bytes.Buffer
+	reports, err := piiscrubber.ScrubCSV(strings.NewReader("name,contact\nJane,jane@example.com\nJohn,call me\n"), &out, policy)
+	assert.NoError(t, err)
+	assert.Equal(t, "name,contact\nJane,<EMAIL>\nJohn,call me\n", out.String())
+	// the values that changed tell where PII was, but not which entities
+	assert.Equal(t, piiscrubber.CSVColumnReport{Index: 1, Name: "contact", Action: piiscrubber.Scan, Values: 2, Changed: 1}, reports[1])
+
+	// a first row the scrubber changes is data, and the sampled columns it
+	// changes are redacted
+	policy.DetectRows = 10
+	out.Reset()
+	_, err = piiscrubber.ScrubCSV(strings.NewReader("jane@example.com,x\nbob,y\n"), &out, policy)
+	assert.NoError(t, err)
+	assert.Equal(t, "<REDACTED>,x\n<REDACTED>,y\n", out.String())
+}
+
+func Test_ScrubCSV_DetectRows(t *testing.T) {
+	var input strings.Builder
+	input.WriteString("id,contact,comment\n")
+	for i := 0; i < 300; i++ {
+		contact := fmt.Sprintf("user%d@example.com", i)
+		if i >= 10 {
+			// values the scrubber does not detect, but that are in a column
+			// that holds emails
+			contact = fmt.Sprintf("user%d at example dot com", i)
+		}
+		fmt.Fprintf(&input, "%d,%v,fine\n", i, contact)
+	}
+	policy := piiscrubber.CSVPolicy{
+		Scrubber:   newJSONTestScrubber(t),
+		Header:     piiscrubber.HasCSVHeader,
+		DetectRows: 10,
+	}
+
+	reports, err := piiscrubber.DetectCSVColumns(strings.NewReader(input.String()), policy)
+	assert.NoError(t, err)
+	assert.Equal(t, []piiscrubber.CSVColumnReport{
+		{Index: 0, Name: "id", Action: piiscrubber.Scan, Values: 10},
+		{Index: 1, Name: "contact", Action: piiscrubber.Redact, Values: 10,
+			Findings: map[piiscrubber.Entity]int{piiscrubber.Email: 10}},
+		{Index: 2, Name: "comment", Action: piiscrubber.Scan, Values: 10},
+	}, reports)
+
+	var out bytes.Buffer
+	reports, err = piiscrubber.ScrubCSV(strings.NewReader(input.String()), &out, policy)
+	assert.NoError(t, err)
+	lines := strings.Split(out.String(), "\n")
+	assert.Equal(t, "id,contact,comment", lines[0])
+	assert.Equal(t, "0,<REDACTED>,fine", lines[1])
+	assert.Equal(t, "299,<REDACTED>,fine", lines[300])
+	assert.Equal(t, 300, reports[1].Changed)
+}
+
+func Test_ScrubCSV_Errors(t *testing.T) {
+	scrubber := newJSONTestScrubber(t)
+
+	_, err := piiscrubber.ScrubCSV(strings.NewReader("a,b\n1,\"2\n"), &bytes.Buffer{}, piiscrubber.CSVPolicy{Scrubber: scrubber})
+	assert.ErrorIs(t, err, piiscrubber.ErrInvalidCSV)
+
+	for _, policy := range []piiscrubber.CSVPolicy{
+		{},
+		{Scrubber: scrubber, Comma: '"'},
+		{Scrubber: scrubber, Rules: []piiscrubber.CSVColumnRule{{Index: -1, Action: piiscrubber.Keep}}},
+		{Scrubber: scrubber, Rules: []piiscrubber.CSVColumnRule{{Index: 0, Action: piiscrubber.Hash}}},
+		{Scrubber: scrubber, Rules: []piiscrubber.CSVColumnRule{{Index: 0, Action: "mask"}}},
+		{Scrubber: scrubber, Rules: []piiscrubber.CSVColumnRule{{Name: "missing", Action: piiscrubber.Keep}}},
+	} {
+		_, err := piiscrubber.ScrubCSV(strings.NewReader("a,b\n1,2\n"), &bytes.Buffer{}, policy)
+		assert.Error(t, err, policy)
+	}
+}
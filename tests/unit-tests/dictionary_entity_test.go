@@ -0,0 +1,93 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/stretchr/testify/assert"
+)
+
+func matchedTerms(s piiscrubber.EntityScrubber, text string) []string {
+	terms := make([]string, 0)
+	for _, match := range s.Match(text) {
+		terms = append(terms, text[match[0]:match[1]])
+	}
+	return terms
+}
+
+func Test_DictionaryEntityScrubber(t *testing.T) {
+	s := piiscrubber.NewDictionaryEntityScrubber([]string{"Acme", "Acme Corp", "Globex", "Zoë Ångström", ""}, nil)
+
+	assert.Equal(t, []string{"ACME CORP", "globex"}, matchedTerms(s, "ACME CORP bought globex, not Acmeplex or Acme_Corp"))
+	// without Fuzzy whitespace must be the same
+	assert.Equal(t, []string{"Acme"}, matchedTerms(s, "Acme  Corp"))
+	assert.Equal(t, []string{"zoë ångström"}, matchedTerms(s, "ask zoë ångström"))
+	assert.Empty(t, s.Match(""))
+	assert.Equal(t, []byte("<REDACTED>"), s.Mask([]byte("Acme"), nil))
+
+	s = piiscrubber.NewDictionaryEntityScrubber([]string{"Acme"}, &piiscrubber.DictionaryEntityOptions{
+		CaseSensitive:    true,
+		MatchInsideWords: true,
+	})
+	assert.Equal(t, []string{"Acme"}, matchedTerms(s, "ACME Acmeplex"))
+}
+
+func Test_DictionaryEntityScrubber_Fuzzy(t *testing.T) {
+	s := piiscrubber.NewDictionaryEntityScrubber([]string{"Acme, Inc.", "O'Brien"}, &piiscrubber.DictionaryEntityOptions{Fuzzy: true})
+
+	assert.Equal(t, []string{"acme inc", "ACME-Inc", "Acme,  Inc", "O Brien"}, matchedTerms(s, "acme inc, ACME-Inc. and Acme,  Inc! O Brien"))
+	// punctuation can't be left out altogether
+	assert.Empty(t, s.Match("acmeinc obrien"))
+}
+
+func Test_DictionaryEntityScrubber_Scrubber(t *testing.T) {
+	terms, err := piiscrubber.ReadDictionary(strings.NewReader("# customers\nAcme Corp\n\n  Globex  \n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Acme Corp", "Globex"}, terms)
+
+	customers := piiscrubber.NewDictionaryEntityScrubber(terms, &piiscrubber.DictionaryEntityOptions{Placeholder: "<CUSTOMER>"})
+	scrubber, err := piiscrubber.New(piiscrubber.Params{
+		BlacklistedEntities:   []piiscrubber.Entity{piiscrubber.Email, "CUSTOMER"},
+		CustomEntityScrubbers: map[piiscrubber.Entity]piiscrubber.EntityScrubber{"CUSTOMER": customers},
+	})
+	assert.NoError(t, err)
+	scrubbed, err := scrubber.ScrubTexts([]string{"jane@example.com from acme corp and Initech"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"<EMAIL_ADDRESS> from <CUSTOMER> and Initech"}, scrubbed)
+
+	path := filepath.Join(t.TempDir(), "customers.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("Initech\n"), 0644))
+	terms, err = piiscrubber.LoadDictionary(path)
+	assert.NoError(t, err)
+	customers.Update(terms)
+	scrubbed, err = scrubber.ScrubTexts([]string{"jane@example.com from acme corp and Initech"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"<EMAIL_ADDRESS> from acme corp and <CUSTOMER>"}, scrubbed)
+
+	_, err = piiscrubber.LoadDictionary(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+}
+
+func Test_DictionaryEntityScrubber_ConcurrentUpdate(t *testing.T) {
+	s := piiscrubber.NewDictionaryEntityScrubber([]string{"Acme"}, nil)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			s.Update([]string{"Acme", "Globex"})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			assert.Equal(t, []string{"Acme"}, matchedTerms(s, "Acme"))
+		}
+	}()
+	wg.Wait()
+}
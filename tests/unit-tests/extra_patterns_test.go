@@ -0,0 +1,68 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_New_ExtraPatterns(t *testing.T) {
+	// exclusions only apply to their entity, and the digits of the ticket
+	// IDs look like phone numbers as well
+	tickets := []string{`\bTKT-\d{3}-\d{2}-\d{4}\b`}
+	scrubber, err := piiscrubber.New(piiscrubber.Params{
+		BlacklistedEntities: []piiscrubber.Entity{piiscrubber.Phone, piiscrubber.SSN},
+		ExtraPatterns: map[piiscrubber.Entity]piiscrubber.EntityPatterns{
+			// vanity numbers, the default patterns only find their digits
+			piiscrubber.Phone: {Patterns: []string{`\b0800 [A-Z]{7}\b`}, Exclusions: tickets},
+			piiscrubber.SSN:   {Exclusions: tickets},
+		},
+	})
+	assert.NoError(t, err)
+
+	scrubbed, err := scrubber.ScrubTexts([]string{
+		"call 0800 FLOWERS or 01632 960 001",
+		"ticket TKT-123-45-6789 is about 123-45-6789",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"call <PHONE_NUMBER> or <PHONE_NUMBER>",
+		"ticket TKT-123-45-6789 is about <US_SSN>",
+	}, scrubbed)
+}
+
+func Test_New_ExtraPatterns_Validators(t *testing.T) {
+	custom, err := piiscrubber.NewRegexEntityScrubber(`EMP-\d{6}`)
+	assert.NoError(t, err)
+
+	scrubber, err := piiscrubber.New(piiscrubber.Params{
+		BlacklistedEntities:   []piiscrubber.Entity{piiscrubber.SSN, "EMPLOYEE_ID"},
+		CustomEntityScrubbers: map[piiscrubber.Entity]piiscrubber.EntityScrubber{"EMPLOYEE_ID": custom},
+		ExtraPatterns: map[piiscrubber.Entity]piiscrubber.EntityPatterns{
+			// SSNs never start with 000, from the default patterns or the extra ones
+			piiscrubber.SSN: {
+				Patterns:   []string{`\b\d{3} \d{2} \d{4}\b`},
+				Validators: []func(string) bool{func(match string) bool { return !strings.HasPrefix(match, "000") }},
+			},
+			"EMPLOYEE_ID": {Patterns: []string{`E\d{4}`}},
+		},
+	})
+	assert.NoError(t, err)
+
+	scrubbed, err := scrubber.ScrubTexts([]string{"123 45 6789, 000-45-6789, 000 45 6789, EMP-123456 and E1234"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"<US_SSN>, 000-45-6789, 000 45 6789, <REDACTED> and <REDACTED>"}, scrubbed)
+}
+
+func Test_New_ExtraPatterns_Errors(t *testing.T) {
+	for _, patterns := range []map[piiscrubber.Entity]piiscrubber.EntityPatterns{
+		{"UNKNOWN": {Patterns: []string{"x"}}},
+		{piiscrubber.Phone: {Patterns: []string{"("}}},
+		{piiscrubber.Phone: {Exclusions: []string{"("}}},
+	} {
+		_, err := piiscrubber.New(piiscrubber.Params{ExtraPatterns: patterns})
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,124 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/stretchr/testify/assert"
+)
+
+// thirdPartyAddress and thirdPartyCustomer stand in for types we can't tag
+type thirdPartyAddress struct {
+	Line1 string
+	City  string
+}
+
+type thirdPartyCustomer struct {
+	Name        string
+	Description string
+	Address     *thirdPartyAddress
+	Shipping    []thirdPartyAddress
+}
+
+func Test_ScrubStruct_FieldRules(t *testing.T) {
+	scrubber, err := piiscrubber.New(piiscrubber.Params{
+		BlacklistedEntities: []piiscrubber.Entity{
+			piiscrubber.Phone,
+			piiscrubber.Email,
+		},
+		FieldRules: []piiscrubber.FieldRule{
+			{Type: reflect.TypeOf(thirdPartyCustomer{}), Path: "Description", Action: piiscrubber.Scan},
+			{Type: reflect.TypeOf(thirdPartyCustomer{}), Path: "Name", Action: piiscrubber.Redact},
+			{TypeName: "test.thirdPartyAddress", Path: "Line1", Action: piiscrubber.Redact},
+		},
+	})
+	assert.NoError(t, err)
+
+	v := thirdPartyCustomer{
+		Name:        "Jane Doe",
+		Description: "reach me at jane@example.com",
+		Address:     &thirdPartyAddress{Line1: "12 Main Street", City: "Springfield"},
+		Shipping:    []thirdPartyAddress{{Line1: "3 Side Road", City: "Shelbyville"}},
+	}
+
+	response, err := scrubber.ScrubStruct(v)
+	assert.NoError(t, err)
+
+	assert.Equal(t, thirdPartyCustomer{
+		Name:        "<REDACTED>",
+		Description: "reach me at <EMAIL_ADDRESS>",
+		Address:     &thirdPartyAddress{Line1: "<REDACTED>", City: "Springfield"},
+		Shipping:    []thirdPartyAddress{{Line1: "<REDACTED>", City: "Shelbyville"}},
+	}, response)
+
+	// the original is left untouched
+	assert.Equal(t, "Jane Doe", v.Name)
+	assert.Equal(t, "12 Main Street", v.Address.Line1)
+}
+
+func Test_ScrubStruct_FieldRulesOnMaps(t *testing.T) {
+	scrubber, err := piiscrubber.New(piiscrubber.Params{
+		BlacklistedEntities: []piiscrubber.Entity{
+			piiscrubber.Phone,
+			piiscrubber.Email,
+		},
+		FieldRules: []piiscrubber.FieldRule{
+			{Path: "user.**", Action: piiscrubber.Scan},
+			{Path: "user.*.password", Action: piiscrubber.Redact},
+			{Path: "user.profile.public_*", Action: piiscrubber.Keep},
+		},
+	})
+	assert.NoError(t, err)
+
+	v := map[string]interface{}{
+		"user": map[string]interface{}{
+			"profile": map[string]interface{}{
+				"bio":          "call +919140520809",
+				"password":     "hunter2",
+				"public_email": "support@example.com",
+			},
+			"tags": []interface{}{"mail jane@example.com"},
+		},
+		"event": "signup by jane@example.com",
+	}
+
+	response, err := scrubber.ScrubStruct(v)
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"user": map[string]interface{}{
+			"profile": map[string]interface{}{
+				"bio":          "call <PHONE_NUMBER>",
+				"password":     "<REDACTED>",
+				"public_email": "support@example.com",
+			},
+			"tags": []interface{}{"mail <EMAIL_ADDRESS>"},
+		},
+		"event": "signup by jane@example.com",
+	}, response)
+}
+
+func Test_RegisterFieldRule(t *testing.T) {
+	type registeredType struct {
+		Secret string
+		Other  string
+	}
+
+	assert.Error(t, piiscrubber.RegisterFieldRule(reflect.TypeOf(registeredType{}), "Secret", "obliterate"))
+	assert.NoError(t, piiscrubber.RegisterFieldRule(reflect.TypeOf(registeredType{}), "Secret", piiscrubber.Redact))
+
+	scrubber, _ := piiscrubber.NewDefaultScrubber()
+	response, err := scrubber.ScrubStruct(registeredType{Secret: "s3cr3t", Other: "visible"})
+	assert.NoError(t, err)
+	assert.Equal(t, registeredType{Secret: "<REDACTED>", Other: "visible"}, response)
+}
+
+func Test_New_InvalidFieldRule(t *testing.T) {
+	_, err := piiscrubber.New(piiscrubber.Params{
+		FieldRules: []piiscrubber.FieldRule{
+			{Path: "user.[", Action: piiscrubber.Scan},
+		},
+	})
+	assert.Error(t, err)
+}
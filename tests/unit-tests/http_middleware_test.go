@@ -0,0 +1,165 @@
+package test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHTTPScrubber(t *testing.T) piiscrubber.Scrubber {
+	scrubber, err := piiscrubber.New(piiscrubber.Params{
+		BlacklistedEntities: []piiscrubber.Entity{
+			piiscrubber.Email,
+			piiscrubber.Phone,
+		},
+		KeyRules: []piiscrubber.KeyRule{
+			{Pattern: "(?i)^password$", Action: piiscrubber.Redact},
+		},
+	})
+	assert.NoError(t, err)
+	return scrubber
+}
+
+// serve runs a request through the middleware and returns what the handler
+// received, what the client received and the exchange handed to the callback
+func serve(t *testing.T, opts *piiscrubber.HTTPMiddlewareOptions, r *http.Request, handler http.HandlerFunc) (string, *httptest.ResponseRecorder, *piiscrubber.HTTPExchange) {
+	var received string
+	var exchange *piiscrubber.HTTPExchange
+
+	middleware := piiscrubber.HTTPMiddleware(newTestHTTPScrubber(t), func(e *piiscrubber.HTTPExchange) {
+		exchange = e
+	}, opts)
+
+	w := httptest.NewRecorder()
+	middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		received = string(body)
+		handler(w, r)
+	})).ServeHTTP(w, r)
+
+	return received, w, exchange
+}
+
+func Test_HTTPMiddleware_JSON(t *testing.T) {
+	requestBody := `{"email":"jane@example.com","password":"hunter2","age":30}`
+	r := httptest.NewRequest(http.MethodPost, "/users?ref=john@example.com", strings.NewReader(requestBody))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Authorization", "Bearer secret")
+	r.Header.Set("X-Contact", "jane@example.com")
+
+	received, w, exchange := serve(t, nil, r, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Set-Cookie", "session=abc")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"id":1,"contacts":["+919140520809","jane@example.com"]}`)
+	})
+
+	// the handler and the client see everything as it is
+	assert.Equal(t, requestBody, received)
+	assert.Equal(t, `{"id":1,"contacts":["+919140520809","jane@example.com"]}`, w.Body.String())
+	assert.Equal(t, "session=abc", w.Header().Get("Set-Cookie"))
+
+	assert.Equal(t, http.MethodPost, exchange.Method)
+	assert.Equal(t, "/users?ref=%3CEMAIL_ADDRESS%3E", exchange.URL)
+	assert.Equal(t, "<REDACTED>", exchange.Request.Header.Get("Authorization"))
+	assert.Equal(t, "<EMAIL_ADDRESS>", exchange.Request.Header.Get("X-Contact"))
+	assert.Equal(t, `{"email":"<EMAIL_ADDRESS>","password":"<REDACTED>","age":30}`, string(exchange.Request.Body))
+
+	assert.Equal(t, http.StatusCreated, exchange.StatusCode)
+	assert.Equal(t, "<REDACTED>", exchange.Response.Header.Get("Set-Cookie"))
+	assert.Equal(t, `{"id":1,"contacts":["<PHONE_NUMBER>","<EMAIL_ADDRESS>"]}`, string(exchange.Response.Body))
+}
+
+func Test_HTTPMiddleware_FormAndText(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader("user=jane%40example.com&password=hunter2"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	received, _, exchange := serve(t, nil, r, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "welcome jane@example.com")
+	})
+
+	assert.Equal(t, "user=jane%40example.com&password=hunter2", received)
+	assert.Equal(t, "user=%3CEMAIL_ADDRESS%3E&password=%3CREDACTED%3E", string(exchange.Request.Body))
+	assert.Equal(t, http.StatusOK, exchange.StatusCode)
+	assert.Equal(t, "welcome <EMAIL_ADDRESS>", string(exchange.Response.Body))
+}
+
+func Test_HTTPMiddleware_SizeLimit(t *testing.T) {
+	requestBody := `{"password":"hunter2","note":"call jane@example.com or +919140520809"}`
+	r := httptest.NewRequest(http.MethodPost, "/notes", strings.NewReader(requestBody))
+	r.Header.Set("Content-Type", "application/json")
+
+	received, _, exchange := serve(t, &piiscrubber.HTTPMiddlewareOptions{MaxBodySize: 40}, r, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write([]byte{0, 1, 2})
+	})
+
+	// the handler still reads the whole body
+	assert.Equal(t, requestBody, received)
+
+	// truncated JSON is scrubbed up to the value that was cut off, so that
+	// key rules still apply
+	assert.True(t, exchange.Request.Truncated)
+	assert.Equal(t, `{"password":"<REDACTED>","note":`, string(exchange.Request.Body))
+
+	// binary bodies are left out
+	assert.Nil(t, exchange.Response.Body)
+}
+
+func Test_HTTPMiddleware_InvalidJSON(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"password":"hunter2","user":"jane@example.com",}`))
+	r.Header.Set("Content-Type", "application/json")
+
+	_, _, exchange := serve(t, nil, r, func(w http.ResponseWriter, r *http.Request) {})
+
+	assert.False(t, exchange.Request.Truncated)
+	assert.Equal(t, `{"password":"<REDACTED>","user":"<EMAIL_ADDRESS>"`, string(exchange.Request.Body))
+}
+
+func Test_HTTPMiddleware_QueryKeys(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/search?jane@example.com&john%40example.com=1&ref=a%2Bb&password=hunter2&", nil)
+
+	_, _, exchange := serve(t, nil, r, func(w http.ResponseWriter, r *http.Request) {})
+
+	// unchanged keys and values keep their encoding
+	assert.Equal(t, "/search?%3CEMAIL_ADDRESS%3E&%3CEMAIL_ADDRESS%3E=1&ref=a%2Bb&password=%3CREDACTED%3E&", exchange.URL)
+}
+
+func Test_HTTPMiddleware_FlushAndHijack(t *testing.T) {
+	_, w, exchange := serve(t, nil, httptest.NewRequest(http.MethodGet, "/events", nil), func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		assert.True(t, ok)
+		io.WriteString(w, "data: jane@example.com\n\n")
+		flusher.Flush()
+	})
+	assert.True(t, w.Flushed)
+	assert.Equal(t, http.StatusOK, exchange.StatusCode)
+	assert.Equal(t, "data: <EMAIL_ADDRESS>\n\n", string(exchange.Response.Body))
+
+	middleware := piiscrubber.HTTPMiddleware(newTestHTTPScrubber(t), func(*piiscrubber.HTTPExchange) {}, nil)
+	server := httptest.NewServer(middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hijacker, ok := w.(http.Hijacker)
+		assert.True(t, ok)
+		conn, buf, err := hijacker.Hijack()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+		buf.Flush()
+	})))
+	defer server.Close()
+
+	response, err := http.Get(server.URL)
+	assert.NoError(t, err)
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "hijacked", string(body))
+}
@@ -0,0 +1,128 @@
+package test
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/stretchr/testify/assert"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// sentRequest is what a transport passed on to the network
+type sentRequest struct {
+	url           string
+	header        http.Header
+	body          string
+	contentLength int64
+}
+
+func newTestTransport(t *testing.T, opts *piiscrubber.TransportOptions) (http.RoundTripper, *sentRequest) {
+	sent := &sentRequest{}
+	next := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.NoError(t, r.Body.Close())
+
+		*sent = sentRequest{
+			url:           r.URL.String(),
+			header:        r.Header,
+			body:          string(body),
+			contentLength: r.ContentLength,
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+	})
+	return piiscrubber.NewTransport(next, opts), sent
+}
+
+func Test_Transport_PerHostPolicy(t *testing.T) {
+	vendor := &piiscrubber.TransportPolicy{
+		Scrubber:      newTestHTTPScrubber(t),
+		ScrubHeaders:  []string{"X-User"},
+		RedactHeaders: []string{"X-Session"},
+	}
+	transport, sent := newTestTransport(t, &piiscrubber.TransportOptions{
+		Policies: map[string]*piiscrubber.TransportPolicy{
+			"analytics.example.com": vendor,
+			"*.llm.example.com":     vendor,
+		},
+	})
+
+	requestBody := `{"prompt":"reply to jane@example.com","password":"hunter2","max_tokens":1e3}`
+	for _, host := range []string{"analytics.example.com", "eu.llm.example.com"} {
+		r, err := http.NewRequest(http.MethodPost, "https://"+host+"/v1/track?uid=7&email=jane%40example.com", strings.NewReader(requestBody))
+		assert.NoError(t, err)
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("X-User", "jane@example.com")
+		r.Header.Set("X-Session", "abc")
+		r.Header.Set("Authorization", "Bearer key")
+
+		_, err = transport.RoundTrip(r)
+		assert.NoError(t, err)
+
+		assert.Equal(t, "https://"+host+"/v1/track?uid=7&email=%3CEMAIL_ADDRESS%3E", sent.url)
+		assert.Equal(t, "<EMAIL_ADDRESS>", sent.header.Get("X-User"))
+		assert.Equal(t, "<REDACTED>", sent.header.Get("X-Session"))
+		assert.Equal(t, "Bearer key", sent.header.Get("Authorization"))
+		assert.Equal(t, `{"prompt":"reply to <EMAIL_ADDRESS>","password":"<REDACTED>","max_tokens":1e3}`, sent.body)
+		assert.Equal(t, int64(len(sent.body)), sent.contentLength)
+
+		// the caller's request is left as it is
+		assert.Equal(t, "jane@example.com", r.Header.Get("X-User"))
+		assert.Equal(t, "uid=7&email=jane%40example.com", r.URL.RawQuery)
+	}
+
+	// hosts without a policy get the request as it is
+	r, err := http.NewRequest(http.MethodPost, "https://internal.example.org/users?email=jane%40example.com", strings.NewReader(requestBody))
+	assert.NoError(t, err)
+	r.Header.Set("Content-Type", "application/json")
+	_, err = transport.RoundTrip(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://internal.example.org/users?email=jane%40example.com", sent.url)
+	assert.Equal(t, requestBody, sent.body)
+}
+
+func Test_Transport_StreamsLargeBodies(t *testing.T) {
+	transport, sent := newTestTransport(t, &piiscrubber.TransportOptions{
+		DefaultPolicy: &piiscrubber.TransportPolicy{Scrubber: newTestHTTPScrubber(t)},
+		MaxBufferSize: 64,
+	})
+
+	var jsonBody, formBody, textBody, expectedJSON, expectedForm, expectedText []string
+	for i := 0; i < 500; i++ {
+		jsonBody = append(jsonBody, `{"to":"jane@example.com","n":1.50}`)
+		expectedJSON = append(expectedJSON, `{"to":"<EMAIL_ADDRESS>","n":1.50}`)
+		formBody = append(formBody, "to=jane%40example.com&password=hunter2")
+		expectedForm = append(expectedForm, "to=%3CEMAIL_ADDRESS%3E&password=%3CREDACTED%3E")
+		textBody = append(textBody, "mail jane@example.com")
+		expectedText = append(expectedText, "mail <EMAIL_ADDRESS>")
+	}
+
+	tests := []struct {
+		contentType string
+		body        string
+		expected    string
+	}{
+		{"application/json", "[" + strings.Join(jsonBody, ",") + "]", "[" + strings.Join(expectedJSON, ",") + "]"},
+		{"application/x-www-form-urlencoded", strings.Join(formBody, "&"), strings.Join(expectedForm, "&")},
+		{"text/plain", strings.Join(textBody, "\n"), strings.Join(expectedText, "\n")},
+	}
+
+	for _, tt := range tests {
+		r, err := http.NewRequest(http.MethodPost, "https://api.example.com/upload", strings.NewReader(tt.body))
+		assert.NoError(t, err)
+		r.Header.Set("Content-Type", tt.contentType)
+
+		_, err = transport.RoundTrip(r)
+		assert.NoError(t, err, tt.contentType)
+		assert.Equal(t, tt.expected, sent.body, tt.contentType)
+		assert.Equal(t, int64(-1), sent.contentLength, tt.contentType)
+	}
+}
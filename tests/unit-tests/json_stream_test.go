@@ -0,0 +1,83 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ScrubNDJSON(t *testing.T) {
+	policy := piiscrubber.JSONPolicy{
+		Scrubber: newJSONTestScrubber(t),
+		Rules: []piiscrubber.JSONRule{
+			{Path: "$.id", Action: piiscrubber.Keep},
+			{Path: "$.password", Action: piiscrubber.Drop},
+		},
+	}
+
+	input := `{"id":"jane@example.com","note":"call +919140520809","password":"x"}
+
+{"id": "john@example.com", "n": 1.0, "tags": ["a\"b", "é"]}
+`
+	var out bytes.Buffer
+	err := piiscrubber.ScrubNDJSON(iotest.OneByteReader(strings.NewReader(input)), &out, policy)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":"jane@example.com","note":"call <PHONE_NUMBER>"}`+"\n"+
+		`{"id":"john@example.com","n":1.0,"tags":["a\"b","é"]}`+"\n", out.String())
+}
+
+func Test_ScrubNDJSON_Batches(t *testing.T) {
+	var input, expected strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&input, `{"n":%d,"email":"user%d@example.com","tags":["x","y"]}`+"\n", i, i)
+		fmt.Fprintf(&expected, `{"n":%d,"email":"<EMAIL_ADDRESS>","tags":["x","y"]}`+"\n", i)
+	}
+
+	var out bytes.Buffer
+	err := piiscrubber.ScrubNDJSON(strings.NewReader(input.String()), &out,
+		piiscrubber.JSONPolicy{Scrubber: newJSONTestScrubber(t)})
+	assert.NoError(t, err)
+	assert.Equal(t, expected.String(), out.String())
+}
+
+func Test_ScrubJSONStream(t *testing.T) {
+	policy := piiscrubber.JSONPolicy{
+		Scrubber: newJSONTestScrubber(t),
+		Rules: []piiscrubber.JSONRule{
+			{Path: "$[*].name", Action: piiscrubber.Redact},
+			{Path: "$[1]", Action: piiscrubber.Drop},
+		},
+	}
+
+	var out bytes.Buffer
+	err := piiscrubber.ScrubJSONStream(strings.NewReader(`[
+		{"name": "Jane", "email": "jane@example.com"},
+		{"name": "John"},
+		{"name": "Joe", "phone": "+919140520809"}
+	] ["jane@example.com"]`), &out, policy)
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"name":"<REDACTED>","email":"<EMAIL_ADDRESS>"},{"name":"<REDACTED>","phone":"<PHONE_NUMBER>"}]`+"\n"+
+		`["<EMAIL_ADDRESS>"]`, out.String())
+}
+
+func Test_ScrubJSONStream_Errors(t *testing.T) {
+	scrubber := newJSONTestScrubber(t)
+
+	for _, data := range []string{`[{"a":1}`, `{"a":1}}`, `{"a" 1}`, `[1] nope`} {
+		err := piiscrubber.ScrubJSONStream(strings.NewReader(data), &bytes.Buffer{}, piiscrubber.JSONPolicy{Scrubber: scrubber})
+		assert.ErrorIs(t, err, piiscrubber.ErrInvalidJSON, data)
+		err = piiscrubber.ScrubNDJSON(strings.NewReader(data), &bytes.Buffer{}, piiscrubber.JSONPolicy{Scrubber: scrubber})
+		assert.ErrorIs(t, err, piiscrubber.ErrInvalidJSON, data)
+	}
+
+	err := piiscrubber.ScrubNDJSON(strings.NewReader(`{}`), &bytes.Buffer{}, piiscrubber.JSONPolicy{
+		Scrubber: scrubber,
+		Rules:    []piiscrubber.JSONRule{{Path: "$", Action: piiscrubber.Drop}},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,122 @@
+package test
+
+import (
+	"testing"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/stretchr/testify/assert"
+)
+
+func newJSONTestScrubber(t *testing.T, keyRules ...piiscrubber.KeyRule) piiscrubber.Scrubber {
+	scrubber, err := piiscrubber.New(piiscrubber.Params{
+		BlacklistedEntities: []piiscrubber.Entity{
+			piiscrubber.Email,
+			piiscrubber.Phone,
+		},
+		KeyRules: keyRules,
+	})
+	assert.NoError(t, err)
+	return scrubber
+}
+
+func Test_ScrubJSON_Rules(t *testing.T) {
+	policy := piiscrubber.JSONPolicy{
+		Scrubber: newJSONTestScrubber(t),
+		Rules: []piiscrubber.JSONRule{
+			{Path: "$.user.email", Action: piiscrubber.Redact},
+			{Path: "$.items", Action: piiscrubber.Keep},
+			{Path: "$.items[*].note", Action: piiscrubber.Scan},
+			{Path: "$..password", Action: piiscrubber.Drop},
+			{Path: "$.tags[1]", Action: piiscrubber.Drop},
+			{Path: "$['odd.key']", Action: piiscrubber.Redact},
+		},
+	}
+
+	scrubbed, err := piiscrubber.ScrubJSON([]byte(`{
+		"z": 1.50,
+		"user": {"name": "Jane", "email": "jane@example.com", "password": "hunter2", "age": 1e3},
+		"items": [
+			{"id": "jane@example.com", "note": "call +919140520809", "password": "x"},
+			{"id": "john@example.com", "note": null}
+		],
+		"tags": ["a", "jane@example.com", "john@example.com"],
+		"odd.key": {"x": true},
+		"bio": "mail jane@example.com"
+	}`), policy)
+	assert.NoError(t, err)
+
+	// keys keep their order and numbers their formatting
+	assert.Equal(t, `{"z":1.50,`+
+		`"user":{"name":"Jane","email":"<REDACTED>","age":1e3},`+
+		`"items":[{"id":"jane@example.com","note":"call <PHONE_NUMBER>"},{"id":"john@example.com","note":null}],`+
+		`"tags":["a","<EMAIL_ADDRESS>"],`+
+		`"odd.key":{"x":"<REDACTED>"},`+
+		`"bio":"mail <EMAIL_ADDRESS>"}`, string(scrubbed))
+}
+
+func Test_ScrubJSON_Escaping(t *testing.T) {
+	scrubbed, err := piiscrubber.ScrubJSON(
+		[]byte(`["say \"hi\" to jane@example.com\n", "tab\there", "é & <b>", " "]`),
+		piiscrubber.JSONPolicy{Scrubber: newJSONTestScrubber(t)},
+	)
+	assert.NoError(t, err)
+	// line separators are escaped so that the output is valid JavaScript too
+	assert.Equal(t, `["say \"hi\" to <EMAIL_ADDRESS>\n","tab\there","é & <b>","\u2028"]`, string(scrubbed))
+}
+
+func Test_ScrubJSON_RecursiveDescent(t *testing.T) {
+	policy := piiscrubber.JSONPolicy{
+		Scrubber: newJSONTestScrubber(t, piiscrubber.KeyRule{Pattern: "^phone$", Action: piiscrubber.Redact}),
+		Rules: []piiscrubber.JSONRule{
+			{Path: "$..*", Action: piiscrubber.Keep},
+			{Path: "$..contacts..email", Action: piiscrubber.Scan},
+			{Path: "$..[0]", Action: piiscrubber.Redact},
+		},
+	}
+
+	scrubbed, err := piiscrubber.ScrubJSON([]byte(`{
+		"email": "jane@example.com",
+		"phone": "+919140520809",
+		"contacts": {"work": {"email": "jane@work.com"}},
+		"list": ["first", "second"]
+	}`), policy)
+	assert.NoError(t, err)
+
+	// JSON rules take precedence over the key rules of the scrubber
+	assert.Equal(t, `{"email":"jane@example.com","phone":"+919140520809",`+
+		`"contacts":{"work":{"email":"<EMAIL_ADDRESS>"}},"list":["<REDACTED>","second"]}`, string(scrubbed))
+
+	// without JSON rules, key rules still apply
+	scrubbed, err = piiscrubber.ScrubJSON([]byte(`{"phone":"1","note":"+919140520809"}`),
+		piiscrubber.JSONPolicy{Scrubber: policy.Scrubber})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"phone":"<REDACTED>","note":"<PHONE_NUMBER>"}`, string(scrubbed))
+}
+
+func Test_ScrubJSON_Errors(t *testing.T) {
+	scrubber := newJSONTestScrubber(t)
+
+	for _, data := range []string{`{"a":`, `{} {}`, `nope`, ``} {
+		_, err := piiscrubber.ScrubJSON([]byte(data), piiscrubber.JSONPolicy{Scrubber: scrubber})
+		assert.ErrorIs(t, err, piiscrubber.ErrInvalidJSON, data)
+	}
+
+	for _, rule := range []piiscrubber.JSONRule{
+		{Path: "user.email", Action: piiscrubber.Redact},
+		{Path: "$.items[-1]", Action: piiscrubber.Redact},
+		{Path: "$.items[?(@.a)]", Action: piiscrubber.Redact},
+		{Path: "$['a", Action: piiscrubber.Redact},
+		{Path: "$.a..", Action: piiscrubber.Redact},
+		{Path: "$", Action: piiscrubber.Drop},
+		{Path: "$.a", Action: "hash"},
+	} {
+		_, err := piiscrubber.ScrubJSON([]byte(`{}`), piiscrubber.JSONPolicy{
+			Scrubber: scrubber,
+			Rules:    []piiscrubber.JSONRule{rule},
+		})
+		assert.Error(t, err, rule.Path)
+	}
+
+	_, err := piiscrubber.ScrubJSON([]byte(`{}`), piiscrubber.JSONPolicy{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,154 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ScrubStruct_MapKeys(t *testing.T) {
+	type sampleStruct struct {
+		Contacts map[string]string `pii:"true"`
+		Counts   map[string]int
+	}
+
+	scrubber, err := piiscrubber.New(piiscrubber.Params{
+		BlacklistedEntities: []piiscrubber.Entity{
+			piiscrubber.Email,
+		},
+		ScrubMapKeys: true,
+	})
+	assert.NoError(t, err)
+
+	v := sampleStruct{
+		Contacts: map[string]string{
+			"jane@x.com": "primary",
+			"john@x.com": "secondary",
+			"office":     "write to desk@x.com",
+		},
+		Counts: map[string]int{
+			"jane@x.com": 1,
+		},
+	}
+
+	response, report, err := piiscrubber.ScrubStructWithReport(scrubber, v)
+	assert.NoError(t, err)
+
+	assert.Equal(t, sampleStruct{
+		Contacts: map[string]string{
+			"<EMAIL_ADDRESS>":     "primary",
+			"<EMAIL_ADDRESS> (2)": "secondary",
+			"office":              "write to <EMAIL_ADDRESS>",
+		},
+		// not pii tagged, so the keys are left alone
+		Counts: map[string]int{
+			"jane@x.com": 1,
+		},
+	}, response)
+
+	reportLines := make([]string, 0, len(report))
+	for _, field := range report {
+		reportLines = append(reportLines, field.String())
+	}
+	assert.Equal(t, []string{
+		`Contacts["<EMAIL_ADDRESS> (2)"] (key): EMAIL@0-10`,
+		`Contacts["<EMAIL_ADDRESS>"] (key): EMAIL@0-10`,
+		`Contacts["office"]: EMAIL@9-19`,
+	}, reportLines)
+}
+
+func Test_ScrubStruct_KeyRules(t *testing.T) {
+	scrubber, err := piiscrubber.New(piiscrubber.Params{
+		BlacklistedEntities: []piiscrubber.Entity{
+			piiscrubber.Email,
+		},
+		KeyRules: []piiscrubber.KeyRule{
+			{Pattern: `(?i)email|phone|ssn|password`, Action: piiscrubber.Redact},
+			{Pattern: `^notes$`, Action: piiscrubber.Scan},
+		},
+	})
+	assert.NoError(t, err)
+
+	v := map[string]interface{}{
+		"Email":    "not-even-an-email",
+		"password": "hunter2",
+		"notes":    "contact jane@x.com",
+		"plan":     "pro for jane@x.com",
+		"attributes": map[string]interface{}{
+			"home_phone": 5551234,
+			"work_phone": "555-1234",
+		},
+	}
+
+	response, err := scrubber.ScrubStruct(v)
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"Email":    "<REDACTED>",
+		"password": "<REDACTED>",
+		"notes":    "contact <EMAIL_ADDRESS>",
+		"plan":     "pro for jane@x.com",
+		"attributes": map[string]interface{}{
+			"home_phone": "<REDACTED>",
+			"work_phone": "<REDACTED>",
+		},
+	}, response)
+}
+
+func Test_ScrubStruct_RedactNonStrings(t *testing.T) {
+	type account struct {
+		Number  int64
+		Pin     *int
+		Active  bool
+		Balance float64
+		Extra   map[string]interface{}
+	}
+
+	scrubber, err := piiscrubber.New(piiscrubber.Params{
+		FieldRules: []piiscrubber.FieldRule{
+			{Type: reflect.TypeOf(account{}), Path: "**", Action: piiscrubber.Redact},
+		},
+		KeyRules: []piiscrubber.KeyRule{
+			{Pattern: `^ssn$`, Action: piiscrubber.Redact},
+		},
+	})
+	assert.NoError(t, err)
+
+	pin := 1234
+	v := map[string]interface{}{
+		"account": account{
+			Number:  4111111111111111,
+			Pin:     &pin,
+			Active:  true,
+			Balance: 12.5,
+			Extra:   map[string]interface{}{"verified": true, "codes": []interface{}{42, "x"}},
+		},
+		"ssn":   []interface{}{123456789, 4.5, true, nil},
+		"count": 3,
+	}
+
+	response, err := scrubber.ScrubStruct(v)
+	assert.NoError(t, err)
+
+	zero := 0
+	assert.Equal(t, map[string]interface{}{
+		// typed values can't hold the placeholder and are zeroed
+		"account": account{
+			Pin:   &zero,
+			Extra: map[string]interface{}{"verified": "<REDACTED>", "codes": []interface{}{"<REDACTED>", "<REDACTED>"}},
+		},
+		"ssn":   []interface{}{"<REDACTED>", "<REDACTED>", "<REDACTED>", nil},
+		"count": 3,
+	}, response)
+}
+
+func Test_New_InvalidKeyRule(t *testing.T) {
+	_, err := piiscrubber.New(piiscrubber.Params{
+		KeyRules: []piiscrubber.KeyRule{
+			{Pattern: `(email`, Action: piiscrubber.Redact},
+		},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,215 @@
+package test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LoadPolicy_YAML(t *testing.T) {
+	params, err := piiscrubber.LoadPolicy(strings.NewReader(`
+# reviewed by the security team
+entities: [EMAIL, PHONE, EMPLOYEE_ID]
+allow_list: [Support@Example.com]
+masking:
+  PHONE: {mask_with_char: "*", unmasked_suffix: 4}
+custom_entities:
+  EMPLOYEE_ID:
+    patterns: ['\bEMP-\d{6}\b']
+key_rules:
+  - {pattern: '(?i)^password$', action: redact}
+field_rules:
+  - {type: stripe.Customer, path: Email, action: redact}
+scrub_map_keys: true
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, []piiscrubber.Entity{piiscrubber.Email, piiscrubber.Phone, "EMPLOYEE_ID"}, params.BlacklistedEntities)
+	// entities that aren't listed keep their defaults
+	assert.Equal(t, piiscrubber.DefaultParams().IgnoredEntities, params.IgnoredEntities)
+	assert.Equal(t, []piiscrubber.FieldRule{{TypeName: "stripe.Customer", Path: "Email", Action: piiscrubber.Redact}}, params.FieldRules)
+	assert.True(t, params.ScrubMapKeys)
+
+	scrubber, err := piiscrubber.New(params)
+	assert.NoError(t, err)
+	scrubbed, err := scrubber.ScrubTexts([]string{
+		"EMP-123456 wrote from jane@example.com to support@example.com, call +919140520809",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"<EMPLOYEE_ID> wrote from <EMAIL_ADDRESS> to support@example.com, call *********0809"}, scrubbed)
+
+	result, err := scrubber.ScrubStruct(map[string]interface{}{"Password": "hunter2"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"Password": "<REDACTED>"}, result)
+}
+
+func Test_LoadPolicy_JSON(t *testing.T) {
+	params, err := piiscrubber.LoadPolicy(strings.NewReader(`{
+	"entities": ["EMAIL", "ORG"],
+	"custom_entities": {
+		"ORG": {"patterns": ["(?i)\\bacme corp\\b"], "placeholder": "<ORG_NAME>"}
+	},
+	"masking": {"EMAIL": {"replace_with": "<EMAIL>"}}
+}`))
+	assert.NoError(t, err)
+
+	scrubber, err := piiscrubber.New(params)
+	assert.NoError(t, err)
+	scrubbed, err := scrubber.ScrubTexts([]string{"jane@example.com works at ACME Corp"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"<EMAIL> works at <ORG_NAME>"}, scrubbed)
+
+	// an empty policy is the default one
+	params, err = piiscrubber.LoadPolicy(strings.NewReader(""))
+	assert.NoError(t, err)
+	assert.Equal(t, piiscrubber.DefaultParams(), params)
+}
+
+func Test_LoadPolicy_Errors(t *testing.T) {
+	for _, tc := range []struct {
+		policy string
+		line   int
+		column int
+	}{
+		{"entities: [EMAIL, PHONE]\nignored_entities: [NOPE]\n", 2, 20},
+		{"entities: [EMAIL]\nmasking:\n  EMAIL: {replace_with: x, bogus: 1}\n", 3, 28},
+		{"masking:\n  PHONE: {unmasked_suffix: 4}\n", 2, 10},
+		{"masking:\n  PHONE: {mask_with_char: '**'}\n", 2, 27},
+		{"masking:\n  PHONE: {mask_with_char: '*', unmasked_suffix: -1}\n", 2, 49},
+		{"custom_entities:\n  ORG:\n    patterns: ['(']\n", 3, 16},
+		{"custom_entities:\n  ORG: {placeholder: x}\n", 2, 8},
+		{"key_rules:\n  - {pattern: x, action: hide}\n", 2, 5},
+		{"field_rules:\n  - {path: '', action: keep}\n", 2, 5},
+		{"scrub_map_keys: yes please\n", 1, 17},
+		{"entities: EMAIL\n", 1, 11},
+		{"entities: [EMAIL]\nentities: [PHONE]\n", 2, 1},
+		{"{\n\t\"entities\": [\"EMAIL\"],\n\t\"colour\": 1\n}", 3, 2},
+	} {
+		_, err := piiscrubber.LoadPolicy(strings.NewReader(tc.policy))
+		var policyErr *piiscrubber.PolicyError
+		if assert.True(t, errors.As(err, &policyErr), "%v: %v", tc.policy, err) {
+			assert.Equal(t, tc.line, policyErr.Line, tc.policy)
+			assert.Equal(t, tc.column, policyErr.Column, tc.policy)
+		}
+	}
+
+	_, err := piiscrubber.LoadPolicy(strings.NewReader("entities: [EMAIL\n"))
+	assert.Error(t, err)
+}
+
+func Test_New_AllowList(t *testing.T) {
+	scrubber, err := piiscrubber.New(piiscrubber.Params{
+		BlacklistedEntities: []piiscrubber.Entity{piiscrubber.Email},
+		AllowList:           []string{"noreply@example.com"},
+	})
+	assert.NoError(t, err)
+
+	scrubbed, err := scrubber.ScrubTexts([]string{"from NoReply@example.com to jane@example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"from NoReply@example.com to <EMAIL_ADDRESS>"}, scrubbed)
+}
+
+func Test_LoadPolicy_CustomEntityOptions(t *testing.T) {
+	params, err := piiscrubber.LoadPolicy(strings.NewReader(`
+entities: [ORDER_ID]
+custom_entities:
+  ORDER_ID:
+    patterns: ['order (\d+)']
+    group: 1
+    ignore_case: true
+    word_boundaries: true
+`))
+	assert.NoError(t, err)
+	scrubber, err := piiscrubber.New(params)
+	assert.NoError(t, err)
+	scrubbed, err := scrubber.ScrubTexts([]string{"Order 1234 and preorder 5678"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Order <ORDER_ID> and preorder 5678"}, scrubbed)
+
+	_, err = piiscrubber.LoadPolicy(strings.NewReader("custom_entities:\n  ORDER_ID: {patterns: [order], group: 1}\n"))
+	var policyErr *piiscrubber.PolicyError
+	if assert.True(t, errors.As(err, &policyErr), "%v", err) {
+		assert.Equal(t, 2, policyErr.Line)
+	}
+}
+
+func Test_LoadPolicy_Booleans(t *testing.T) {
+	for value, expected := range map[string]bool{
+		"true": true, "True": true, "TRUE": true,
+		"false": false, "False": false, "FALSE": false,
+	} {
+		params, err := piiscrubber.LoadPolicy(strings.NewReader("scrub_map_keys: " + value))
+		assert.NoError(t, err, value)
+		assert.Equal(t, expected, params.ScrubMapKeys, value)
+	}
+
+	// YAML 1.1 booleans are strings in YAML 1.2
+	_, err := piiscrubber.LoadPolicy(strings.NewReader("scrub_map_keys: yes"))
+	var policyErr *piiscrubber.PolicyError
+	assert.True(t, errors.As(err, &policyErr), "%v", err)
+}
+
+func Test_LoadPolicyWithOptions_Extensions(t *testing.T) {
+	type rule struct {
+		Path   string `json:"path"`
+		Action string `json:"action"`
+	}
+	var rules []rule
+	var level int
+	opts := &piiscrubber.PolicyOptions{
+		Extensions: map[string]interface{}{"rules": &rules, "level": &level},
+	}
+
+	params, err := piiscrubber.LoadPolicyWithOptions(strings.NewReader(`
+entities: [EMAIL]
+rules:
+  - {path: $.a, action: drop}
+level: 3
+`), opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []piiscrubber.Entity{piiscrubber.Email}, params.BlacklistedEntities)
+	assert.Equal(t, []rule{{Path: "$.a", Action: "drop"}}, rules)
+	assert.Equal(t, 3, level)
+
+	// unknown fields of the extensions are errors too
+	_, err = piiscrubber.LoadPolicyWithOptions(strings.NewReader("\nrules: [{path: $.a, acton: drop}]\n"), opts)
+	var policyErr *piiscrubber.PolicyError
+	if assert.True(t, errors.As(err, &policyErr), "%v", err) {
+		assert.Equal(t, 2, policyErr.Line)
+	}
+
+	// extensions can't replace the fields of the policy
+	_, err = piiscrubber.LoadPolicyWithOptions(strings.NewReader("entities: [EMAIL]"), &piiscrubber.PolicyOptions{
+		Extensions: map[string]interface{}{"entities": &rules},
+	})
+	assert.Error(t, err)
+}
+
+func Test_LoadPolicy_ExtraPatterns(t *testing.T) {
+	params, err := piiscrubber.LoadPolicy(strings.NewReader(`
+entities: [SSN]
+extra_patterns:
+  SSN:
+    patterns: ['\b\d{3} \d{2} \d{4}\b']
+    exclusions: ['\bTKT-\d{3}-\d{2}-\d{4}\b']
+`))
+	assert.NoError(t, err)
+	scrubber, err := piiscrubber.New(params)
+	assert.NoError(t, err)
+	scrubbed, err := scrubber.ScrubTexts([]string{"TKT-123-45-6789 is about 123 45 6789"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"TKT-123-45-6789 is about <US_SSN>"}, scrubbed)
+
+	for _, policy := range []string{
+		"extra_patterns:\n  NOPE: {patterns: [x]}\n",
+		"extra_patterns:\n  SSN: {exclusions: ['(']}\n",
+	} {
+		_, err = piiscrubber.LoadPolicy(strings.NewReader(policy))
+		var policyErr *piiscrubber.PolicyError
+		if assert.True(t, errors.As(err, &policyErr), "%v", err) {
+			assert.Equal(t, 2, policyErr.Line)
+		}
+	}
+}
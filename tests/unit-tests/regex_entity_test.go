@@ -0,0 +1,78 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RegexEntityScrubber(t *testing.T) {
+	s, err := piiscrubber.NewRegexEntityScrubber(`EMP-\d{6}`, `E\d{4}`)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]int{{0, 10}, {15, 20}}, s.Match("EMP-123456 and E1234"))
+	assert.Equal(t, []byte("<REDACTED>"), s.Mask([]byte("E1234"), nil))
+
+	char := '*'
+	assert.Equal(t, []byte("*1234"), s.Mask([]byte("E1234"), &piiscrubber.EntityConfig{MaskWithChar: &char, UnmaskedSuffixOffset: 4}))
+}
+
+func Test_RegexEntityScrubber_Options(t *testing.T) {
+	s, err := piiscrubber.NewRegexEntityScrubberWithOptions(&piiscrubber.RegexEntityOptions{
+		WordBoundaries: true,
+		IgnoreCase:     true,
+	}, "acme")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]int{{0, 4}, {22, 26}}, s.Match("ACME bought Acmeplex, acme"))
+
+	s, err = piiscrubber.NewRegexEntityScrubberWithOptions(&piiscrubber.RegexEntityOptions{Group: 1}, `id=(\d+)`)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]int{{9, 13}}, s.Match("order id=1234"))
+
+	// only the numbers with an even check digit
+	s, err = piiscrubber.NewRegexEntityScrubberWithOptions(&piiscrubber.RegexEntityOptions{
+		Validators: []func(string) bool{
+			func(match string) bool { return (match[len(match)-1]-'0')%2 == 0 },
+		},
+	}, `\d{4}`)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]int{{5, 9}}, s.Match("1231 1232"))
+
+	for _, tc := range []struct {
+		opts     *piiscrubber.RegexEntityOptions
+		patterns []string
+	}{
+		{nil, nil},
+		{nil, []string{"("}},
+		{&piiscrubber.RegexEntityOptions{Group: 1}, []string{`id=\d+`}},
+		{&piiscrubber.RegexEntityOptions{Group: -1}, []string{`id`}},
+	} {
+		_, err := piiscrubber.NewRegexEntityScrubberWithOptions(tc.opts, tc.patterns...)
+		assert.Error(t, err, strings.Join(tc.patterns, ","))
+	}
+}
+
+func Test_New_CustomEntityPatterns(t *testing.T) {
+	scrubber, err := piiscrubber.New(piiscrubber.Params{
+		BlacklistedEntities:  []piiscrubber.Entity{piiscrubber.Email, "EMPLOYEE_ID"},
+		CustomEntityPatterns: map[piiscrubber.Entity][]string{"EMPLOYEE_ID": {`\bEMP-\d{6}\b`}},
+	})
+	assert.NoError(t, err)
+	scrubbed, err := scrubber.ScrubTexts([]string{"EMP-123456 is jane@example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"<EMPLOYEE_ID> is <EMAIL_ADDRESS>"}, scrubbed)
+
+	_, err = piiscrubber.New(piiscrubber.Params{
+		CustomEntityPatterns: map[piiscrubber.Entity][]string{"EMPLOYEE_ID": {"("}},
+	})
+	assert.Error(t, err)
+
+	custom, err := piiscrubber.NewRegexEntityScrubber("x")
+	assert.NoError(t, err)
+	_, err = piiscrubber.New(piiscrubber.Params{
+		CustomEntityScrubbers: map[piiscrubber.Entity]piiscrubber.EntityScrubber{"EMPLOYEE_ID": custom},
+		CustomEntityPatterns:  map[piiscrubber.Entity][]string{"EMPLOYEE_ID": {"y"}},
+	})
+	assert.Error(t, err)
+}
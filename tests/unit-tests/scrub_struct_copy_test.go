@@ -0,0 +1,76 @@
+package test
+
+import (
+	"testing"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/stretchr/testify/assert"
+)
+
+type unexportedFields struct {
+	Note    string `pii:"true"`
+	private string
+	secret  *string
+}
+
+func Test_ScrubStruct_CopiesUnexportedFields(t *testing.T) {
+	scrubber, _ := piiscrubber.NewDefaultScrubber()
+
+	secret := "jane@example.com"
+	original := unexportedFields{
+		Note:    "mail jane@example.com",
+		private: "jane@example.com",
+		secret:  &secret,
+	}
+
+	response, err := scrubber.ScrubStruct(original)
+	assert.NoError(t, err)
+
+	// unexported fields can't be scrubbed and are carried over as they are,
+	// pointers included
+	scrubbed := response.(unexportedFields)
+	assert.Equal(t, "mail <EMAIL_ADDRESS>", scrubbed.Note)
+	assert.Equal(t, "jane@example.com", scrubbed.private)
+	assert.True(t, scrubbed.secret == original.secret)
+	assert.Equal(t, "mail jane@example.com", original.Note)
+}
+
+func Test_ScrubStruct_TaggedUnexportedField(t *testing.T) {
+	type account struct {
+		Email string `pii:"true"`
+		phone string `pii:"true"`
+	}
+	type holder struct {
+		Accounts []account
+	}
+
+	scrubber, _ := piiscrubber.NewDefaultScrubber()
+
+	// the field can't be scrubbed, which fails rather than leaks it
+	_, err := scrubber.ScrubStruct(account{Email: "jane@example.com", phone: "+919140520809"})
+	assert.ErrorIs(t, err, piiscrubber.ErrUnexportedPIIField)
+	assert.Contains(t, err.Error(), "test.account.phone")
+
+	_, err = scrubber.ScrubStruct(holder{Accounts: []account{{phone: "+919140520809"}}})
+	assert.ErrorIs(t, err, piiscrubber.ErrUnexportedPIIField)
+}
+
+func Test_ScrubStruct_NilInterfaces(t *testing.T) {
+	type holder struct {
+		Tagged   interface{} `pii:"true"`
+		Untagged interface{}
+		Values   []interface{} `pii:"true"`
+	}
+
+	scrubber, _ := piiscrubber.NewDefaultScrubber()
+
+	response, err := scrubber.ScrubStruct(holder{
+		Values: []interface{}{nil, "jane@example.com"},
+	})
+	assert.NoError(t, err)
+
+	scrubbed := response.(holder)
+	assert.Nil(t, scrubbed.Tagged)
+	assert.Nil(t, scrubbed.Untagged)
+	assert.Equal(t, []interface{}{nil, "<EMAIL_ADDRESS>"}, scrubbed.Values)
+}
@@ -0,0 +1,155 @@
+package test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ScrubStructWithReport(t *testing.T) {
+
+	type address struct {
+		Location string
+		ZipCode  string
+	}
+
+	type user struct {
+		Name             string            `pii:"true"`
+		CustomAttributes map[string]string `pii:"true"`
+		Age              int
+		Position         string
+		Address          *address `pii:"true"`
+		Notes            []string `pii:"true"`
+	}
+
+	v := user{
+		Name: "Anshal +9140528009",
+		CustomAttributes: map[string]string{
+			"PIIKey": "Hello here is my credit card 6011553157232994",
+			"Other":  "nothing to see here",
+		},
+		Age:      10,
+		Position: "Software Engineer abc@gmail.com",
+		Address: &address{
+			Location: "My 488-23-3729",
+		},
+		Notes: []string{"clean", "mail me at abc@gmail.com"},
+	}
+
+	scrubber, _ := piiscrubber.New(piiscrubber.Params{
+		BlacklistedEntities: []piiscrubber.Entity{
+			piiscrubber.CreditCard,
+			piiscrubber.Phone,
+			piiscrubber.Email,
+			piiscrubber.SSN,
+		},
+	})
+
+	response, report, err := piiscrubber.ScrubStructWithReport(scrubber, v)
+	assert.NoError(t, err)
+
+	expected, err := scrubber.ScrubStruct(v)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, response)
+
+	reportLines := make([]string, 0, len(report))
+	for _, field := range report {
+		reportLines = append(reportLines, field.String())
+	}
+
+	assert.Equal(t, []string{
+		`Address.Location: SSN@3-14`,
+		`CustomAttributes["PIIKey"]: CREDIT_CARD@29-45`,
+		`Name: PHONE@7-18`,
+		`Notes[1]: EMAIL@11-24`,
+	}, reportLines)
+	assert.Equal(t, []piiscrubber.Entity{piiscrubber.CreditCard}, report[1].Entities())
+}
+
+func Test_ScrubStructWithReport_MapKeys(t *testing.T) {
+	type user struct {
+		Contacts map[string]string `pii:"true"`
+	}
+
+	v := user{
+		Contacts: map[string]string{"jane@example.com": "call +919140520809"},
+	}
+
+	for _, scrubMapKeys := range []bool{false, true} {
+		scrubber, err := piiscrubber.New(piiscrubber.Params{
+			BlacklistedEntities: []piiscrubber.Entity{piiscrubber.Phone, piiscrubber.Email},
+			ScrubMapKeys:        scrubMapKeys,
+		})
+		assert.NoError(t, err)
+
+		response, report, err := piiscrubber.ScrubStructWithReport(scrubber, v)
+		assert.NoError(t, err)
+
+		// the copy only loses its keys with ScrubMapKeys, the report never
+		// holds them
+		_, kept := response.(user).Contacts["jane@example.com"]
+		assert.Equal(t, !scrubMapKeys, kept)
+		for _, field := range report {
+			assert.NotContains(t, field.String(), "jane@example.com")
+			if !field.InKey {
+				assert.Equal(t, `Contacts["<EMAIL_ADDRESS>"]: PHONE@5-18`, field.String())
+			}
+		}
+	}
+}
+
+func Test_ScrubTextsWithFindings(t *testing.T) {
+	scrubber, _ := piiscrubber.NewDefaultScrubber()
+
+	texts, findings, err := piiscrubber.ScrubTextsWithFindings(scrubber, []string{
+		"write to abc@gmail.com",
+		"nothing here",
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"write to <EMAIL_ADDRESS>", "nothing here"}, texts)
+	assert.Equal(t, [][]piiscrubber.Finding{
+		{{Entity: piiscrubber.Email, Start: 9, End: 22}},
+		{},
+	}, findings)
+}
+
+// upperScrubber implements only the methods of Scrubber, like the mocks and
+// wrappers of users do
+type upperScrubber struct{}
+
+func (upperScrubber) ScrubTexts(texts []string) ([]string, error) {
+	scrubbed := make([]string, 0, len(texts))
+	for _, text := range texts {
+		scrubbed = append(scrubbed, strings.ToUpper(text))
+	}
+	return scrubbed, nil
+}
+
+func (upperScrubber) ScrubStruct(obj interface{}) (interface{}, error) {
+	return obj, nil
+}
+
+func Test_ScrubTextsWithFindings_NoFindingsScrubber(t *testing.T) {
+	var scrubber piiscrubber.Scrubber = upperScrubber{}
+
+	_, _, err := piiscrubber.ScrubTextsWithFindings(scrubber, []string{"a"})
+	assert.ErrorIs(t, err, piiscrubber.ErrNoFindings)
+	_, _, err = piiscrubber.ScrubStructWithReport(scrubber, struct{}{})
+	assert.ErrorIs(t, err, piiscrubber.ErrNoFindings)
+
+	// the other scrubbing functions only need a Scrubber
+	scrubbed, err := piiscrubber.ScrubJSON([]byte(`{"a":"b"}`), piiscrubber.JSONPolicy{Scrubber: scrubber})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":"B"}`, string(scrubbed))
+	_, err = piiscrubber.ScrubCSV(strings.NewReader("a\n"), io.Discard, piiscrubber.CSVPolicy{Scrubber: scrubber})
+	assert.NoError(t, err)
+
+	defaultScrubber, err := piiscrubber.NewDefaultScrubber()
+	assert.NoError(t, err)
+	_, ok := defaultScrubber.(piiscrubber.FindingsScrubber)
+	assert.True(t, ok)
+}
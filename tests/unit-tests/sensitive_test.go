@@ -0,0 +1,125 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Sensitive_Redacts(t *testing.T) {
+	type user struct {
+		Name  string
+		Email piiscrubber.SensitiveString
+	}
+
+	u := user{Name: "Jane", Email: piiscrubber.NewSensitive("jane@example.com")}
+
+	assert.Equal(t, "jane@example.com", u.Email.Reveal())
+	assert.Equal(t, "<REDACTED>", u.Email.String())
+	assert.Equal(t, "{Jane <REDACTED>}", fmt.Sprintf("%v", u))
+	assert.Equal(t, "{Name:Jane Email:<REDACTED>}", fmt.Sprintf("%+v", u))
+	assert.Equal(t, `test.user{Name:"Jane", Email:"<REDACTED>"}`, fmt.Sprintf("%#v", u))
+	assert.Equal(t, `"<REDACTED>"`, fmt.Sprintf("%q", u.Email))
+	assert.Equal(t, "<REDACTED>", fmt.Sprintf("%s", u.Email))
+
+	data, err := json.Marshal(u)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Name":"Jane","Email":"\u003cREDACTED\u003e"}`, string(data))
+
+	data, err = json.Marshal(map[piiscrubber.SensitiveString]int{u.Email: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"\u003cREDACTED\u003e":1}`, string(data))
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	logger.Info("login", "email", u.Email)
+	assert.Equal(t, "level=INFO msg=login email=<REDACTED>\n", buf.String())
+}
+
+func Test_Sensitive_Redactors(t *testing.T) {
+	lastFour, err := piiscrubber.MaskRedactor(&piiscrubber.EntityConfig{
+		MaskWithChar:         runePtr('X'),
+		UnmaskedSuffixOffset: 4,
+	})
+	assert.NoError(t, err)
+
+	card := piiscrubber.NewSensitive("4263982640269299").WithRedactor(lastFour)
+	assert.Equal(t, "XXXXXXXXXXXX9299", fmt.Sprint(card))
+	assert.Equal(t, "4263982640269299", card.Reveal())
+
+	_, err = piiscrubber.MaskRedactor(&piiscrubber.EntityConfig{})
+	assert.Error(t, err)
+
+	_, err = piiscrubber.MaskRedactor(nil)
+	assert.ErrorIs(t, err, piiscrubber.ErrInvalidEntityConfig)
+
+	hash := piiscrubber.HashRedactor([]byte("key"))
+	a := piiscrubber.NewSensitive("jane@example.com").WithRedactor(hash)
+	b := piiscrubber.NewSensitive("jane@example.com").WithRedactor(hash)
+	c := piiscrubber.NewSensitive("john@example.com").WithRedactor(hash)
+	assert.Equal(t, a.String(), b.String())
+	assert.NotEqual(t, a.String(), c.String())
+	assert.Regexp(t, `^<HASH:[0-9a-f]{16}>$`, a.String())
+
+	scrubber, _ := piiscrubber.NewDefaultScrubber()
+	note := piiscrubber.NewSensitive("call me at jane@example.com").WithRedactor(piiscrubber.ScrubberRedactor(scrubber))
+	assert.Equal(t, "call me at <EMAIL_ADDRESS>", note.String())
+
+	id := piiscrubber.NewSensitive(488233729)
+	assert.Equal(t, "<REDACTED>", fmt.Sprintf("%d", id))
+	assert.Equal(t, 488233729, id.Reveal())
+}
+
+func Test_Sensitive_Unmarshal(t *testing.T) {
+	var u struct {
+		Email piiscrubber.SensitiveString
+		Age   piiscrubber.Sensitive[int]
+	}
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"Email":"jane@example.com","Age":42}`), &u))
+	assert.Equal(t, "jane@example.com", u.Email.Reveal())
+	assert.Equal(t, 42, u.Age.Reveal())
+
+	var s piiscrubber.SensitiveString
+	assert.NoError(t, s.UnmarshalText([]byte("secret")))
+	assert.Equal(t, "secret", s.Reveal())
+	assert.Error(t, u.Age.UnmarshalText([]byte("42")))
+}
+
+func Test_ScrubStruct_UnexportedFields(t *testing.T) {
+	type event struct {
+		Note   string `pii:"true"`
+		Email  piiscrubber.SensitiveString
+		At     time.Time
+		Nested interface{} `pii:"true"`
+	}
+
+	at := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	scrubber, _ := piiscrubber.NewDefaultScrubber()
+
+	response, err := scrubber.ScrubStruct(event{
+		Note:  "mail jane@example.com",
+		Email: piiscrubber.NewSensitive("jane@example.com"),
+		At:    at,
+	})
+	assert.NoError(t, err)
+
+	scrubbed := response.(event)
+	assert.Equal(t, "mail <EMAIL_ADDRESS>", scrubbed.Note)
+	assert.Equal(t, "jane@example.com", scrubbed.Email.Reveal())
+	assert.True(t, at.Equal(scrubbed.At))
+	assert.Nil(t, scrubbed.Nested)
+}
@@ -0,0 +1,82 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/stretchr/testify/assert"
+)
+
+type slogUser struct {
+	Name  string
+	Email string
+}
+
+func (u slogUser) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("name", u.Name), slog.String("email", u.Email))
+}
+
+func newTestSlogLogger(t *testing.T, buf *bytes.Buffer, opts *piiscrubber.SlogHandlerOptions) *slog.Logger {
+	scrubber, err := piiscrubber.New(piiscrubber.Params{
+		BlacklistedEntities: []piiscrubber.Entity{
+			piiscrubber.Email,
+			piiscrubber.Phone,
+			piiscrubber.GUID,
+		},
+	})
+	assert.NoError(t, err)
+
+	next := slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	return slog.New(piiscrubber.NewSlogHandler(next, scrubber, opts))
+}
+
+func Test_SlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestSlogLogger(t, &buf, &piiscrubber.SlogHandlerOptions{
+		RedactKeys: []string{"password", "request.token"},
+		ExemptKeys: []string{"trace_id"},
+	})
+
+	logger.Info("signup by jane@example.com",
+		"password", "hunter2",
+		"trace_id", "0f8fad5b-d9cb-469f-a165-70867728950e",
+		"session", "0f8fad5b-d9cb-469f-a165-70867728950e",
+		"user", slogUser{Name: "Jane", Email: "jane@example.com"},
+		slog.Group("request", "token", "abc", "from", "+919140520809"),
+		"err", errors.New("no mailbox jane@example.com"),
+		"tags", []string{"jane@example.com"},
+		"attempt", 3,
+	)
+
+	assert.Equal(t, `level=INFO msg="signup by <EMAIL_ADDRESS>"`+
+		` password=<REDACTED>`+
+		` trace_id=0f8fad5b-d9cb-469f-a165-70867728950e`+
+		` session=<GUID>`+
+		` user.name=Jane user.email=<EMAIL_ADDRESS>`+
+		` request.token=<REDACTED> request.from=<PHONE_NUMBER>`+
+		` err="no mailbox <EMAIL_ADDRESS>"`+
+		` tags=[<EMAIL_ADDRESS>]`+
+		` attempt=3`+"\n", buf.String())
+}
+
+func Test_SlogHandler_WithAttrsAndGroup(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestSlogLogger(t, &buf, &piiscrubber.SlogHandlerOptions{
+		RedactKeys: []string{"account.password"},
+	})
+
+	logger = logger.With("owner", "jane@example.com").WithGroup("account")
+	logger.Warn("updated", "password", "hunter2", "contact", "jane@example.com")
+
+	assert.Equal(t, `level=WARN msg=updated owner=<EMAIL_ADDRESS> account.password=<REDACTED> account.contact=<EMAIL_ADDRESS>`+"\n", buf.String())
+}
@@ -0,0 +1,101 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_New_Validation(t *testing.T) {
+	for _, tc := range []struct {
+		params piiscrubber.Params
+		entity piiscrubber.Entity
+		err    error
+	}{
+		{
+			piiscrubber.Params{BlacklistedEntities: []piiscrubber.Entity{piiscrubber.Email, "EMPLOYEE_ID"}},
+			"EMPLOYEE_ID", piiscrubber.ErrUnknownEntity,
+		},
+		{
+			piiscrubber.Params{IgnoredEntities: []piiscrubber.Entity{"STRICTLINK"}},
+			"STRICTLINK", piiscrubber.ErrUnknownEntity,
+		},
+		{
+			piiscrubber.Params{Config: map[piiscrubber.Entity]*piiscrubber.EntityConfig{"EMAIL_ADDRESS": {ReplaceWith: stringPtr("x")}}},
+			"EMAIL_ADDRESS", piiscrubber.ErrUnknownEntity,
+		},
+		{
+			piiscrubber.Params{
+				BlacklistedEntities: []piiscrubber.Entity{piiscrubber.Email, piiscrubber.Link},
+				IgnoredEntities:     []piiscrubber.Entity{piiscrubber.Link},
+			},
+			piiscrubber.Link, piiscrubber.ErrConflictingEntity,
+		},
+		{
+			piiscrubber.Params{
+				CustomEntityScrubbers: map[piiscrubber.Entity]piiscrubber.EntityScrubber{"ORG": &customTestEntityScrubberError{}},
+				CustomEntityPatterns:  map[piiscrubber.Entity][]string{"ORG": {"acme"}},
+			},
+			"ORG", piiscrubber.ErrConflictingEntity,
+		},
+		{
+			piiscrubber.Params{Config: map[piiscrubber.Entity]*piiscrubber.EntityConfig{piiscrubber.Phone: {}}},
+			piiscrubber.Phone, piiscrubber.ErrInvalidEntityConfig,
+		},
+		{
+			piiscrubber.Params{Config: map[piiscrubber.Entity]*piiscrubber.EntityConfig{piiscrubber.Phone: nil}},
+			piiscrubber.Phone, piiscrubber.ErrInvalidEntityConfig,
+		},
+		{
+			piiscrubber.Params{CustomEntityPatterns: map[piiscrubber.Entity][]string{"ORG": {"("}}},
+			"ORG", piiscrubber.ErrInvalidEntityPattern,
+		},
+		{
+			piiscrubber.Params{CustomEntityScrubbers: map[piiscrubber.Entity]piiscrubber.EntityScrubber{"ORG": nil}},
+			"ORG", piiscrubber.ErrNilEntityScrubber,
+		},
+	} {
+		_, err := piiscrubber.New(tc.params)
+		assert.ErrorIs(t, err, tc.err)
+		var entityErr *piiscrubber.EntityError
+		if assert.True(t, errors.As(err, &entityErr), "%v", err) {
+			assert.Equal(t, tc.entity, entityErr.Entity)
+		}
+	}
+
+	_, err := piiscrubber.NewWithCustomEntityScrubbers(piiscrubber.NewWithCustomEntityScrubbersParams{
+		BlacklistedEntities: []piiscrubber.Entity{"ORG"},
+	})
+	assert.ErrorIs(t, err, piiscrubber.ErrUnknownEntity)
+}
+
+type outOfRangeEntityScrubber struct {
+	match []int
+}
+
+func (s *outOfRangeEntityScrubber) Match(text string) [][]int {
+	return [][]int{s.match}
+}
+
+func (s *outOfRangeEntityScrubber) Mask(detectedEntity []byte, config *piiscrubber.EntityConfig) []byte {
+	return []byte("<ORG>")
+}
+
+func Test_ScrubTexts_InvalidMatchIndices(t *testing.T) {
+	for _, match := range [][]int{{-1, 2}, {2, 20}, {3, 3}, {0}} {
+		scrubber, err := piiscrubber.New(piiscrubber.Params{
+			BlacklistedEntities:   []piiscrubber.Entity{"ORG"},
+			CustomEntityScrubbers: map[piiscrubber.Entity]piiscrubber.EntityScrubber{"ORG": &outOfRangeEntityScrubber{match: match}},
+		})
+		assert.NoError(t, err)
+
+		_, err = scrubber.ScrubTexts([]string{"short"})
+		assert.ErrorIs(t, err, piiscrubber.ErrInvalidMatchIndices, "%v", match)
+		var entityErr *piiscrubber.EntityError
+		if assert.True(t, errors.As(err, &entityErr)) {
+			assert.Equal(t, piiscrubber.Entity("ORG"), entityErr.Entity)
+		}
+	}
+}
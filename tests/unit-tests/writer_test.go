@@ -0,0 +1,112 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/stretchr/testify/assert"
+)
+
+// lockedBuffer records the writes made to it, it is written to from the
+// flush timer
+type lockedBuffer struct {
+	lock   sync.Mutex
+	writes []string
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.writes = append(b.writes, string(p))
+	return len(p), nil
+}
+
+func (b *lockedBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return strings.Join(b.writes, "")
+}
+
+func newTestWriterScrubber(t *testing.T) piiscrubber.Scrubber {
+	scrubber, err := piiscrubber.New(piiscrubber.Params{
+		BlacklistedEntities: []piiscrubber.Entity{piiscrubber.Email},
+	})
+	assert.NoError(t, err)
+	return scrubber
+}
+
+func Test_Writer(t *testing.T) {
+	var buf bytes.Buffer
+	w := piiscrubber.NewWriter(&buf, newTestWriterScrubber(t), nil)
+
+	// an email split across writes is only scrubbed once its line is complete
+	n, err := w.Write([]byte("mail from jane@exa"))
+	assert.NoError(t, err)
+	assert.Equal(t, 18, n)
+	assert.Equal(t, "", buf.String())
+
+	_, err = w.Write([]byte("mple.com\nreply to john@example.com\nbye jo"))
+	assert.NoError(t, err)
+	assert.Equal(t, "mail from <EMAIL_ADDRESS>\nreply to <EMAIL_ADDRESS>\n", buf.String())
+
+	assert.NoError(t, w.Close())
+	assert.Equal(t, "mail from <EMAIL_ADDRESS>\nreply to <EMAIL_ADDRESS>\nbye jo", buf.String())
+
+	_, err = w.Write([]byte("more\n"))
+	assert.ErrorIs(t, err, piiscrubber.ErrWriterClosed)
+}
+
+func Test_Writer_MaxLineSize(t *testing.T) {
+	var buf bytes.Buffer
+	w := piiscrubber.NewWriter(&buf, newTestWriterScrubber(t), &piiscrubber.WriterOptions{MaxLineSize: 16})
+
+	_, err := w.Write([]byte("jane@example.com"))
+	assert.NoError(t, err)
+	assert.Equal(t, "<EMAIL_ADDRESS>", buf.String())
+}
+
+func Test_Writer_FlushInterval(t *testing.T) {
+	buf := &lockedBuffer{}
+	w := piiscrubber.NewWriter(buf, newTestWriterScrubber(t), &piiscrubber.WriterOptions{FlushInterval: 10 * time.Millisecond})
+	defer w.Close()
+
+	_, err := w.Write([]byte("prompt for jane@example.com: "))
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return buf.String() == "prompt for <EMAIL_ADDRESS>: "
+	}, time.Second, 5*time.Millisecond)
+}
+
+func Test_Writer_ConcurrentLines(t *testing.T) {
+	buf := &lockedBuffer{}
+	w := piiscrubber.NewWriter(buf, newTestWriterScrubber(t), nil)
+	logger := log.New(w, "", 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				logger.Printf("worker %d sent mail to user%d@example.com", i, j)
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.NoError(t, w.Close())
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.Len(t, lines, 400)
+	for _, line := range lines {
+		var worker int
+		_, err := fmt.Sscanf(line, "worker %d sent mail to <EMAIL_ADDRESS>", &worker)
+		assert.NoError(t, err, line)
+	}
+}
@@ -0,0 +1,185 @@
+package piiscrubber
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const _defaultMaxLineSize = 64 * 1024
+
+var (
+	// ErrWriterClosed ...
+	ErrWriterClosed = fmt.Errorf("write to closed scrubbing writer")
+)
+
+// WriterOptions ...
+type WriterOptions struct {
+	// MaxLineSize is the number of bytes a partial line may grow to before it
+	// is scrubbed and written without waiting for its newline. Entities split
+	// by such a flush may go undetected. Defaults to 64KiB
+	MaxLineSize int
+	// FlushInterval is how long a partial line is held before it is scrubbed
+	// and written without waiting for its newline, zero holds it until the
+	// newline, MaxLineSize, Flush or Close
+	FlushInterval time.Duration
+}
+
+// Writer is an io.Writer that scrubs each line before writing it to the
+// underlying writer, e.g. for log.SetOutput or the Stdout of an exec.Cmd.
+// Lines are written whole with a single Write call, so lines written from
+// different goroutines are never interleaved
+type Writer struct {
+	next     io.Writer
+	scrubber Scrubber
+	opts     WriterOptions
+
+	lock    sync.Mutex
+	partial []byte
+	timer   *time.Timer
+	// timerID tells a firing timer whether it is still the current one
+	timerID int
+	closed  bool
+}
+
+// NewWriter returns a Writer that scrubs lines with s and writes them to next
+func NewWriter(next io.Writer, s Scrubber, opts *WriterOptions) *Writer {
+	w := &Writer{
+		next:     next,
+		scrubber: s,
+	}
+	if opts != nil {
+		w.opts = *opts
+	}
+	if w.opts.MaxLineSize <= 0 {
+		w.opts.MaxLineSize = _defaultMaxLineSize
+	}
+	return w
+}
+
+// Write scrubs and writes the complete lines of p, the partial line at its
+// end is held until it is completed or flushed. Nothing is written when
+// scrubbing fails
+func (w *Writer) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		return 0, ErrWriterClosed
+	}
+
+	w.partial = append(w.partial, p...)
+
+	if end := bytes.LastIndexByte(w.partial, '\n'); end >= 0 {
+		lines := w.partial[:end+1]
+		// lines is written before partial is appended to again, so the
+		// remainder can share its backing array
+		w.partial = w.partial[end+1:]
+		if err := w.writeLines(lines); err != nil {
+			w.dropPartial()
+			return 0, err
+		}
+	}
+
+	if len(w.partial) >= w.opts.MaxLineSize {
+		if err := w.flushPartial(); err != nil {
+			return 0, err
+		}
+	}
+
+	if len(w.partial) == 0 {
+		w.stopTimer()
+	} else if w.opts.FlushInterval > 0 && w.timer == nil {
+		w.timerID++
+		id := w.timerID
+		w.timer = time.AfterFunc(w.opts.FlushInterval, func() {
+			w.flushOnTimer(id)
+		})
+	}
+
+	return len(p), nil
+}
+
+// Flush scrubs and writes the partial line held by w, if any
+func (w *Writer) Flush() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.flushPartial()
+}
+
+// Close flushes the partial line held by w. Writes after Close fail with
+// ErrWriterClosed, the underlying writer is not closed
+func (w *Writer) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.flushPartial()
+}
+
+func (w *Writer) flushOnTimer(id int) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	// the timer may have been stopped, or replaced, while it was firing
+	if w.timer == nil || id != w.timerID {
+		return
+	}
+	// there is no caller to return the error to, the line is dropped
+	_ = w.flushPartial()
+}
+
+func (w *Writer) flushPartial() error {
+	w.stopTimer()
+	if len(w.partial) == 0 {
+		return nil
+	}
+
+	partial := w.partial
+	w.partial = nil
+	return w.writeLines(partial)
+}
+
+func (w *Writer) dropPartial() {
+	w.stopTimer()
+	w.partial = nil
+}
+
+func (w *Writer) stopTimer() {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+}
+
+// writeLines scrubs the newline separated lines of chunk with a single
+// ScrubTexts call and writes them with a single Write call
+func (w *Writer) writeLines(chunk []byte) error {
+	lines := bytes.Split(chunk, []byte{'\n'})
+	texts := make([]string, 0, len(lines))
+	for _, line := range lines {
+		texts = append(texts, string(line))
+	}
+
+	scrubbedTexts, err := w.scrubber.ScrubTexts(texts)
+	if err != nil {
+		return err
+	}
+
+	out := make([]byte, 0, len(chunk))
+	for i, text := range scrubbedTexts {
+		if i > 0 {
+			out = append(out, '\n')
+		}
+		out = append(out, text...)
+	}
+
+	_, err = w.next.Write(out)
+	return err
+}
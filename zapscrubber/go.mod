@@ -0,0 +1,19 @@
+module github.com/aavaz-ai/pii-scrubber/zapscrubber
+
+go 1.21
+
+require (
+	github.com/aavaz-ai/pii-scrubber v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.8.1
+	go.uber.org/zap v1.28.0
+)
+
+require (
+	github.com/anshal21/go-worker v1.1.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/aavaz-ai/pii-scrubber => ../
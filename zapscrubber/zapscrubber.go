@@ -0,0 +1,241 @@
+// Package zapscrubber provides a zapcore.Core that scrubs the message and the
+// fields of every entry with a piiscrubber.Scrubber before they are encoded
+//
+//	logger := zap.New(zapscrubber.NewCore(core, scrubber, nil))
+//	logger = logger.WithOptions(zapscrubber.WrapCore(scrubber, nil))
+//
+// It lives in its own module so that the library doesn't depend on zap
+package zapscrubber
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const _redactedValue = "<REDACTED>"
+
+// Options ...
+type Options struct {
+	// RedactKeys are field keys whose values are always replaced with
+	// <REDACTED>, e.g. "password". Keys of fields added after a zap.Namespace
+	// can also be given with the namespace, e.g. "user.email"
+	RedactKeys []string
+	// ExemptKeys are field keys whose values are never scrubbed, e.g. trace ids
+	// that look like a GUID or SHA1Hex
+	ExemptKeys []string
+}
+
+type core struct {
+	next       zapcore.Core
+	scrubber   piiscrubber.Scrubber
+	redactKeys map[string]bool
+	exemptKeys map[string]bool
+	// namespaces opened by fields added with With, used to match keys
+	namespaces []string
+}
+
+// NewCore returns a zapcore.Core that scrubs the message and the fields of
+// every entry before passing them on to next. Strings, errors and stringers
+// are scrubbed with a single ScrubTexts call per entry. Objects and arrays,
+// e.g. structs passed with zap.Any or zap.Reflect, are scrubbed with
+// ScrubStruct as if they were tagged with pii:"true", the same way
+// piiscrubber.NewSlogHandler scrubs them, since log fields are untrusted
+func NewCore(next zapcore.Core, s piiscrubber.Scrubber, opts *Options) zapcore.Core {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	c := &core{
+		next:       next,
+		scrubber:   s,
+		redactKeys: make(map[string]bool, len(opts.RedactKeys)),
+		exemptKeys: make(map[string]bool, len(opts.ExemptKeys)),
+	}
+	for _, key := range opts.RedactKeys {
+		c.redactKeys[key] = true
+	}
+	for _, key := range opts.ExemptKeys {
+		c.exemptKeys[key] = true
+	}
+	return c
+}
+
+// WrapCore returns a zap.Option that wraps the core of a logger with NewCore
+func WrapCore(s piiscrubber.Scrubber, opts *Options) zap.Option {
+	return zap.WrapCore(func(next zapcore.Core) zapcore.Core {
+		return NewCore(next, s, opts)
+	})
+}
+
+func (c *core) Enabled(level zapcore.Level) bool {
+	return c.next.Enabled(level)
+}
+
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	_, scrubbed, err := c.scrubFields("", fields)
+	if err != nil {
+		// never hand unscrubbed fields over
+		scrubbed = make([]zapcore.Field, 0, len(fields))
+		for _, field := range fields {
+			if field.Type == zapcore.NamespaceType {
+				scrubbed = append(scrubbed, field)
+				continue
+			}
+			scrubbed = append(scrubbed, zap.String(field.Key, _redactedValue))
+		}
+	}
+
+	copy := *c
+	copy.next = c.next.With(scrubbed)
+	copy.namespaces = c.namespaces[:len(c.namespaces):len(c.namespaces)]
+	for _, field := range fields {
+		if field.Type == zapcore.NamespaceType {
+			copy.namespaces = append(copy.namespaces, field.Key)
+		}
+	}
+	return &copy
+}
+
+func (c *core) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	message, scrubbed, err := c.scrubFields(entry.Message, fields)
+	if err != nil {
+		return err
+	}
+
+	entry.Message = message
+	return c.next.Write(entry, scrubbed)
+}
+
+func (c *core) Sync() error {
+	return c.next.Sync()
+}
+
+// scrubFields scrubs message and fields. The texts of all fields are
+// collected first and scrubbed with a single ScrubTexts call, then put back
+// in place of the fields they came from
+func (c *core) scrubFields(message string, fields []zapcore.Field) (string, []zapcore.Field, error) {
+	texts := []string{message}
+	// textFields[i] is the index of the field texts[i+1] came from
+	textFields := make([]int, 0, len(fields))
+
+	scrubbed := make([]zapcore.Field, len(fields))
+	namespaces := c.namespaces
+	for i, field := range fields {
+		scrubbed[i] = field
+
+		path := strings.Join(append(namespaces[:len(namespaces):len(namespaces)], field.Key), ".")
+		if field.Type == zapcore.NamespaceType {
+			namespaces = append(namespaces[:len(namespaces):len(namespaces)], field.Key)
+			continue
+		}
+		if c.exemptKeys[field.Key] || c.exemptKeys[path] {
+			continue
+		}
+		if c.redactKeys[field.Key] || c.redactKeys[path] {
+			scrubbed[i] = zap.String(field.Key, _redactedValue)
+			continue
+		}
+
+		text, ok := fieldText(field)
+		if ok {
+			texts = append(texts, text)
+			textFields = append(textFields, i)
+			continue
+		}
+
+		value, err := c.scrubField(field)
+		if err != nil {
+			return "", nil, err
+		}
+		scrubbed[i] = value
+	}
+
+	scrubbedTexts, err := c.scrubber.ScrubTexts(texts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	for i, index := range textFields {
+		scrubbed[index] = zap.String(fields[index].Key, scrubbedTexts[i+1])
+	}
+	return scrubbedTexts[0], scrubbed, nil
+}
+
+// fieldText returns the text a field is encoded as, for fields that are
+// encoded as a single string
+func fieldText(field zapcore.Field) (string, bool) {
+	switch field.Type {
+	case zapcore.StringType:
+		return field.String, true
+	case zapcore.ByteStringType:
+		return string(field.Interface.([]byte)), true
+	case zapcore.ErrorType:
+		if field.Interface == nil {
+			return "", false
+		}
+		return field.Interface.(error).Error(), true
+	case zapcore.StringerType:
+		// fmt recovers from String panicking on nil pointers the same way zap does
+		return fmt.Sprint(field.Interface), true
+	}
+	return "", false
+}
+
+// scrubField returns a scrubbed copy of a field holding an object or array.
+// Marshalers are encoded to maps and slices first so that their values can be
+// walked
+func (c *core) scrubField(field zapcore.Field) (zapcore.Field, error) {
+	switch field.Type {
+	case zapcore.ReflectType:
+		value, err := piiscrubber.ScrubValue(c.scrubber, field.Interface, true)
+		if err != nil {
+			return field, err
+		}
+		return zap.Reflect(field.Key, value), nil
+
+	case zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType, zapcore.InlineMarshalerType:
+		enc := zapcore.NewMapObjectEncoder()
+		field.AddTo(enc)
+		value, err := piiscrubber.ScrubValue(c.scrubber, enc.Fields, true)
+		if err != nil {
+			return field, err
+		}
+		encoded := value.(map[string]interface{})
+		if field.Type == zapcore.InlineMarshalerType {
+			return zap.Inline(encodedObject(encoded)), nil
+		}
+		return zap.Reflect(field.Key, encoded[field.Key]), nil
+	}
+	return field, nil
+}
+
+// encodedObject re-encodes the fields of an inline marshaler after they were
+// scrubbed
+type encodedObject map[string]interface{}
+
+func (o encodedObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	keys := make([]string, 0, len(o))
+	for key := range o {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := enc.AddReflected(key, o[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package zapscrubber_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	piiscrubber "github.com/aavaz-ai/pii-scrubber"
+	"github.com/aavaz-ai/pii-scrubber/zapscrubber"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type user struct {
+	Name  string `pii:"true" json:"name"`
+	Email string `pii:"true" json:"email"`
+	// not tagged, scrubbed all the same since log fields are untrusted
+	Support string `json:"support"`
+}
+
+func newTestLogger(t *testing.T, buf *bytes.Buffer, opts *zapscrubber.Options) *zap.Logger {
+	scrubber, err := piiscrubber.New(piiscrubber.Params{
+		BlacklistedEntities: []piiscrubber.Entity{
+			piiscrubber.Email,
+			piiscrubber.Phone,
+			piiscrubber.GUID,
+		},
+	})
+	assert.NoError(t, err)
+
+	encoder := zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+		MessageKey:  "msg",
+		LevelKey:    "level",
+		EncodeLevel: zapcore.LowercaseLevelEncoder,
+	})
+	next := zapcore.NewCore(encoder, zapcore.AddSync(buf), zapcore.DebugLevel)
+	return zap.New(zapscrubber.NewCore(next, scrubber, opts))
+}
+
+func TestCore(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(t, &buf, &zapscrubber.Options{
+		RedactKeys: []string{"password", "request.token"},
+		ExemptKeys: []string{"trace_id"},
+	})
+
+	logger.Info("signup by jane@example.com",
+		zap.String("password", "hunter2"),
+		zap.String("trace_id", "0f8fad5b-d9cb-469f-a165-70867728950e"),
+		zap.String("session", "0f8fad5b-d9cb-469f-a165-70867728950e"),
+		zap.Any("user", user{Name: "Jane", Email: "jane@example.com", Support: "help@example.com"}),
+		zap.Error(errors.New("no mailbox jane@example.com")),
+		zap.Strings("tags", []string{"jane@example.com"}),
+		zap.Int("attempt", 3),
+		zap.Namespace("request"),
+		zap.String("token", "abc"),
+		zap.String("from", "+919140520809"),
+	)
+
+	assert.Equal(t, `{"level":"info","msg":"signup by <EMAIL_ADDRESS>",`+
+		`"password":"<REDACTED>",`+
+		`"trace_id":"0f8fad5b-d9cb-469f-a165-70867728950e",`+
+		`"session":"<GUID>",`+
+		`"user":{"name":"Jane","email":"<EMAIL_ADDRESS>","support":"<EMAIL_ADDRESS>"},`+
+		`"error":"no mailbox <EMAIL_ADDRESS>",`+
+		`"tags":["<EMAIL_ADDRESS>"],`+
+		`"attempt":3,`+
+		`"request":{"token":"<REDACTED>","from":"<PHONE_NUMBER>"}}`+"\n", buf.String())
+}
+
+func TestCore_With(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(t, &buf, &zapscrubber.Options{
+		RedactKeys: []string{"account.password"},
+	})
+
+	logger = logger.With(zap.String("owner", "jane@example.com"), zap.Namespace("account"))
+	logger.Warn("updated", zap.String("password", "hunter2"), zap.String("contact", "jane@example.com"))
+
+	assert.Equal(t, `{"level":"warn","msg":"updated","owner":"<EMAIL_ADDRESS>","account":{"password":"<REDACTED>","contact":"<EMAIL_ADDRESS>"}}`+"\n", buf.String())
+}